@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"validating-webhook/policy"
+)
+
+var (
+	benchDir        string
+	benchIterations int
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Benchmark policy evaluation against a directory of manifests",
+	Long: `Evaluate every manifest in a directory against the policy, without
+starting the webhook server, and report the evaluation throughput and how
+long each rule took. The policy is set with the same flags as the server.
+
+Example:
+$ validating-webhook bench --dir <manifests_dir> --config <config_file>`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if benchDir == "" {
+			fmt.Println("--dir required")
+			os.Exit(1)
+		}
+		if benchIterations <= 0 {
+			fmt.Println("--iterations must be positive")
+			os.Exit(1)
+		}
+
+		objects, err := readManifests(benchDir)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if len(objects) == 0 {
+			fmt.Printf("no manifests found in %s\n", benchDir)
+			os.Exit(1)
+		}
+
+		timings := map[string]*ruleTiming{}
+		opts.ObserveRule = func(rule string, elapsed time.Duration) {
+			t, ok := timings[rule]
+			if !ok {
+				t = &ruleTiming{rule: rule}
+				timings[rule] = t
+			}
+			t.count++
+			t.total += elapsed
+		}
+		engine, err := newEngineFromFlags()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		rejected := 0
+		start := time.Now()
+		for i := 0; i < benchIterations; i++ {
+			for _, obj := range objects {
+				if decision := engine.Evaluate(context.Background(), obj, policy.RequestMeta{}); !decision.Allowed && i == 0 {
+					rejected++
+				}
+			}
+		}
+		printBenchSummary(os.Stdout, len(objects), rejected, time.Since(start), timings)
+	},
+}
+
+// ruleTiming is how long a rule took across every evaluation.
+type ruleTiming struct {
+	rule  string
+	count int
+	total time.Duration
+}
+
+// readManifests decodes every object in the YAML and JSON files in dir.
+// Files can hold more than one object, separated by ---.
+func readManifests(dir string) ([]runtime.Object, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading manifests: %w", err)
+	}
+
+	deserializer := scheme.Codecs.UniversalDeserializer()
+	var objects []runtime.Object
+	for _, file := range files {
+		switch filepath.Ext(file.Name()) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+
+		path := filepath.Join(dir, file.Name())
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading manifest %s: %w", path, err)
+		}
+
+		reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+		for {
+			doc, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("error reading manifest %s: %w", path, err)
+			}
+			if len(bytes.TrimSpace(doc)) == 0 {
+				continue
+			}
+
+			obj, _, err := deserializer.Decode(doc, nil, nil)
+			if err != nil {
+				return nil, fmt.Errorf("error decoding manifest %s: %w", path, err)
+			}
+			objects = append(objects, obj)
+		}
+	}
+	return objects, nil
+}
+
+// printBenchSummary prints the throughput of evaluating the objects, and how
+// long each rule took, slowest first.
+func printBenchSummary(w io.Writer, objects, rejected int, elapsed time.Duration, timings map[string]*ruleTiming) {
+	evaluations := objects * benchIterations
+	fmt.Fprintf(w, "evaluated %d objects %d times in %s (%d rejected)\n", objects, benchIterations, elapsed.Round(time.Millisecond), rejected)
+	fmt.Fprintf(w, "throughput: %.0f evaluations/s, %s per evaluation\n", float64(evaluations)/elapsed.Seconds(), (elapsed / time.Duration(evaluations)).Round(time.Microsecond/10))
+
+	sorted := make([]*ruleTiming, 0, len(timings))
+	for _, t := range timings {
+		sorted = append(sorted, t)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].total > sorted[j].total
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nRULE\tEVALUATIONS\tTOTAL\tAVERAGE")
+	for _, t := range sorted {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", t.rule, t.count, t.total.Round(time.Microsecond), (t.total / time.Duration(t.count)).Round(time.Nanosecond))
+	}
+	tw.Flush()
+}
+
+func init() {
+	benchCmd.Flags().StringVar(&benchDir, "dir", "", "Directory of manifests to evaluate")
+	benchCmd.Flags().IntVar(&benchIterations, "iterations", 100, "How many times to evaluate every manifest")
+	benchCmd.Flags().StringVar(&configFile, "config", "", "Policy configuration file")
+	addPolicyFlags(benchCmd.Flags())
+	rootCmd.AddCommand(benchCmd)
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"context"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// newKubeClient creates a client from the webhook's in-cluster service
+// account.
+func newKubeClient() (kubernetes.Interface, error) {
+	restConfig, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(restConfig)
+}
+
+// kubeDependency is the API server, which is a dependency of every rule
+// that looks up objects in the cluster.
+type kubeDependency struct {
+	client kubernetes.Interface
+}
+
+func (d kubeDependency) Name() string {
+	return "kubernetes api server"
+}
+
+func (d kubeDependency) Check(ctx context.Context) error {
+	return d.client.CoreV1().RESTClient().Get().AbsPath("/readyz").Do(ctx).Error()
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"validating-webhook/policy"
+)
+
+// defaultDecisionCacheSize is the most decisions that are cached at once
+// unless --decision-cache-size says otherwise.
+const defaultDecisionCacheSize = 10000
+
+// decisionCache caches policy decisions for ttl, keyed by the resource,
+// namespace, requester and digests of the objects, so that identical
+// objects that are submitted again, e.g. by a controller retrying, aren't
+// evaluated again. At most size decisions are cached: once it's full,
+// expired decisions are dropped to make room, and if none have expired the
+// oldest one is.
+type decisionCache struct {
+	ttl     time.Duration
+	size    int
+	mu      sync.Mutex
+	entries map[string]decisionCacheEntry
+}
+
+// decisionCacheEntry is exported field-wise so that entries can be persisted.
+type decisionCacheEntry struct {
+	Decision policy.Decision `json:"decision"`
+	Expires  time.Time       `json:"expires"`
+}
+
+func newDecisionCache(ttl time.Duration, size int) *decisionCache {
+	if size <= 0 {
+		size = defaultDecisionCacheSize
+	}
+	return &decisionCache{ttl: ttl, size: size, entries: map[string]decisionCacheEntry{}}
+}
+
+// decisionCacheKey returns the key the decision for the raw object is cached
+// under. It includes who made the request, the operation and the old object
+// for updates, since decisions can depend on them, such as the requester
+// rule's. Only cacheable decisions are cached, so decisions that depend on
+// anything else, such as on the time or on the namespace's labels, never
+// need a key. The parts are encoded as JSON before they're hashed, so that
+// no two different requests, such as ones whose group names contain a
+// separator, have the same key.
+func decisionCacheKey(meta policy.RequestMeta, raw, rawOld []byte) string {
+	parts := []interface{}{meta.Resource.String(), meta.Operation, meta.Namespace, meta.Username, meta.Groups, objectDigest(raw)}
+	if len(rawOld) > 0 {
+		parts = append(parts, objectDigest(rawOld))
+	}
+	data, err := json.Marshal(parts)
+	if err != nil {
+		// Every part is a string or a slice of them, which always encode.
+		panic(err)
+	}
+	return objectDigest(data)
+}
+
+// get returns the cached decision for key, if there is an unexpired one.
+func (c *decisionCache) get(key string) (policy.Decision, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || !time.Now().Before(entry.Expires) {
+		return policy.Decision{}, false
+	}
+	return entry.Decision, true
+}
+
+func (c *decisionCache) put(key string, decision policy.Decision) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.add(key, decisionCacheEntry{Decision: decision, Expires: time.Now().Add(c.ttl)})
+}
+
+// add caches the entry under key, evicting another one first if the cache
+// is full. It must be called with mu held.
+func (c *decisionCache) add(key string, entry decisionCacheEntry) {
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.size {
+		c.evict(time.Now())
+	}
+	c.entries[key] = entry
+}
+
+// evict makes room for another entry in the full cache, by dropping every
+// entry that has expired by now or, if none have, the oldest one. It must be
+// called with mu held.
+func (c *decisionCache) evict(now time.Time) {
+	oldest := ""
+	for key, entry := range c.entries {
+		if !now.Before(entry.Expires) {
+			delete(c.entries, key)
+			continue
+		}
+		if oldest == "" || entry.Expires.Before(c.entries[oldest].Expires) {
+			oldest = key
+		}
+	}
+	if len(c.entries) >= c.size {
+		delete(c.entries, oldest)
+	}
+}
+
+// reset drops every cached decision.
+func (c *decisionCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]decisionCacheEntry{}
+}
+
+// save writes the unexpired entries to path. The file is written next to
+// path and renamed into place so that a crash never leaves a partial file.
+func (c *decisionCache) save(path string) error {
+	now := time.Now()
+	c.mu.Lock()
+	entries := make(map[string]decisionCacheEntry, len(c.entries))
+	for key, entry := range c.entries {
+		if now.Before(entry.Expires) {
+			entries[key] = entry
+		} else {
+			delete(c.entries, key)
+		}
+	}
+	c.mu.Unlock()
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// load adds the entries saved in path that haven't expired yet, returning
+// how many were loaded. A missing file isn't an error since there is nothing
+// to load on the first start.
+func (c *decisionCache) load(path string) (int, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var entries map[string]decisionCacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	loaded := 0
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, entry := range entries {
+		if now.Before(entry.Expires) {
+			c.add(key, entry)
+			loaded++
+		}
+	}
+	return loaded, nil
+}
+
+// persistDecisionCache saves the cache to path every interval, so that a
+// restarted webhook can start with recently made decisions.
+func persistDecisionCache(c *decisionCache, path string, interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			if err := c.save(path); err != nil {
+				logger.Printf("error saving decision cache to %s: %v", path, err)
+			}
+		}
+	}()
+}
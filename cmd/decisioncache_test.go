@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"validating-webhook/policy"
+)
+
+// useDecisionCache caches decisions for ttl until the test ends.
+func useDecisionCache(t *testing.T, ttl time.Duration) *decisionCache {
+	decisions = newDecisionCache(ttl, 0)
+	t.Cleanup(func() { decisions = nil })
+	return decisions
+}
+
+func TestValidateDecisionCache(t *testing.T) {
+	useEngine(t, policy.Options{}, nil)
+	markReady(t)
+	cache := useDecisionCache(t, time.Minute)
+
+	pod := testPod(nil)
+	pod.Spec.HostPID = true
+	review := admissionReview(t, podResource, "default", pod, nil)
+	if response := serveReview(t, validate, review); !response.Allowed {
+		t.Fatalf("Allowed = false, want true: %s", resultMessage(response))
+	}
+	if n := len(cache.entries); n != 1 {
+		t.Fatalf("cached %d decisions, want 1", n)
+	}
+
+	// The policy changed without the cache being reset, so only a new
+	// evaluation would reject the pod.
+	useEngine(t, policy.Options{ForbidHostPID: true}, nil)
+	if response := serveReview(t, validate, review); !response.Allowed {
+		t.Error("Allowed = false for an identical request, want the cached decision")
+	}
+
+	review.Request.UserInfo.Username = "someone-else"
+	if response := serveReview(t, validate, review); response.Allowed {
+		t.Error("Allowed = true for a request by another user, want it evaluated again")
+	}
+}
+
+func TestDecisionCacheKey(t *testing.T) {
+	meta := policy.RequestMeta{Resource: podResource, Namespace: "default", Username: "alice", Groups: []string{"dev"}}
+	key := decisionCacheKey(meta, []byte("object"), nil)
+	if again := decisionCacheKey(meta, []byte("object"), nil); again != key {
+		t.Errorf("decisionCacheKey() = %q then %q, want the same key", key, again)
+	}
+
+	other := meta
+	other.Groups = []string{"admin"}
+	update := meta
+	update.Operation = policy.OperationUpdate
+	for name, otherKey := range map[string]string{
+		"object":     decisionCacheKey(meta, []byte("other"), nil),
+		"old object": decisionCacheKey(meta, []byte("object"), []byte("old")),
+		"groups":     decisionCacheKey(other, []byte("object"), nil),
+		"operation":  decisionCacheKey(update, []byte("object"), nil),
+	} {
+		if otherKey == key {
+			t.Errorf("decisionCacheKey() with a different %s = %q, want a different key", name, otherKey)
+		}
+	}
+
+	// Joined with commas, these groups would look the same.
+	split, joined := meta, meta
+	split.Groups = []string{"dev", "admin"}
+	joined.Groups = []string{"dev,admin"}
+	if decisionCacheKey(split, []byte("object"), nil) == decisionCacheKey(joined, []byte("object"), nil) {
+		t.Error("decisionCacheKey() is the same for groups dev and admin as for group dev,admin")
+	}
+}
+
+func TestValidateDoesNotCacheUncacheableDecisions(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("get", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("connection refused")
+	})
+
+	tests := []struct {
+		name string
+		opts policy.Options
+	}{
+		{
+			name: "rule that can't be evaluated",
+			opts: policy.Options{VerifyPullSecretsExist: true, FailurePolicy: policy.FailurePolicyIgnore, Client: client},
+		},
+		{
+			name: "rule that looks up the namespace",
+			opts: policy.Options{VerifyEnvironment: true, EnvironmentLabel: "environment", Client: fake.NewSimpleClientset()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useEngine(t, tt.opts, nil)
+			markReady(t)
+			cache := useDecisionCache(t, time.Minute)
+
+			pod := testPod(map[string]string{"environment": "prod"})
+			pod.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "registry"}}
+			serveReview(t, validate, admissionReview(t, podResource, "default", pod, nil))
+			if n := len(cache.entries); n != 0 {
+				t.Errorf("cached %d decisions, want none", n)
+			}
+		})
+	}
+}
+
+func TestDecisionCacheTTL(t *testing.T) {
+	c := newDecisionCache(time.Minute, 0)
+	c.put("key", policy.Decision{Allowed: true})
+	if _, ok := c.get("key"); !ok {
+		t.Fatal("get() found no decision, want the one just put")
+	}
+
+	c.entries["key"] = decisionCacheEntry{Decision: policy.Decision{Allowed: true}, Expires: time.Now().Add(-time.Second)}
+	if _, ok := c.get("key"); ok {
+		t.Error("get() found an expired decision")
+	}
+}
+
+func TestDecisionCacheEviction(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		entries map[string]decisionCacheEntry
+		want    []string
+	}{
+		{
+			name: "expired entries",
+			entries: map[string]decisionCacheEntry{
+				"expired":   {Expires: now.Add(-time.Second)},
+				"expired-2": {Expires: now.Add(-time.Minute)},
+				"current":   {Expires: now.Add(time.Minute)},
+			},
+			want: []string{"current", "new"},
+		},
+		{
+			name: "oldest entry",
+			entries: map[string]decisionCacheEntry{
+				"oldest": {Expires: now.Add(time.Second)},
+				"newer":  {Expires: now.Add(time.Minute)},
+				"newest": {Expires: now.Add(time.Hour)},
+			},
+			want: []string{"new", "newer", "newest"},
+		},
+		{
+			name: "key that's already cached",
+			entries: map[string]decisionCacheEntry{
+				"new":    {Expires: now.Add(time.Second)},
+				"newer":  {Expires: now.Add(time.Minute)},
+				"newest": {Expires: now.Add(time.Hour)},
+			},
+			want: []string{"new", "newer", "newest"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newDecisionCache(time.Minute, 3)
+			c.entries = tt.entries
+			c.put("new", policy.Decision{Allowed: true})
+			var got []string
+			for key := range c.entries {
+				got = append(got, key)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cached keys = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecisionCachePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.json")
+	c := newDecisionCache(time.Minute, 0)
+	decision := policy.Decision{Allowed: false, Message: "rejected", Warnings: []string{"warned"}}
+	c.put("current", decision)
+	c.entries["expired"] = decisionCacheEntry{Decision: decision, Expires: time.Now().Add(-time.Second)}
+	if err := c.save(path); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+	if _, ok := c.entries["expired"]; ok {
+		t.Error("save() kept the expired decision in the cache")
+	}
+
+	loaded := newDecisionCache(time.Minute, 0)
+	n, err := loaded.load(path)
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if n != 1 {
+		t.Errorf("load() = %d, want 1", n)
+	}
+	got, ok := loaded.get("current")
+	if !ok || got.Message != decision.Message || got.Allowed != decision.Allowed || len(got.Warnings) != 1 {
+		t.Errorf("get() after load = %+v, %t, want %+v", got, ok, decision)
+	}
+
+	// Decisions that expired after they were saved aren't loaded.
+	expired := loaded.entries["current"]
+	expired.Expires = time.Now().Add(-time.Second)
+	loaded.entries["current"] = expired
+	if err := ioutil.WriteFile(path, mustMarshal(t, loaded.entries), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := newDecisionCache(time.Minute, 0).load(path); err != nil || n != 0 {
+		t.Errorf("load() of expired decisions = %d, %v, want 0", n, err)
+	}
+
+	// A missing file is a cold start, not an error.
+	if n, err := newDecisionCache(time.Minute, 0).load(filepath.Join(t.TempDir(), "missing.json")); err != nil || n != 0 {
+		t.Errorf("load() of a missing file = %d, %v, want 0, nil", n, err)
+	}
+}
+
+func TestReloadTeamAllowlistResetsDecisionCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "teams")
+	if err := ioutil.WriteFile(path, []byte("payments\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	previousFile := teamAllowlistFile
+	teamAllowlistFile = path
+	t.Cleanup(func() { teamAllowlistFile = previousFile })
+	useEngine(t, policy.Options{TeamAllowlist: policy.NewAllowlist([]string{"payments"}), TeamLabel: "team"}, nil)
+	markReady(t)
+	cache := useDecisionCache(t, time.Minute)
+
+	review := admissionReview(t, podResource, "default", testPod(map[string]string{"team": "payments"}), nil)
+	if response := serveReview(t, validate, review); !response.Allowed {
+		t.Fatalf("Allowed = false, want true: %s", resultMessage(response))
+	}
+
+	if err := ioutil.WriteFile(path, []byte("search\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := reloadTeamAllowlist(); err != nil {
+		t.Fatalf("reloadTeamAllowlist() error = %v", err)
+	}
+	if n := len(cache.entries); n != 0 {
+		t.Errorf("cached %d decisions after reloading, want none", n)
+	}
+	if response := serveReview(t, validate, review); response.Allowed {
+		t.Error("Allowed = true for a team that was removed from the allowlist")
+	}
+}
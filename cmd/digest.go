@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// objectDigestAnnotation is the audit annotation the object digest is
+// returned in. The API server prefixes it with the webhook name.
+const objectDigestAnnotation = "object-digest"
+
+// objectDigest returns the SHA256 digest of the raw object exactly as the
+// API server sent it, so identical objects always have the same digest.
+func objectDigest(raw []byte) string {
+	sum := sha256.Sum256(raw)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+
+	"validating-webhook/policy"
+)
+
+// downstreamRule is the rule name that rejections by the downstream webhook
+// are recorded under.
+const downstreamRule = "downstream"
+
+var (
+	downstreamURL     string
+	downstreamTimeout time.Duration
+	downstreamCAFile  string
+	downstreamClient  = &http.Client{}
+)
+
+// newDownstreamClient creates the client for calling the downstream webhook,
+// trusting the CA in caFile if there is one, as webhooks are usually served
+// with certificates from a cluster-internal CA.
+func newDownstreamClient(caFile string) (*http.Client, error) {
+	if caFile == "" {
+		return &http.Client{}, nil
+	}
+	ca, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading downstream CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates found in downstream CA %s", caFile)
+	}
+	return &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+	}, nil
+}
+
+// chainDownstream returns the findings of the downstream webhook for the
+// object, which is only called when the object passed this webhook's own
+// rules, as the decision says: a rejection if the downstream webhook rejects
+// it, along with its warnings. If the downstream webhook can't be called,
+// the object is rejected, or with the ignore failure policy only warned
+// about. The findings are to be decided on together with the decision's.
+func chainDownstream(ctx context.Context, review *admissionv1.AdmissionReview, decision policy.Decision) []policy.Finding {
+	if downstreamURL == "" || !decision.Allowed {
+		return nil
+	}
+
+	response, err := callDownstream(ctx, review)
+	if err != nil {
+		msg := fmt.Sprintf("error calling downstream webhook: %v", err)
+		logger.Printf(msg)
+		return []policy.Finding{{Rule: downstreamRule, Message: msg, Warning: opts.FailurePolicy == policy.FailurePolicyIgnore}}
+	}
+
+	var findings []policy.Finding
+	for _, warning := range response.Warnings {
+		findings = append(findings, policy.Finding{Rule: downstreamRule, Message: warning, Warning: true})
+	}
+	if !response.Allowed {
+		msg := "rejected by downstream webhook"
+		if response.Result != nil && response.Result.Message != "" {
+			msg = response.Result.Message
+		}
+		findings = append(findings, policy.Finding{Rule: downstreamRule, Message: msg})
+	}
+	return findings
+}
+
+// callDownstream sends the AdmissionReview to the downstream webhook and
+// returns its response, giving up after the downstream timeout.
+func callDownstream(ctx context.Context, review *admissionv1.AdmissionReview) (*admissionv1.AdmissionResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, downstreamTimeout)
+	defer cancel()
+
+	body, err := json.Marshal(review)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling admission review: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, downstreamURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := downstreamClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var downstreamReview admissionv1.AdmissionReview
+	if err := json.NewDecoder(resp.Body).Decode(&downstreamReview); err != nil {
+		return nil, fmt.Errorf("error decoding admission review: %w", err)
+	}
+	if downstreamReview.Response == nil {
+		return nil, fmt.Errorf("admission review has no response")
+	}
+	if downstreamReview.Response.UID != review.Request.UID {
+		return nil, fmt.Errorf("response UID %s doesn't match request UID %s", downstreamReview.Response.UID, review.Request.UID)
+	}
+	return downstreamReview.Response, nil
+}
+
+// validateDownstreamURL checks that url is an absolute http or https URL.
+func validateDownstreamURL(downstream string) error {
+	u, err := url.Parse(downstream)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("must be an http or https URL")
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"validating-webhook/policy"
+)
+
+// useDownstream chains /validate to a downstream webhook that responds to
+// every AdmissionReview with response, or with status if it isn't 200.
+// It returns a pointer to the number of reviews the downstream webhook
+// received.
+func useDownstream(t *testing.T, status int, response admissionv1.AdmissionResponse) *int {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var review admissionv1.AdmissionReview
+		if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+			t.Errorf("downstream received an invalid admission review: %v", err)
+		}
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		reply := response
+		reply.UID = review.Request.UID
+		review.Response = &reply
+		json.NewEncoder(w).Encode(review)
+	}))
+	t.Cleanup(server.Close)
+
+	downstreamURL, downstreamTimeout, downstreamClient = server.URL, time.Second, server.Client()
+	t.Cleanup(func() { downstreamURL, downstreamTimeout, downstreamClient = "", 0, &http.Client{} })
+	return &calls
+}
+
+func TestValidateDownstream(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         policy.Options
+		hostPID      bool
+		status       int
+		response     admissionv1.AdmissionResponse
+		wantCalls    int
+		wantAllowed  bool
+		wantMessage  string
+		wantWarnings []string
+	}{
+		{
+			name:         "both allow",
+			status:       http.StatusOK,
+			response:     admissionv1.AdmissionResponse{Allowed: true, Warnings: []string{"from downstream"}},
+			wantCalls:    1,
+			wantAllowed:  true,
+			wantWarnings: []string{"from downstream"},
+		},
+		{
+			name:        "downstream rejects",
+			status:      http.StatusOK,
+			response:    admissionv1.AdmissionResponse{Result: &metav1.Status{Message: "no thanks"}},
+			wantCalls:   1,
+			wantMessage: "no thanks",
+		},
+		{
+			name:        "rejected before downstream",
+			opts:        policy.Options{ForbidHostPID: true},
+			hostPID:     true,
+			status:      http.StatusOK,
+			response:    admissionv1.AdmissionResponse{Allowed: true},
+			wantMessage: "must not use the host PID namespace (hostPID)",
+		},
+		{
+			name:        "downstream fails",
+			status:      http.StatusInternalServerError,
+			wantCalls:   1,
+			wantMessage: "error calling downstream webhook: unexpected status 500",
+		},
+		{
+			name:         "downstream fails with ignore",
+			opts:         policy.Options{FailurePolicy: policy.FailurePolicyIgnore},
+			status:       http.StatusInternalServerError,
+			wantCalls:    1,
+			wantAllowed:  true,
+			wantWarnings: []string{"error calling downstream webhook: unexpected status 500"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useEngine(t, tt.opts, nil)
+			markReady(t)
+			calls := useDownstream(t, tt.status, tt.response)
+
+			pod := testPod(nil)
+			pod.Spec.HostPID = tt.hostPID
+			response := serveReview(t, validate, admissionReview(t, podResource, "default", pod, nil))
+			if *calls != tt.wantCalls {
+				t.Errorf("downstream calls = %d, want %d", *calls, tt.wantCalls)
+			}
+			if response.Allowed != tt.wantAllowed {
+				t.Errorf("Allowed = %t, want %t", response.Allowed, tt.wantAllowed)
+			}
+			if msg := resultMessage(response); msg != tt.wantMessage {
+				t.Errorf("Result.Message = %q, want %q", msg, tt.wantMessage)
+			}
+			if !reflect.DeepEqual(response.Warnings, tt.wantWarnings) {
+				t.Errorf("Warnings = %q, want %q", response.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestValidateDownstreamWarnings(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         policy.Options
+		warnings     []string
+		wantWarnings []string
+	}{
+		{
+			name:         "deduplicated",
+			warnings:     []string{"world will be deprecated for hello in the future", "from downstream"},
+			wantWarnings: []string{"request for pods has no namespace, evaluated as if in namespace default", "world will be deprecated for hello in the future", "from downstream"},
+		},
+		{
+			name:         "capped",
+			opts:         policy.Options{MaxWarnings: 2},
+			warnings:     []string{"from downstream", "also from downstream"},
+			wantWarnings: []string{"request for pods has no namespace, evaluated as if in namespace default", "world will be deprecated for hello in the future", "2 more warnings suppressed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useEngine(t, tt.opts, nil)
+			markReady(t)
+			useDownstream(t, http.StatusOK, admissionv1.AdmissionResponse{Allowed: true, Warnings: tt.warnings})
+
+			pod := testPod(map[string]string{"hello": "world"})
+			pod.Namespace = ""
+			response := serveReview(t, validate, admissionReview(t, podResource, "", pod, nil))
+			if !reflect.DeepEqual(response.Warnings, tt.wantWarnings) {
+				t.Errorf("Warnings = %q, want %q", response.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestValidateDownstreamURL(t *testing.T) {
+	for url, wantErr := range map[string]bool{
+		"https://downstream.default.svc/validate": false,
+		"http://localhost:8443/validate":          false,
+		"downstream.default.svc/validate":         true,
+		"ftp://downstream/validate":               true,
+	} {
+		if err := validateDownstreamURL(url); (err != nil) != wantErr {
+			t.Errorf("validateDownstreamURL(%q) error = %v, want error %t", url, err, wantErr)
+		}
+	}
+}
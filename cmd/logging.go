@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+
+	"validating-webhook/policy"
+)
+
+var (
+	accessLogOutput   string
+	decisionLogOutput string
+	decisionLogger    = log.New(os.Stdout, "decision: ", log.LstdFlags)
+)
+
+// logOutput returns the stream a log set to output, stdout or stderr, is
+// written to.
+func logOutput(output string) (io.Writer, error) {
+	switch output {
+	case "stdout":
+		return os.Stdout, nil
+	case "stderr":
+		return os.Stderr, nil
+	}
+	return nil, fmt.Errorf("invalid log output %s: must be stdout or stderr", output)
+}
+
+// setLogOutputs sends the access logs and decision logs to the streams set
+// by their flags, so that log pipelines can separate them.
+func setLogOutputs() error {
+	accessOutput, err := logOutput(accessLogOutput)
+	if err != nil {
+		return fmt.Errorf("--access-log-output: %w", err)
+	}
+	decisionOutput, err := logOutput(decisionLogOutput)
+	if err != nil {
+		return fmt.Errorf("--decision-log-output: %w", err)
+	}
+	logger.SetOutput(accessOutput)
+	decisionLogger.SetOutput(decisionOutput)
+	return nil
+}
+
+// logDecision logs the decision made for the admission request.
+func logDecision(request *admissionv1.AdmissionRequest, decision policy.Decision) {
+	object := request.Name
+	if request.Namespace != "" {
+		object = request.Namespace + "/" + object
+	}
+
+	if decision.Allowed {
+		decisionLogger.Printf("allowed %s %s %s (%d warnings)", request.Operation, request.Resource.Resource, object, len(decision.Warnings))
+		return
+	}
+	var rules []string
+	for _, f := range decision.Findings {
+		if !f.Warning && !contains(rules, f.Rule) {
+			rules = append(rules, f.Rule)
+		}
+	}
+	decisionLogger.Printf("rejected %s %s %s by %s: %s", request.Operation, request.Resource.Resource, object, strings.Join(rules, ", "), decision.Message)
+}
+
+// contains reports whether s is one of values.
+func contains(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
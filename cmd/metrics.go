@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"validating-webhook/policy"
+)
+
+var rejectionsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "webhook_rejections_total",
+		Help: "Number of objects rejected, by the rule that rejected them.",
+	},
+	[]string{"rule"},
+)
+
+func init() {
+	prometheus.MustRegister(rejectionsTotal)
+}
+
+// recordRejections increments the rejection counter once for every rule that
+// rejected the object, no matter how many violations the rule found.
+func recordRejections(findings []policy.Finding) {
+	counted := map[string]bool{}
+	for _, f := range findings {
+		if f.Warning || counted[f.Rule] {
+			continue
+		}
+		counted[f.Rule] = true
+		rejectionsTotal.WithLabelValues(f.Rule).Inc()
+	}
+}
+
+// newMetricsServer returns the server for the Prometheus metrics over plain
+// HTTP on addr, so that they can be scraped without a client certificate for
+// the webhook's TLS port.
+func newMetricsServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return &http.Server{
+		Addr:     addr,
+		Handler:  mux,
+		ErrorLog: logger,
+	}
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+
+	"validating-webhook/policy"
+)
+
+// mutate responds to AdmissionReviews from the mutating webhook with the
+// JSON patch that policy.Mutate makes for the object, such as injecting a
+// sidecar. Objects are always allowed: rejecting them is up to /validate.
+func mutate(w http.ResponseWriter, r *http.Request) {
+	logger.Printf("received message on mutate")
+
+	deserializer := codecs.UniversalDeserializer()
+
+	admissionReviewRequest, err := admissionReviewFromRequest(r, deserializer)
+	if err != nil {
+		msg := fmt.Sprintf("error getting admission review from request: %v", err)
+		logger.Printf(msg)
+		w.WriteHeader(400)
+		w.Write([]byte(msg))
+		return
+	}
+
+	admissionResponse := &admissionv1.AdmissionResponse{Allowed: true}
+
+	resource := admissionReviewRequest.Request.Resource
+	object, ok := policy.NewObject(resource)
+	if !ok {
+		writeAdmissionReview(w, admissionReviewRequest, admissionResponse)
+		return
+	}
+	if _, _, err := deserializer.Decode(admissionReviewRequest.Request.Object.Raw, nil, object); err != nil {
+		msg := fmt.Sprintf("error decoding raw %s: %v", resource.Resource, err)
+		logger.Printf(msg)
+		admissionResponse.Warnings = []string{msg}
+		writeAdmissionReview(w, admissionReviewRequest, admissionResponse)
+		return
+	}
+
+	meta := policy.RequestMeta{
+		Resource:  resource,
+		Namespace: admissionReviewRequest.Request.Namespace,
+	}
+	if patch := policy.Mutate(r.Context(), object, meta); len(patch) > 0 {
+		data, err := json.Marshal(patch)
+		if err != nil {
+			msg := fmt.Sprintf("error marshalling patch json: %v", err)
+			logger.Printf(msg)
+			w.WriteHeader(500)
+			w.Write([]byte(msg))
+			return
+		}
+		patchType := admissionv1.PatchTypeJSONPatch
+		admissionResponse.Patch = data
+		admissionResponse.PatchType = &patchType
+	}
+
+	writeAdmissionReview(w, admissionReviewRequest, admissionResponse)
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	"validating-webhook/policy"
+)
+
+func TestMutate(t *testing.T) {
+	sidecar := &corev1.Container{Name: "proxy", Image: "proxy:v1"}
+	useEngine(t, policy.Options{InjectSidecar: true}, &policy.Config{Sidecar: sidecar})
+
+	tests := []struct {
+		name      string
+		optIn     bool
+		wantPatch []policy.PatchOperation
+	}{
+		{
+			name:      "opted in",
+			optIn:     true,
+			wantPatch: []policy.PatchOperation{{Op: "add", Path: "/spec/containers/-", Value: map[string]interface{}{"name": "proxy", "image": "proxy:v1", "resources": map[string]interface{}{}}}},
+		},
+		{
+			name: "not opted in",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := testPod(nil)
+			pod.Spec.Containers = []corev1.Container{{Name: "app", Image: "app:v1"}}
+			if tt.optIn {
+				pod.Annotations = map[string]string{"webhook.trstringer.com/inject-sidecar": "true"}
+			}
+			response := serveReview(t, mutate, admissionReview(t, podResource, "default", pod, nil))
+			if !response.Allowed {
+				t.Error("Allowed = false, want true")
+			}
+			if tt.wantPatch == nil {
+				if response.Patch != nil || response.PatchType != nil {
+					t.Errorf("Patch = %s, want none", response.Patch)
+				}
+				return
+			}
+
+			if response.PatchType == nil || *response.PatchType != admissionv1.PatchTypeJSONPatch {
+				t.Errorf("PatchType = %v, want %s", response.PatchType, admissionv1.PatchTypeJSONPatch)
+			}
+			var patch []policy.PatchOperation
+			if err := json.Unmarshal(response.Patch, &patch); err != nil {
+				t.Fatalf("error decoding patch: %v", err)
+			}
+			if !reflect.DeepEqual(patch, tt.wantPatch) {
+				t.Errorf("Patch = %+v, want %+v", patch, tt.wantPatch)
+			}
+		})
+	}
+}
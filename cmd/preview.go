@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"validating-webhook/policy"
+)
+
+// previewResource is the resource that objects posted to /preview are.
+var previewResource = metav1.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}
+
+// previewResponse is what /preview returns: the decision the pod would get,
+// along with the names of the rules that found something.
+type previewResponse struct {
+	policy.Decision
+	Rules []string `json:"rules,omitempty"`
+}
+
+// preview returns the decision that a pod posted as JSON would get if it was
+// created, without needing the caller to wrap it in an AdmissionReview.
+func preview(w http.ResponseWriter, r *http.Request) {
+	logger.Printf("received message on preview")
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(405)
+		w.Write([]byte("preview requires a POST of a pod"))
+		return
+	}
+
+	if !isReady() {
+		msg := "webhook is not ready"
+		logger.Printf(msg)
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds()))
+		w.WriteHeader(503)
+		w.Write([]byte(msg))
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		msg := fmt.Sprintf("error reading pod: %v", err)
+		logger.Printf(msg)
+		w.WriteHeader(400)
+		w.Write([]byte(msg))
+		return
+	}
+
+	object, _ := policy.NewObject(previewResource)
+	if _, _, err := codecs.UniversalDeserializer().Decode(body, nil, object); err != nil {
+		msg := fmt.Sprintf("error decoding pod: %v", err)
+		logger.Printf(msg)
+		w.WriteHeader(400)
+		w.Write([]byte(msg))
+		return
+	}
+
+	// The pod is evaluated in the namespace it would be created in.
+	meta := policy.RequestMeta{Resource: previewResource}
+	namespaceWarning := defaultRequestNamespace(&meta, object)
+	evaluated := policy.Evaluate(r.Context(), object, meta)
+	decision := policy.Decide(withNamespaceWarning(evaluated.Findings, namespaceWarning), opts.MaxWarnings)
+	decision.TimedOut = evaluated.TimedOut
+	response := previewResponse{Decision: decision, Rules: findingRules(decision.Findings)}
+
+	resp, err := json.Marshal(response)
+	if err != nil {
+		msg := fmt.Sprintf("error marshalling response json: %v", err)
+		logger.Printf(msg)
+		w.WriteHeader(500)
+		w.Write([]byte(msg))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// findingRules returns the names of the rules that found something, in the
+// order they first did.
+func findingRules(findings []policy.Finding) []string {
+	var rules []string
+	seen := map[string]bool{}
+	for _, f := range findings {
+		if !seen[f.Rule] {
+			seen[f.Rule] = true
+			rules = append(rules, f.Rule)
+		}
+	}
+	return rules
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"validating-webhook/policy"
+)
+
+func TestPreview(t *testing.T) {
+	useEngine(t, policy.Options{ForbidHostPID: true}, nil)
+	markReady(t)
+
+	pod := testPod(map[string]string{"hello": "world"})
+	pod.Spec.HostPID = true
+	rec := serve(preview, http.MethodPost, mustMarshal(t, pod))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	var response previewResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("error decoding response: %v", err)
+	}
+	if response.Allowed {
+		t.Error("Allowed = true, want false")
+	}
+	if want := "must not use the host PID namespace (hostPID)"; response.Message != want {
+		t.Errorf("Message = %q, want %q", response.Message, want)
+	}
+	if want := []string{"hello-label", "host-pid"}; !reflect.DeepEqual(response.Rules, want) {
+		t.Errorf("Rules = %q, want %q", response.Rules, want)
+	}
+}
+
+func TestPreviewNamespace(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "fallback", Labels: map[string]string{"env": "prod"}}})
+	useEngine(t, policy.Options{VerifyEnvironment: true, EnvironmentLabel: "env", Client: client}, nil)
+	markReady(t)
+	previousNamespace := defaultNamespace
+	defaultNamespace = "fallback"
+	t.Cleanup(func() { defaultNamespace = previousNamespace })
+
+	pod := testPod(map[string]string{"env": "dev"})
+	pod.Namespace = ""
+	rec := serve(preview, http.MethodPost, mustMarshal(t, pod))
+	var response previewResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("error decoding response: %v", err)
+	}
+
+	// The pod is evaluated in the default namespace, whose environment
+	// doesn't match the pod's.
+	if want := "pod env dev does not match namespace fallback env prod"; response.Message != want {
+		t.Errorf("Message = %q, want %q", response.Message, want)
+	}
+	if want := []string{"request for pods has no namespace, evaluated as if in namespace fallback"}; !reflect.DeepEqual(response.Warnings, want) {
+		t.Errorf("Warnings = %q, want %q", response.Warnings, want)
+	}
+}
+
+func TestPreviewErrors(t *testing.T) {
+	useEngine(t, policy.Options{}, nil)
+	markReady(t)
+
+	tests := []struct {
+		name       string
+		method     string
+		body       []byte
+		wantStatus int
+	}{
+		{name: "not a POST", method: http.MethodGet, wantStatus: http.StatusMethodNotAllowed},
+		{name: "not a pod", method: http.MethodPost, body: []byte(`{"spec": "not a spec"}`), wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serve(preview, tt.method, tt.body); rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
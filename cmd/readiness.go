@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// warmUpInterval is how long to wait between checks of a dependency that
+// isn't reachable yet.
+const warmUpInterval = time.Second
+
+// dependency is an external service that the webhook needs to be able to
+// reach before it can evaluate policy.
+type dependency interface {
+	// Name identifies the dependency in logs.
+	Name() string
+	// Check returns an error if the dependency isn't reachable.
+	Check(ctx context.Context) error
+}
+
+// httpDependency is a dependency that is reachable once a GET to its url
+// returns a non-5xx status.
+type httpDependency struct {
+	url string
+}
+
+func (d httpDependency) Name() string {
+	return d.url
+}
+
+func (d httpDependency) Check(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+func init() {
+	// Seed the jitter so that every replica doesn't pick the same values.
+	rand.Seed(time.Now().UnixNano())
+}
+
+// ready is set to 1 once every dependency has been reached.
+var ready int32
+
+// warmUpError is why the last attempt to warm up failed, which readyz
+// reports until the webhook is ready. It is empty before any attempt has
+// failed.
+var warmUpError atomic.Value
+
+func isReady() bool {
+	return atomic.LoadInt32(&ready) == 1
+}
+
+// warmUp waits until every dependency is reachable and then marks the
+// webhook as ready. It returns an error if that doesn't happen within
+// timeout.
+func warmUp(dependencies []dependency, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for _, dep := range dependencies {
+		for {
+			err := dep.Check(ctx)
+			if err == nil {
+				logger.Printf("dependency %s is reachable", dep.Name())
+				break
+			}
+
+			logger.Printf("waiting for dependency %s: %v", dep.Name(), err)
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("dependency %s not reachable within %s: %v", dep.Name(), timeout, err)
+			case <-time.After(warmUpInterval):
+			}
+		}
+	}
+
+	atomic.StoreInt32(&ready, 1)
+	return nil
+}
+
+// keepWarmingUp warms up, trying again whenever the dependencies aren't all
+// reachable within timeout, until they are. The webhook stays unready in
+// the meantime, and every failed attempt is logged and reported by readyz,
+// rather than taking the webhook down over a dependency that is only slow
+// to start.
+func keepWarmingUp(dependencies []dependency, timeout time.Duration) {
+	for {
+		err := warmUp(dependencies, timeout)
+		if err == nil {
+			return
+		}
+		logger.Printf("error warming up, trying again: %v", err)
+		warmUpError.Store(err.Error())
+	}
+}
+
+// readyz reports whether the webhook is ready to receive admission requests.
+func readyz(w http.ResponseWriter, r *http.Request) {
+	if !isReady() {
+		msg := "warming up"
+		if err, _ := warmUpError.Load().(string); err != "" {
+			msg += ": " + err
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(msg))
+		return
+	}
+	w.Write([]byte("ok"))
+}
+
+// retryAfterSeconds returns the value for the Retry-After header sent with
+// 503 responses. It is --retry-after with up to --response-timeout-jitter
+// randomly added or removed, so that the API server's retries are spread
+// out instead of all arriving at once. It is never less than one second.
+func retryAfterSeconds() int {
+	delay := retryAfter
+	if responseTimeoutJitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(2*responseTimeoutJitter)+1)) - responseTimeoutJitter
+	}
+
+	seconds := int(delay.Round(time.Second) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
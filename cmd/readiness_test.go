@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeDependency is a dependency whose reachability is decided by check,
+// which is passed the number of times it has been called before.
+type fakeDependency struct {
+	calls int
+	check func(calls int) error
+}
+
+func (d *fakeDependency) Name() string {
+	return "fake"
+}
+
+func (d *fakeDependency) Check(ctx context.Context) error {
+	err := d.check(d.calls)
+	d.calls++
+	return err
+}
+
+// getReadyz returns the status and body readyz responds with.
+func getReadyz() (int, string) {
+	rec := httptest.NewRecorder()
+	readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	return rec.Code, rec.Body.String()
+}
+
+func TestKeepWarmingUp(t *testing.T) {
+	t.Cleanup(func() {
+		atomic.StoreInt32(&ready, 0)
+		warmUpError.Store("")
+	})
+
+	if status, body := getReadyz(); status != http.StatusServiceUnavailable || body != "warming up" {
+		t.Errorf("readyz before warming up = %d %q, want %d %q", status, body, http.StatusServiceUnavailable, "warming up")
+	}
+
+	// The dependency isn't reachable within the timeout the first time, so
+	// the webhook must stay unready, report why and try again.
+	var retryStatus int
+	var retryBody string
+	dep := &fakeDependency{check: func(calls int) error {
+		if calls == 0 {
+			return errors.New("connection refused")
+		}
+		retryStatus, retryBody = getReadyz()
+		return nil
+	}}
+	keepWarmingUp([]dependency{dep}, 10*time.Millisecond)
+
+	wantBody := "warming up: dependency fake not reachable within 10ms: connection refused"
+	if retryStatus != http.StatusServiceUnavailable || retryBody != wantBody {
+		t.Errorf("readyz while retrying = %d %q, want %d %q", retryStatus, retryBody, http.StatusServiceUnavailable, wantBody)
+	}
+	if status, body := getReadyz(); status != http.StatusOK || body != "ok" {
+		t.Errorf("readyz after warming up = %d %q, want %d %q", status, body, http.StatusOK, "ok")
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	previousRetryAfter, previousJitter := retryAfter, responseTimeoutJitter
+	t.Cleanup(func() { retryAfter, responseTimeoutJitter = previousRetryAfter, previousJitter })
+
+	retryAfter, responseTimeoutJitter = 5*time.Second, 2*time.Second
+	for i := 0; i < 100; i++ {
+		if seconds := retryAfterSeconds(); seconds < 3 || seconds > 7 {
+			t.Fatalf("retryAfterSeconds() = %d, want between 3 and 7", seconds)
+		}
+	}
+
+	retryAfter, responseTimeoutJitter = 500*time.Millisecond, 0
+	if seconds := retryAfterSeconds(); seconds != 1 {
+		t.Errorf("retryAfterSeconds() = %d, want at least 1", seconds)
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"validating-webhook/policy"
+)
+
+// reloadOnSIGHUP reloads the files that can change while the webhook is
+// running, the team allowlist and the config, every time the process
+// receives SIGHUP. If a file can't be reloaded, the previously loaded values
+// stay in use.
+func reloadOnSIGHUP() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		for range signals {
+			if opts.TeamAllowlist != nil {
+				if err := reloadTeamAllowlist(); err != nil {
+					logger.Printf("error reloading team allowlist, keeping previous teams: %v", err)
+				}
+			}
+
+			if configFile != "" {
+				if err := reloadConfig(); err != nil {
+					logger.Printf("error reloading config, keeping previous ruleset: %v", err)
+				} else {
+					logger.Printf("reloaded config from %s", configFile)
+				}
+			}
+		}
+	}()
+}
+
+// reloadTeamAllowlist reads the team allowlist file again and replaces the
+// allowed teams with its teams.
+func reloadTeamAllowlist() error {
+	teams, err := policy.ReadAllowlistFile(teamAllowlistFile)
+	if err != nil {
+		return err
+	}
+	opts.TeamAllowlist.Set(teams)
+	logger.Printf("reloaded %d teams from %s", len(teams), teamAllowlistFile)
+
+	// Decisions made with the previous teams may no longer be right.
+	if decisions != nil {
+		decisions.reset()
+	}
+	return nil
+}
+
+// reloadConfig loads the config file again and swaps in an engine built from
+// it. The engine compiles and validates the whole ruleset before it is
+// swapped in, so a config with any bad rule is rejected as a whole and
+// requests keep being evaluated against the previous ruleset.
+func reloadConfig() error {
+	cfg, err := policy.LoadConfig(configFile)
+	if err != nil {
+		return err
+	}
+	engine, err := policy.NewEngine(opts, cfg)
+	if err != nil {
+		return err
+	}
+	policy.SetDefault(engine)
+
+	// Decisions made under the previous ruleset may no longer be right.
+	if decisions != nil {
+		decisions.reset()
+	}
+	return nil
+}
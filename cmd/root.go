@@ -6,23 +6,53 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"mime"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	admissionv1 "k8s.io/api/admission/v1"
-	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+
+	"validating-webhook/policy"
 )
 
 var (
-	tlsCert string
-	tlsKey  string
-	port    int
-	codecs  = serializer.NewCodecFactory(runtime.NewScheme())
-	logger  = log.New(os.Stdout, "http: ", log.LstdFlags)
+	tlsCert                      string
+	tlsKey                       string
+	configFile                   string
+	port                         int
+	listenAddress                string
+	dependencyURLs               []string
+	warmUpTimeout                time.Duration
+	retryAfter                   time.Duration
+	responseTimeoutJitter        time.Duration
+	emitObjectDigest             bool
+	opts                         policy.Options
+	teamAllowlistFile            string
+	decisionCacheTTL             time.Duration
+	decisionCacheSize            int
+	decisionCacheFile            string
+	decisionCachePersistInterval time.Duration
+	decisions                    *decisionCache
+	rejectOnDecodeError          bool
+	emitViolations               bool
+	metricsAddr                  string
+	defaultNamespace             string
+	strictContentType            bool
+	includeSubresources          []string
+	codecs                       = serializer.NewCodecFactory(runtime.NewScheme())
+	logger                       = log.New(os.Stdout, "http: ", log.LstdFlags)
 )
 
 var rootCmd = &cobra.Command{
@@ -37,10 +67,91 @@ $ validating-webhook --tls-cert <tls_cert> --tls-key <tls_key> --port <port>`,
 			fmt.Println("--tls-cert and --tls-key required")
 			os.Exit(1)
 		}
-		runWebhookServer(tlsCert, tlsKey)
+
+		if err := validateFlags(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if err := setLogOutputs(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		engine, err := newEngineFromFlags()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		policy.SetDefault(engine)
+		reloadOnSIGHUP()
+
+		if downstreamURL != "" {
+			if downstreamClient, err = newDownstreamClient(downstreamCAFile); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+
+		if enableSimulate {
+			if simulateClient, err = newKubeClient(); err != nil {
+				fmt.Printf("error creating kubernetes client for --enable-simulate: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if decisionCacheTTL > 0 {
+			decisions = newDecisionCache(decisionCacheTTL, decisionCacheSize)
+			if decisionCacheFile != "" {
+				loaded, err := decisions.load(decisionCacheFile)
+				if err != nil {
+					logger.Printf("error loading decision cache from %s, starting cold: %v", decisionCacheFile, err)
+				} else {
+					logger.Printf("loaded %d decisions from %s", loaded, decisionCacheFile)
+				}
+				persistDecisionCache(decisions, decisionCacheFile, decisionCachePersistInterval)
+			}
+		}
+
+		if err := runWebhookServer(tlsCert, tlsKey); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 	},
 }
 
+// newEngineFromFlags creates the policy engine from the policy flags,
+// loading the config and the other files they refer to.
+func newEngineFromFlags() (*policy.Engine, error) {
+	var err error
+	var cfg *policy.Config
+	if configFile != "" {
+		if cfg, err = policy.LoadConfig(configFile); err != nil {
+			return nil, err
+		}
+		if cfg.MigratedFrom != "" {
+			logger.Printf("config %s migrated from apiVersion %s to %s", configFile, cfg.MigratedFrom, policy.ConfigAPIVersion)
+		}
+	}
+
+	if teamAllowlistFile != "" {
+		teams, err := policy.ReadAllowlistFile(teamAllowlistFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading team allowlist: %w", err)
+		}
+		opts.TeamAllowlist = policy.NewAllowlist(teams)
+	}
+
+	if opts.RequiresClient() {
+		if opts.Client, err = newKubeClient(); err != nil {
+			return nil, fmt.Errorf("error creating kubernetes client: %w", err)
+		}
+	}
+
+	opts.Logger = logger
+	return policy.NewEngine(opts, cfg)
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
@@ -50,12 +161,203 @@ func Execute() {
 func init() {
 	rootCmd.Flags().StringVar(&tlsCert, "tls-cert", "", "Certificate for TLS")
 	rootCmd.Flags().StringVar(&tlsKey, "tls-key", "", "Private key file for TLS")
+	rootCmd.Flags().StringVar(&configFile, "config", "", "Policy configuration file")
+	rootCmd.Flags().StringSliceVar(&dependencyURLs, "dependency-url", nil, "URL of an external dependency that must be reachable before the webhook reports ready (repeatable)")
+	rootCmd.Flags().DurationVar(&warmUpTimeout, "warmup-timeout", time.Minute, "How long to wait for dependencies to become reachable at startup before logging the failure and trying again")
+	rootCmd.Flags().DurationVar(&retryAfter, "retry-after", 5*time.Second, "Retry-After sent when the webhook is temporarily unavailable")
+	rootCmd.Flags().DurationVar(&responseTimeoutJitter, "response-timeout-jitter", 0, "Maximum random jitter added to or removed from --retry-after")
+	rootCmd.Flags().BoolVar(&emitObjectDigest, "emit-object-digest", false, "Add a SHA256 digest of the evaluated object to the response's audit annotations")
+	rootCmd.Flags().BoolVar(&emitViolations, "emit-violations", false, "Add a JSON document describing each violation to the audit annotations of rejections")
+	rootCmd.Flags().BoolVar(&rejectOnDecodeError, "reject-on-decode-error", true, "Reject objects that can't be decoded; when false they are allowed with a warning")
+	rootCmd.Flags().BoolVar(&strictContentType, "strict-content-type", false, "Only accept a Content-Type of exactly application/json, rejecting ones with parameters such as charset")
+	rootCmd.Flags().StringSliceVar(&includeSubresources, "include-subresources", nil, "Subresources to validate, pods/exec or pods/attach; requests for other subresources are allowed")
+	rootCmd.Flags().StringVar(&defaultNamespace, "default-namespace", "default", "Namespace to evaluate requests without one in, with a warning")
+	rootCmd.Flags().StringVar(&downstreamURL, "downstream-url", "", "URL of a webhook to forward AdmissionReviews to after they pass this webhook's rules; the object is rejected if either rejects it")
+	rootCmd.Flags().DurationVar(&downstreamTimeout, "downstream-timeout", 5*time.Second, "How long to wait for the downstream webhook before handling it as a failure per --failure-policy")
+	rootCmd.Flags().StringVar(&downstreamCAFile, "downstream-ca-file", "", "CA certificate to trust for the downstream webhook's TLS certificate")
+	rootCmd.Flags().DurationVar(&decisionCacheTTL, "decision-cache-ttl", 0, "How long to reuse the decision for an identical object (0 to disable)")
+	rootCmd.Flags().IntVar(&decisionCacheSize, "decision-cache-size", defaultDecisionCacheSize, "Maximum number of decisions to cache at once")
+	rootCmd.Flags().StringVar(&decisionCacheFile, "decision-cache-file", "", "File to periodically save the decision cache to and load it from at startup")
+	rootCmd.Flags().DurationVar(&decisionCachePersistInterval, "decision-cache-persist-interval", time.Minute, "How often to save the decision cache to --decision-cache-file")
+	rootCmd.Flags().StringVar(&listenAddress, "listen-address", "0.0.0.0", "IP address of the interface to listen on for HTTPS traffic")
 	rootCmd.Flags().IntVar(&port, "port", 443, "Port to listen on for HTTPS traffic")
+	rootCmd.Flags().StringVar(&metricsAddr, "metrics-addr", "", "Address to serve metrics on over plain HTTP (e.g. :8080), instead of on the webhook's TLS port")
+	rootCmd.Flags().BoolVar(&enableSimulate, "enable-simulate", false, "Serve /simulate?namespace=<namespace>, which reports which of the namespace's existing pods the policy would reject to callers with a bearer token that can list them; needs RBAC to list pods and create token and subject access reviews, as in kubernetes/webhook-simulate-rbac.yaml")
+	rootCmd.Flags().StringVar(&accessLogOutput, "access-log-output", "stdout", "Stream to write access logs to: stdout or stderr")
+	rootCmd.Flags().StringVar(&decisionLogOutput, "decision-log-output", "stdout", "Stream to write the log of admission decisions to: stdout or stderr")
+	addPolicyFlags(rootCmd.Flags())
+}
+
+// addPolicyFlags adds the flags that set the policy engine's options to
+// flags, so that every command evaluates policy the same way.
+func addPolicyFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&opts.ShortCircuit, "short-circuit", false, "Stop evaluating rules after the first rejection instead of reporting every violation")
+	flags.StringArrayVar(&opts.DisabledRules, "disable-rule", nil, "Name of a rule to turn off, regardless of other flags or config (repeatable)")
+	flags.StringArrayVar(&opts.SkipObjectLabels, "skip-object-label", nil, "Label, as key=value, that exempts objects carrying it from validation (repeatable)")
+	flags.IntVar(&opts.MaxWarnings, "max-warnings", 0, "Maximum number of warnings to return, with a summary of how many more there were (0 for no maximum)")
+	flags.StringVar(&opts.FailurePolicy, "failure-policy", policy.FailurePolicyFail, "How to handle rules that can't be evaluated, such as when a lookup fails: Fail or Ignore")
+	flags.StringVar(&opts.DecisionDefault, "decision-default", "", "Decision when every rule for an object can't be evaluated, overriding --failure-policy for those errors even when it is Ignore: allow, deny or allow-with-warning (empty to leave it to --failure-policy)")
+	flags.DurationVar(&opts.PipelineTimeout, "pipeline-timeout", 0, "Maximum time to evaluate all of an object's rules combined, which should be less than the webhook's timeoutSeconds (0 for no maximum)")
+	flags.StringVar(&opts.PipelineTimeoutDecision, "pipeline-timeout-decision", policy.DecisionDefaultDeny, "Decision when --pipeline-timeout is hit before every rule is evaluated: allow, deny or allow-with-warning")
+	flags.DurationVar(&opts.LookupCacheTTL, "lookup-cache-ttl", 30*time.Second, "How long to cache lookups of cluster objects")
+	flags.IntVar(&opts.LookupCacheSize, "lookup-cache-size", policy.DefaultLookupCacheSize, "Maximum number of lookups of cluster objects and images to cache at once")
+	flags.BoolVar(&opts.EnforceProbesTimeout, "enforce-probes-timeout", false, "Reject pods whose probe timeouts overlap their period or start too early")
+	flags.Int32Var(&opts.ProbeInitialDelayFloor, "probe-initial-delay-floor", 0, "Minimum probe initialDelaySeconds when --enforce-probes-timeout is set")
+	flags.StringVar(&opts.NamePattern, "name-pattern", "", "Regex that pod names must match, templated with the pod's labels (e.g. ^{{ .Labels.team }}-)")
+	flags.BoolVar(&opts.ValidateContainerNames, "validate-container-names", false, "Reject pods with duplicate container names, or names that don't match --container-name-pattern")
+	flags.StringVar(&opts.ContainerNamePattern, "container-name-pattern", "", "Regex that container names must match when --validate-container-names is set")
+	flags.BoolVar(&opts.VerifyPullSecretsExist, "verify-pull-secrets-exist", false, "Reject pods whose imagePullSecrets don't exist in their namespace; needs RBAC to get secrets in the namespaces of the pods")
+	flags.BoolVar(&opts.VerifyEnvRefsExist, "verify-env-refs-exist", false, "Reject pods whose required env config map and secret key references don't exist; needs RBAC to get config maps and secrets in the namespaces of the pods")
+	flags.StringVar(&opts.ConfigMapOwnerLabel, "configmap-owner-label", "", "Owner label, such as team, that the config maps a pod uses must share with the pod, looked up in the cluster; mismatches are warned about")
+	flags.BoolVar(&opts.ForbidPrivilegeEscalation, "forbid-privilege-escalation", false, "Reject containers that set allowPrivilegeEscalation to true")
+	flags.BoolVar(&opts.RequireExplicitPrivilegeEscalation, "require-explicit-privilege-escalation", false, "Also reject containers that leave allowPrivilegeEscalation unset when --forbid-privilege-escalation is set")
+	flags.BoolVar(&opts.RequireAppArmor, "require-apparmor", false, "Reject pods that don't set a confined AppArmor profile annotation for every container")
+	flags.BoolVar(&opts.RequireReadOnlyRootFilesystem, "require-read-only-root-filesystem", false, "Reject containers that don't set readOnlyRootFilesystem, unless exempted by annotation")
+	flags.BoolVar(&opts.ForbidHostPID, "forbid-hostpid", false, "Reject pods that use the host PID namespace")
+	flags.BoolVar(&opts.ForbidHostIPC, "forbid-hostipc", false, "Reject pods that use the host IPC namespace")
+	flags.BoolVar(&opts.RequireSecurityContext, "require-security-context", false, "Reject containers that don't set a securityContext at all")
+	flags.StringSliceVar(&opts.FSGroupRanges, "fsgroup-ranges", nil, "Allowed ranges of pod fsGroup IDs, as min-max (e.g. 1000-1999)")
+	flags.StringSliceVar(&opts.RunAsUserRanges, "run-as-user-ranges", nil, "Allowed ranges of runAsUser IDs, as min-max")
+	flags.StringSliceVar(&opts.RunAsGroupRanges, "run-as-group-ranges", nil, "Allowed ranges of runAsGroup IDs, as min-max")
+	flags.BoolVar(&opts.RequireRevisionHistoryLimit, "require-revision-history-limit", false, "Reject deployments that don't set revisionHistoryLimit")
+	flags.Int32Var(&opts.MaxRevisionHistoryLimit, "max-revision-history-limit", 0, "Maximum revisionHistoryLimit allowed for deployments (0 for no maximum)")
+	flags.BoolVar(&opts.ValidateDeploymentStrategy, "validate-deployment-strategy", false, "Reject deployments using the Recreate strategy, unless annotated to allow it")
+	flags.StringVar(&opts.MaxUnavailable, "max-unavailable", "", "Maximum rolling update maxUnavailable, as a number or percentage, allowed for deployments when --validate-deployment-strategy is set")
+	flags.BoolVar(&opts.RequireProgressDeadline, "require-progress-deadline", false, "Reject deployments that leave progressDeadlineSeconds at the default of 600")
+	flags.Int32Var(&opts.MaxProgressDeadlineSeconds, "max-progress-deadline-seconds", 0, "Maximum progressDeadlineSeconds allowed for deployments (0 for no maximum)")
+	flags.StringVar(&opts.ParallelStartupLabel, "parallel-startup-label", "", "Label set to true or false on statefulsets whose pods can or can't start in parallel, to warn about a mismatched podManagementPolicy")
+	flags.BoolVar(&opts.RequireJobTTL, "require-job-ttl", false, "Reject jobs and cron jobs that don't set ttlSecondsAfterFinished (use the warn action to only warn)")
+	flags.BoolVar(&opts.ValidateHPAReplicas, "validate-hpa-replicas", false, "Reject horizontal pod autoscalers with inconsistent minReplicas and maxReplicas")
+	flags.Int32Var(&opts.MaxHPAReplicas, "max-hpa-replicas", 0, "Maximum maxReplicas allowed for horizontal pod autoscalers when --validate-hpa-replicas is set (0 for no maximum)")
+	flags.BoolVar(&opts.AllowHPAScaleToZero, "allow-hpa-scale-to-zero", false, "Allow minReplicas of 0, for clusters with the HPAScaleToZero feature enabled")
+	flags.StringSliceVar(&opts.RestrictedNetworkNamespaces, "restricted-network-namespaces", nil, "Namespaces where network policies may not allow all ingress or egress traffic")
+	flags.StringVar(&opts.PublicHostPattern, "public-host-pattern", "", "Regex matching public ingress hosts, which must be covered by a TLS entry (e.g. \\.example\\.com$)")
+	flags.StringSliceVar(&opts.AllowedIngressClasses, "allowed-ingress-classes", nil, "Ingress classes that ingresses may set in spec.ingressClassName or the legacy kubernetes.io/ingress.class annotation")
+	flags.BoolVar(&opts.AllowDefaultIngressClass, "allow-default-ingress-class", true, "Also allow ingresses that don't set an ingress class, and so use the cluster's default, when --allowed-ingress-classes is set")
+	flags.StringVar(&teamAllowlistFile, "team-allowlist-file", "", "File of allowed team label values, one per line, reloaded on SIGHUP")
+	flags.StringVar(&opts.TeamLabel, "team-label", "team", "Label that carries a pod's team when --team-allowlist-file is set")
+	flags.BoolVar(&opts.VerifyEnvironment, "verify-environment", false, "Reject pods whose environment label doesn't match their namespace's environment label")
+	flags.StringVar(&opts.EnvironmentLabel, "environment-label", "environment", "Label that carries the environment of pods and namespaces")
+	flags.BoolVar(&opts.RejectMissingNamespaceEnvironment, "reject-missing-namespace-environment", false, "Reject labeled pods in namespaces without an environment label when --verify-environment is set")
+	flags.BoolVar(&opts.VerifyPriorityClassTier, "verify-priority-class-tier", false, "Reject pods using a priority class restricted by config to other namespace tiers")
+	flags.StringVar(&opts.TierLabel, "tier-label", "tier", "Label that carries the tier of namespaces when --verify-priority-class-tier is set")
+	flags.StringSliceVar(&opts.LoadBalancerAnnotations, "load-balancer-required-annotations", nil, "Annotations, as key or key=value, that LoadBalancer services must have")
+	flags.StringSliceVar(&opts.ServiceSelectorLabels, "service-selector-labels", nil, "Labels that service selectors should use by convention (e.g. app.kubernetes.io/name)")
+	flags.StringVar(&opts.AntiAffinitySelector, "anti-affinity-selector", "", "Label selector of pods that must declare podAntiAffinity against each other (e.g. tier=database)")
+	flags.IntVar(&opts.MaxTopologySpreadConstraints, "max-topology-spread-constraints", 0, "Maximum number of topologySpreadConstraints allowed per pod, also warning when they're all DoNotSchedule (0 to disable)")
+	flags.StringSliceVar(&opts.AllowedSchedulers, "allowed-schedulers", nil, "Schedulers that pods may set in schedulerName")
+	flags.BoolVar(&opts.AllowDefaultScheduler, "allow-default-scheduler", true, "Also allow pods to use the default scheduler when --allowed-schedulers is set")
+	flags.StringVar(&opts.StatefulLabel, "stateful-label", "", "Label, as key=value or key, of stateful pods that must not run on spot nodes")
+	flags.StringSliceVar(&opts.SpotNodeTaints, "spot-node-taints", nil, "Taints, as key=value or key, of spot or preemptible nodes")
+	flags.StringSliceVar(&opts.SpotNodeLabels, "spot-node-labels", nil, "Labels, as key=value or key, of spot or preemptible nodes")
+	flags.StringVar(&opts.SensitiveDataLabel, "sensitive-data-label", "", "Label, as key=value or key, of pods handling sensitive data such as PII, which require a strict security context")
+	flags.StringVar(&opts.SecretEnvPattern, "secret-env-pattern", "", "Regex matching the names of sensitive secrets, which containers must mount as files rather than consume through env or envFrom (e.g. -(credentials|tls)$)")
+	flags.StringVar(&opts.QuotaEnforcedNamespaceLabel, "quota-enforced-namespace-label", "", "Label, as key=value or key, of namespaces where pods must not set annotations that bypass resource quotas")
+	flags.StringSliceVar(&opts.QuotaBypassAnnotations, "quota-bypass-annotations", policy.DefaultQuotaBypassAnnotations, "Pod annotations, as key=value or key, that bypass resource quotas, for --quota-enforced-namespace-label")
+	flags.StringVar(&opts.FreezeNamespaceLabel, "freeze-namespace-label", "", "Label, as key=value or key, of namespaces where new pods are rejected during the config's freeze windows")
+	flags.StringVar(&opts.ProductionNamespaceLabel, "production-namespace-label", "", "Label, as key=value or key, of production namespaces where images with mutable tags are rejected")
+	flags.StringVar(&opts.MutableTagPattern, "mutable-tag-pattern", policy.DefaultMutableTagPattern, "Regex matching mutable image tags when --production-namespace-label is set")
+	flags.StringVar(&opts.NoExecNamespaceLabel, "no-exec-namespace-label", "", "Label, as key=value or key, of namespaces where pods/exec and pods/attach requests are rejected; requires --include-subresources")
+	flags.StringVar(&opts.RestrictedNamespaceLabel, "restricted-namespace-label", "", "Label, as key=value or key, of namespaces where containers must not add any capabilities")
+	flags.StringVar(&opts.GPUNamespaceLabel, "gpu-namespace-label", "", "Label, as key=value or key, of GPU namespaces where pods must get Guaranteed QoS, with CPU and memory requests equal to limits")
+	flags.StringVar(&opts.PluginDir, "plugin-dir", "", "Directory of plugins to load as extra rules: Go plugins, .so files exporting Validate func(resource string, object []byte) (string, error), and WebAssembly plugins, .wasm files")
+	flags.BoolVar(&opts.InjectSidecar, "inject-sidecar", false, "Inject the sidecar from the config into pods annotated with webhook.trstringer.com/inject-sidecar: \"true\", via /mutate")
+	flags.Float64Var(&opts.LimitRatio, "limit-ratio", 0, "Warn when a container's memory or ephemeral-storage limit is more than this multiple of the pod's total requests (0 to disable)")
+	flags.StringVar(&opts.MaxEmptyDirSize, "max-emptydir-size", "", "Maximum emptyDir sizeLimit allowed for pod volumes (e.g. 1Gi)")
+	flags.BoolVar(&opts.RequireEmptyDirSizeLimit, "require-emptydir-size-limit", false, "Reject pods with emptyDir volumes that don't set a sizeLimit")
+	flags.BoolVar(&opts.RequireEmptyDirStorageLimits, "require-emptydir-storage-limits", false, "Reject containers that mount disk-backed emptyDir volumes without an ephemeral-storage limit")
+	flags.StringVar(&opts.MinPVCStorage, "min-pvc-storage", "", "Minimum storage persistent volume claims may request (e.g. 1Gi)")
+	flags.StringVar(&opts.MaxPVCStorage, "max-pvc-storage", "", "Maximum storage persistent volume claims may request (e.g. 500Gi)")
+	flags.StringVar(&opts.MaxImageSize, "max-image-size", "", "Maximum compressed size of container images, looked up in their registries (e.g. 2Gi)")
+	flags.BoolVar(&opts.RejectRootImages, "reject-root-images", false, "Reject containers whose image runs as root by default, unless they set runAsNonRoot or a non-root runAsUser")
+	flags.StringVar(&opts.RegistryConfigFile, "registry-config-file", "", "Docker config file with credentials for looking up images in private registries for --max-image-size and --reject-root-images")
+	flags.IntVar(&opts.MaxEnvVars, "max-env-vars", 0, "Maximum number of environment variables allowed per container (0 to disable)")
+	flags.IntVar(&opts.MaxVolumeMounts, "max-volume-mounts", 0, "Maximum number of volumeMounts allowed per container (0 to disable)")
+	flags.BoolVar(&opts.WarnOverlappingMounts, "warn-overlapping-mounts", false, "Warn about containers with a volume mounted at or inside another of their mounts")
+	flags.BoolVar(&opts.ValidateHugePages, "validate-hugepages", false, "Reject containers whose huge pages requests and limits differ, and warn about pods requesting huge pages that aren't scheduled onto nodes with them")
+	flags.StringVar(&opts.HugePagesNodeLabel, "hugepages-node-label", "", "Node label, as key=value or key, of nodes with huge pages that pods requesting them must select or tolerate")
+	flags.BoolVar(&opts.WarnQOSMismatch, "warn-qos-mismatch", false, "Warn about pods whose QoS class differs from the one declared in their webhook.trstringer.com/qos-class annotation")
+	flags.Int64Var(&opts.MaxObjectSize, "max-object-size", 0, "Maximum size in bytes of admitted objects of any resource, measured on the raw request object (0 for no maximum)")
+	flags.StringSliceVar(&opts.AllowedManagedBy, "allowed-managed-by", nil, "Tools, such as argocd or flux, that objects of every resource must name in their app.kubernetes.io/managed-by label")
+	flags.StringSliceVar(&opts.SanitizeMetadataKeys, "sanitize-metadata-keys", nil, "Label and annotation keys, with a trailing * for a prefix, whose values must not contain injection characters on objects of every resource")
+	flags.StringSliceVar(&opts.ImmutableFields, "immutable-fields", nil, "Field paths, such as spec.serviceAccountName, that must not change when objects of any resource are updated")
+	flags.StringVar(&opts.InjectionPattern, "injection-pattern", policy.DefaultInjectionPattern, "Regex matching injection characters for --sanitize-metadata-keys")
+	flags.BoolVar(&opts.RejectUnresolvedPlaceholders, "reject-unresolved-placeholders", false, "Reject containers whose command, args or env values contain unrendered template placeholders")
+	flags.StringVar(&opts.PlaceholderPattern, "placeholder-pattern", policy.DefaultPlaceholderPattern, "Regex matching unrendered template placeholders when --reject-unresolved-placeholders is set")
+	flags.BoolVar(&opts.WarnExpensiveLivenessExec, "warn-expensive-liveness-exec", false, "Warn about liveness probes that exec expensive commands such as curl or scripts")
+	flags.StringVar(&opts.ExpensiveExecPattern, "expensive-exec-pattern", policy.DefaultExpensiveExecPattern, "Regex matching expensive liveness probe exec commands when --warn-expensive-liveness-exec is set")
+	flags.BoolVar(&opts.ValidateProbePorts, "validate-probe-ports", false, "Reject httpGet and tcpSocket probes whose port isn't declared in the container's ports")
+	flags.BoolVar(&opts.WarnDeprecatedAnnotations, "warn-deprecated-annotations", false, "Warn about pods with deprecated alpha and beta annotations that have been replaced by fields")
+	flags.BoolVar(&opts.RequireImagePullPolicy, "require-image-pull-policy", false, "Reject containers that don't set imagePullPolicy; configure the rule's action as warn in the config to only warn")
+}
+
+// validateFlags checks the server flags that can't be validated by their type
+// alone. The policy flags are validated when the engine is created.
+func validateFlags() error {
+	if net.ParseIP(listenAddress) == nil {
+		return fmt.Errorf("invalid --listen-address %s: must be an IP address", listenAddress)
+	}
+
+	if warmUpTimeout <= 0 {
+		return fmt.Errorf("--warmup-timeout must be positive")
+	}
+
+	if retryAfter <= 0 || responseTimeoutJitter < 0 {
+		return fmt.Errorf("--retry-after must be positive and --response-timeout-jitter must not be negative")
+	}
+
+	if metricsAddr != "" {
+		if _, _, err := net.SplitHostPort(metricsAddr); err != nil {
+			return fmt.Errorf("invalid --metrics-addr %s: %v", metricsAddr, err)
+		}
+	}
+
+	if decisionCacheFile != "" && (decisionCacheTTL <= 0 || decisionCachePersistInterval <= 0) {
+		return fmt.Errorf("--decision-cache-file requires a positive --decision-cache-ttl and --decision-cache-persist-interval")
+	}
+
+	for _, subresource := range includeSubresources {
+		if _, ok := policy.NewObject(metav1.GroupVersionResource{Version: "v1", Resource: subresource}); !ok {
+			return fmt.Errorf("invalid --include-subresources %s: must be pods/exec or pods/attach", subresource)
+		}
+	}
+
+	if downstreamURL != "" {
+		if err := validateDownstreamURL(downstreamURL); err != nil {
+			return fmt.Errorf("invalid --downstream-url %s: %v", downstreamURL, err)
+		}
+		if downstreamTimeout <= 0 {
+			return fmt.Errorf("--downstream-timeout must be positive")
+		}
+	}
+
+	return nil
+}
+
+// admissionResponseFromDecision creates a response that allows or rejects
+// the object based off of the policy decision.
+func admissionResponseFromDecision(decision policy.Decision) *admissionv1.AdmissionResponse {
+	admissionResponse := &admissionv1.AdmissionResponse{}
+	admissionResponse.Allowed = decision.Allowed
+	admissionResponse.Warnings = decision.Warnings
+	if !decision.Allowed {
+		admissionResponse.Result = &metav1.Status{
+			Message: decision.Message,
+			Reason:  metav1.StatusReason(decision.Reason),
+		}
+	}
+	return admissionResponse
 }
 
 func admissionReviewFromRequest(r *http.Request, deserializer runtime.Decoder) (*admissionv1.AdmissionReview, error) {
-	// Validate that the incoming content type is correct.
-	if r.Header.Get("Content-Type") != "application/json" {
+	// Validate that the incoming content type is correct. Parameters such
+	// as charset are tolerated unless the match must be exact.
+	contentType := r.Header.Get("Content-Type")
+	if strictContentType {
+		if contentType != "application/json" {
+			return nil, fmt.Errorf("expected application/json content-type")
+		}
+	} else if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || mediaType != "application/json" {
 		return nil, fmt.Errorf("expected application/json content-type")
 	}
 
@@ -79,9 +381,20 @@ func admissionReviewFromRequest(r *http.Request, deserializer runtime.Decoder) (
 	return admissionReviewRequest, nil
 }
 
-func validatePod(w http.ResponseWriter, r *http.Request) {
+func validate(w http.ResponseWriter, r *http.Request) {
 	logger.Printf("received message on validate")
 
+	// Don't evaluate anything until the dependencies needed to evaluate
+	// policy are reachable.
+	if !isReady() {
+		msg := "webhook is not ready"
+		logger.Printf(msg)
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds()))
+		w.WriteHeader(503)
+		w.Write([]byte(msg))
+		return
+	}
+
 	deserializer := codecs.UniversalDeserializer()
 
 	// Parse the AdmissionReview from the http request.
@@ -94,45 +407,162 @@ func validatePod(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Do server-side validation that we are only dealing with a pod resource. This
-	// should also be part of the ValidatingWebhookConfiguration in the cluster, but
-	// we should verify here before continuing.
-	podResource := metav1.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}
-	if admissionReviewRequest.Request.Resource != podResource {
-		msg := fmt.Sprintf("did not receive pod, got %s", admissionReviewRequest.Request.Resource.Resource)
+	// Do server-side validation that we are only dealing with a resource we know
+	// how to validate. This should also be part of the ValidatingWebhookConfiguration
+	// in the cluster, but we should verify here before continuing.
+	resource := admissionReviewRequest.Request.Resource
+	// Requests for subresources, such as pods/exec, aren't for the object
+	// itself, so they're validated as their own resource, and only if
+	// they're included.
+	if subResource := admissionReviewRequest.Request.SubResource; subResource != "" {
+		resource.Resource += "/" + subResource
+		if !contains(includeSubresources, resource.Resource) {
+			logger.Printf("allowing %s request, since it isn't an included subresource", resource.Resource)
+			writeAdmissionReview(w, admissionReviewRequest, &admissionv1.AdmissionResponse{Allowed: true})
+			return
+		}
+	}
+	object, ok := policy.NewObject(resource)
+	if !ok {
+		msg := fmt.Sprintf("did not receive a supported resource, got %s", resource.Resource)
 		logger.Printf(msg)
 		w.WriteHeader(400)
 		w.Write([]byte(msg))
 		return
 	}
 
-	// Decode the pod from the AdmissionReview.
+	// Decode the object from the AdmissionReview.
 	rawRequest := admissionReviewRequest.Request.Object.Raw
-	pod := corev1.Pod{}
-	if _, _, err := deserializer.Decode(rawRequest, nil, &pod); err != nil {
-		msg := fmt.Sprintf("error decoding raw pod: %v", err)
+	// An object that can't be decoded gets an explicit decision, rather than
+	// an error, so that what happens to it doesn't depend on the webhook's
+	// failurePolicy.
+	if _, _, err := deserializer.Decode(rawRequest, nil, object); err != nil {
+		msg := fmt.Sprintf("error decoding raw %s: %v", resource.Resource, err)
 		logger.Printf(msg)
-		w.WriteHeader(500)
-		w.Write([]byte(msg))
+		decision := policy.Decision{Allowed: true, Warnings: []string{msg}}
+		if rejectOnDecodeError {
+			decision = policy.Decision{Allowed: false, Message: msg}
+		}
+		logDecision(admissionReviewRequest.Request, decision)
+		writeAdmissionReview(w, admissionReviewRequest, admissionResponseFromDecision(decision))
 		return
 	}
 
-	// Create a response that either allows or rejects the pod creation
-	// based off of the value of the hello label. Also, check to see if
-	// we should supply a warning message even it is allowed.
-	admissionResponse := &admissionv1.AdmissionResponse{}
-	admissionResponse.Allowed = true
+	// Run every rule for the resource against the object and create a response
+	// that either allows or rejects it based off of what they found.
+	// Identical objects get the same decision, so reuse a cached one if
+	// there is one.
+	meta := policy.RequestMeta{
+		Resource:   resource,
+		Namespace:  admissionReviewRequest.Request.Namespace,
+		ObjectSize: len(admissionReviewRequest.Request.Object.Raw),
+		Username:   admissionReviewRequest.Request.UserInfo.Username,
+		Groups:     admissionReviewRequest.Request.UserInfo.Groups,
+		Operation:  string(admissionReviewRequest.Request.Operation),
+	}
 
-	if value, ok := pod.Labels["hello"]; !ok {
-		admissionResponse.Allowed = false
-		admissionResponse.Result = &metav1.Status{
-			Message: "missing required hello label",
+	// Updates carry the existing object, which rules can compare the new
+	// one against. If it can't be decoded, the object is evaluated as if
+	// it were being created.
+	rawOldObject := admissionReviewRequest.Request.OldObject.Raw
+	if len(rawOldObject) > 0 {
+		oldObject, _ := policy.NewObject(resource)
+		if _, _, err := deserializer.Decode(rawOldObject, nil, oldObject); err != nil {
+			logger.Printf("error decoding raw old %s: %v", resource.Resource, err)
+		} else {
+			meta.OldObject = oldObject
+		}
+	}
+
+	namespaceWarning := defaultRequestNamespace(&meta, object)
+
+	var decision policy.Decision
+	var cacheKey string
+	cached := false
+	if decisions != nil {
+		cacheKey = decisionCacheKey(meta, rawRequest, rawOldObject)
+		decision, cached = decisions.get(cacheKey)
+	}
+	if !cached {
+		decision = policy.Evaluate(r.Context(), object, meta)
+		// Decisions that depend on more than the request, such as on a
+		// lookup that failed or on how slow the rules happened to be,
+		// could be different next time, so they aren't cached.
+		if decisions != nil && decision.Cacheable {
+			decisions.put(cacheKey, decision)
 		}
-	} else if value == "world" {
-		admissionResponse.Warnings = []string{"world will be deprecated for hello in the future"}
 	}
+	// Decide again with the namespace warning and the downstream webhook's
+	// findings added, so that every warning is deduplicated and capped
+	// together.
+	findings := withNamespaceWarning(decision.Findings, namespaceWarning)
+	findings = append(findings, chainDownstream(r.Context(), admissionReviewRequest, decision)...)
+	decision = policy.Decide(findings, opts.MaxWarnings)
+	logDecision(admissionReviewRequest.Request, decision)
+	recordRejections(decision.Findings)
+	admissionResponse := admissionResponseFromDecision(decision)
 
-	// Construct the response, which is just another AdmissionReview.
+	// Record exactly which version of the object was evaluated, so that it
+	// can be correlated by systems consuming the audit log.
+	if emitObjectDigest {
+		admissionResponse.AuditAnnotations = map[string]string{
+			objectDigestAnnotation: objectDigest(rawRequest),
+		}
+	}
+
+	// Describe why the object was rejected in a form tooling can parse.
+	if emitViolations && !decision.Allowed {
+		doc, err := violationsDocument(decision.Findings)
+		if err != nil {
+			logger.Printf("error creating violations document: %v", err)
+		} else {
+			if admissionResponse.AuditAnnotations == nil {
+				admissionResponse.AuditAnnotations = map[string]string{}
+			}
+			admissionResponse.AuditAnnotations[violationsAnnotation] = doc
+		}
+	}
+
+	writeAdmissionReview(w, admissionReviewRequest, admissionResponse)
+}
+
+// defaultRequestNamespace sets the namespace the object is evaluated in to
+// its own if the request doesn't have one. Every resource that is validated
+// is namespaced, so rules that depend on the namespace would behave oddly
+// without one: if the object doesn't have one either, it is evaluated in
+// --default-namespace instead, and the returned warning says so.
+func defaultRequestNamespace(meta *policy.RequestMeta, object runtime.Object) string {
+	if meta.Namespace == "" {
+		if accessor, err := apimeta.Accessor(object); err == nil {
+			meta.Namespace = accessor.GetNamespace()
+		}
+	}
+	if meta.Namespace != "" {
+		return ""
+	}
+
+	meta.Namespace = defaultNamespace
+	warning := fmt.Sprintf("request for %s has no namespace, evaluated as if in namespace %s", meta.Resource.Resource, defaultNamespace)
+	logger.Printf(warning)
+	return warning
+}
+
+// defaultNamespaceRule is the rule that the warning about evaluating an
+// object in --default-namespace is recorded under.
+const defaultNamespaceRule = "default-namespace"
+
+// withNamespaceWarning returns the findings with the warning from
+// defaultRequestNamespace, if there is one, as the first of them.
+func withNamespaceWarning(findings []policy.Finding, warning string) []policy.Finding {
+	if warning == "" {
+		return findings
+	}
+	return append([]policy.Finding{{Rule: defaultNamespaceRule, Message: warning, Warning: true}}, findings...)
+}
+
+// writeAdmissionReview writes the response to the request, which is just
+// another AdmissionReview.
+func writeAdmissionReview(w http.ResponseWriter, admissionReviewRequest *admissionv1.AdmissionReview, admissionResponse *admissionv1.AdmissionResponse) {
 	var admissionReviewResponse admissionv1.AdmissionReview
 	admissionReviewResponse.Response = admissionResponse
 	admissionReviewResponse.SetGroupVersionKind(admissionReviewRequest.GroupVersionKind())
@@ -151,23 +581,56 @@ func validatePod(w http.ResponseWriter, r *http.Request) {
 	w.Write(resp)
 }
 
-func runWebhookServer(certFile, keyFile string) {
+// serverAddr returns the address for the webhook server to listen on.
+func serverAddr() string {
+	return net.JoinHostPort(listenAddress, strconv.Itoa(port))
+}
+
+// runWebhookServer serves the webhook, and the metrics if they're served
+// separately, until either server fails or the process is told to stop.
+func runWebhookServer(certFile, keyFile string) error {
 	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("error loading TLS certificate: %w", err)
 	}
 
+	var dependencies []dependency
+	for _, url := range dependencyURLs {
+		dependencies = append(dependencies, httpDependency{url: url})
+	}
+	if opts.Client != nil {
+		dependencies = append(dependencies, kubeDependency{client: opts.Client})
+	}
+	go keepWarmingUp(dependencies, warmUpTimeout)
+
 	fmt.Println("Starting webhook server")
-	http.HandleFunc("/validate", validatePod)
-	server := http.Server{
-		Addr: fmt.Sprintf(":%d", port),
+	http.HandleFunc("/validate", validate)
+	http.HandleFunc("/mutate", mutate)
+	http.HandleFunc("/preview", preview)
+	if enableSimulate {
+		http.HandleFunc("/simulate", simulate)
+	}
+	http.HandleFunc("/readyz", readyz)
+	webhookServer := &http.Server{
+		Addr: serverAddr(),
 		TLSConfig: &tls.Config{
 			Certificates: []tls.Certificate{cert},
 		},
 		ErrorLog: logger,
 	}
-
-	if err := server.ListenAndServeTLS("", ""); err != nil {
-		panic(err)
+	servers := []server{{
+		name:  "webhook",
+		http:  webhookServer,
+		serve: func() error { return webhookServer.ListenAndServeTLS("", "") },
+	}}
+	if metricsAddr == "" {
+		http.Handle("/metrics", promhttp.Handler())
+	} else {
+		metricsServer := newMetricsServer(metricsAddr)
+		servers = append(servers, server{name: "metrics", http: metricsServer, serve: metricsServer.ListenAndServe})
 	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
+	return runServers(servers, stop)
 }
@@ -0,0 +1,342 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	"validating-webhook/policy"
+)
+
+var podResource = metav1.GroupVersionResource{Version: "v1", Resource: "pods"}
+
+func TestMain(m *testing.M) {
+	// Handlers log every request, which would drown out test failures.
+	logger.SetOutput(ioutil.Discard)
+	decisionLogger.SetOutput(ioutil.Discard)
+	os.Exit(m.Run())
+}
+
+// useEngine makes an engine created from o the default for the test, and
+// sets the policy options the handlers read to o. Both are restored when
+// the test ends.
+func useEngine(t *testing.T, o policy.Options, cfg *policy.Config) {
+	t.Helper()
+	o.Logger = logger
+	engine, err := policy.NewEngine(o, cfg)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	previousOpts := opts
+	policy.SetDefault(engine)
+	opts = o
+	t.Cleanup(func() {
+		engine, _ := policy.NewEngine(policy.Options{}, nil)
+		policy.SetDefault(engine)
+		opts = previousOpts
+	})
+}
+
+// markReady marks the webhook as ready until the test ends.
+func markReady(t *testing.T) {
+	atomic.StoreInt32(&ready, 1)
+	t.Cleanup(func() { atomic.StoreInt32(&ready, 0) })
+}
+
+// testPod returns a pod in the default namespace that passes the rules that
+// are enabled by default, with the labels added.
+func testPod(podLabels map[string]string) *corev1.Pod {
+	pod := &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", Labels: map[string]string{"hello": "there"}},
+	}
+	for k, v := range podLabels {
+		pod.Labels[k] = v
+	}
+	return pod
+}
+
+// admissionReview returns an AdmissionReview for a request to create or
+// update obj, which can be nil for requests without an object.
+func admissionReview(t *testing.T, resource metav1.GroupVersionResource, namespace string, obj, oldObj runtime.Object) *admissionv1.AdmissionReview {
+	t.Helper()
+	review := &admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request: &admissionv1.AdmissionRequest{
+			UID:       types.UID("705ab4f5-6393-11e8-b7cc-42010a800002"),
+			Resource:  resource,
+			Namespace: namespace,
+			Operation: admissionv1.Create,
+		},
+	}
+	if obj != nil {
+		review.Request.Object.Raw = mustMarshal(t, obj)
+	}
+	if oldObj != nil {
+		review.Request.OldObject.Raw = mustMarshal(t, oldObj)
+		review.Request.Operation = admissionv1.Update
+	}
+	return review
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// serve posts body to handler as JSON and returns the recorded response.
+func serve(handler http.HandlerFunc, method string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+// serveReview sends the AdmissionReview to handler and returns the response
+// in the AdmissionReview it replied with, failing the test if it didn't
+// reply with one for the same request.
+func serveReview(t *testing.T, handler http.HandlerFunc, review *admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+	t.Helper()
+	rec := serve(handler, http.MethodPost, mustMarshal(t, review))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var reply admissionv1.AdmissionReview
+	if err := json.Unmarshal(rec.Body.Bytes(), &reply); err != nil {
+		t.Fatalf("error decoding response: %v", err)
+	}
+	if reply.Response == nil || reply.Response.UID != review.Request.UID {
+		t.Fatalf("response = %+v, want one for request %s", reply.Response, review.Request.UID)
+	}
+	return reply.Response
+}
+
+// resultMessage returns the message of the response's result, if any.
+func resultMessage(response *admissionv1.AdmissionResponse) string {
+	if response.Result == nil {
+		return ""
+	}
+	return response.Result.Message
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         policy.Options
+		review       func(t *testing.T) *admissionv1.AdmissionReview
+		wantAllowed  bool
+		wantMessage  string
+		wantWarnings []string
+	}{
+		{
+			name: "allowed",
+			review: func(t *testing.T) *admissionv1.AdmissionReview {
+				return admissionReview(t, podResource, "default", testPod(nil), nil)
+			},
+			wantAllowed: true,
+		},
+		{
+			name: "rejected",
+			opts: policy.Options{ForbidHostPID: true},
+			review: func(t *testing.T) *admissionv1.AdmissionReview {
+				pod := testPod(nil)
+				pod.Spec.HostPID = true
+				return admissionReview(t, podResource, "default", pod, nil)
+			},
+			wantMessage: "must not use the host PID namespace (hostPID)",
+		},
+		{
+			name: "warning",
+			review: func(t *testing.T) *admissionv1.AdmissionReview {
+				return admissionReview(t, podResource, "default", testPod(map[string]string{"hello": "world"}), nil)
+			},
+			wantAllowed:  true,
+			wantWarnings: []string{"world will be deprecated for hello in the future"},
+		},
+		{
+			name: "no namespace",
+			review: func(t *testing.T) *admissionv1.AdmissionReview {
+				pod := testPod(nil)
+				pod.Namespace = ""
+				return admissionReview(t, podResource, "", pod, nil)
+			},
+			wantAllowed:  true,
+			wantWarnings: []string{"request for pods has no namespace, evaluated as if in namespace default"},
+		},
+		{
+			name: "update of unprotected field",
+			opts: policy.Options{ImmutableFields: []string{"spec.serviceAccountName"}},
+			review: func(t *testing.T) *admissionv1.AdmissionReview {
+				old := testPod(nil)
+				old.Spec.ServiceAccountName = "web"
+				pod := old.DeepCopy()
+				pod.Spec.NodeName = "node-2"
+				return admissionReview(t, podResource, "default", pod, old)
+			},
+			wantAllowed: true,
+		},
+		{
+			name: "update of protected field",
+			opts: policy.Options{ImmutableFields: []string{"spec.serviceAccountName"}},
+			review: func(t *testing.T) *admissionv1.AdmissionReview {
+				old := testPod(nil)
+				old.Spec.ServiceAccountName = "web"
+				pod := old.DeepCopy()
+				pod.Spec.ServiceAccountName = "admin"
+				return admissionReview(t, podResource, "default", pod, old)
+			},
+			wantMessage: `field spec.serviceAccountName is immutable, but it was changed from "web" to "admin"`,
+		},
+		{
+			name: "update of object created before a rule was enabled",
+			opts: policy.Options{ForbidHostPID: true},
+			review: func(t *testing.T) *admissionv1.AdmissionReview {
+				old := testPod(nil)
+				old.Spec.HostPID = true
+				pod := old.DeepCopy()
+				pod.Labels["team"] = "web"
+				return admissionReview(t, podResource, "default", pod, old)
+			},
+			wantAllowed: true,
+		},
+		{
+			name: "undecodable object",
+			review: func(t *testing.T) *admissionv1.AdmissionReview {
+				review := admissionReview(t, podResource, "default", nil, nil)
+				review.Request.Object.Raw = []byte(`{"spec": "not a spec"}`)
+				return review
+			},
+			wantMessage: "error decoding raw pods",
+		},
+		{
+			name: "subresource that isn't included",
+			review: func(t *testing.T) *admissionv1.AdmissionReview {
+				review := admissionReview(t, podResource, "default", nil, nil)
+				review.Request.SubResource = "exec"
+				return review
+			},
+			wantAllowed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useEngine(t, tt.opts, nil)
+			markReady(t)
+			response := serveReview(t, validate, tt.review(t))
+			if response.Allowed != tt.wantAllowed {
+				t.Errorf("Allowed = %t, want %t", response.Allowed, tt.wantAllowed)
+			}
+			if msg := resultMessage(response); !strings.HasPrefix(msg, tt.wantMessage) || (tt.wantMessage == "" && msg != "") {
+				t.Errorf("Result.Message = %q, want %q", msg, tt.wantMessage)
+			}
+			if !reflect.DeepEqual(response.Warnings, tt.wantWarnings) {
+				t.Errorf("Warnings = %q, want %q", response.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	useEngine(t, policy.Options{}, nil)
+
+	t.Run("not ready", func(t *testing.T) {
+		rec := serve(validate, http.MethodPost, mustMarshal(t, admissionReview(t, podResource, "default", testPod(nil), nil)))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+		}
+		if rec.Header().Get("Retry-After") == "" {
+			t.Error("Retry-After isn't set")
+		}
+	})
+
+	markReady(t)
+
+	t.Run("unsupported resource", func(t *testing.T) {
+		resource := metav1.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+		rec := serve(validate, http.MethodPost, mustMarshal(t, admissionReview(t, resource, "default", nil, nil)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("wrong content type", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(mustMarshal(t, admissionReview(t, podResource, "default", testPod(nil), nil))))
+		req.Header.Set("Content-Type", "text/plain")
+		rec := httptest.NewRecorder()
+		validate(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestValidateAuditAnnotations(t *testing.T) {
+	useEngine(t, policy.Options{ForbidHostPID: true}, nil)
+	markReady(t)
+	emitObjectDigest, emitViolations = true, true
+	t.Cleanup(func() { emitObjectDigest, emitViolations = false, false })
+
+	pod := testPod(nil)
+	pod.Spec.HostPID = true
+	review := admissionReview(t, podResource, "default", pod, nil)
+	response := serveReview(t, validate, review)
+
+	if got, want := response.AuditAnnotations[objectDigestAnnotation], objectDigest(review.Request.Object.Raw); got != want {
+		t.Errorf("%s = %q, want %q", objectDigestAnnotation, got, want)
+	}
+	var doc struct {
+		Violations []policy.Violation `json:"violations"`
+	}
+	if err := json.Unmarshal([]byte(response.AuditAnnotations[violationsAnnotation]), &doc); err != nil {
+		t.Fatalf("error decoding %s: %v", violationsAnnotation, err)
+	}
+	if len(doc.Violations) != 1 || doc.Violations[0].Code != "HOST_PID" {
+		t.Errorf("violations = %+v, want one HOST_PID violation", doc.Violations)
+	}
+}
+
+func TestValidateDefaultDeny(t *testing.T) {
+	useEngine(t, policy.Options{}, &policy.Config{DefaultDeny: &policy.DefaultDeny{
+		Allow: []policy.AllowRule{{Name: "web", Selector: "team=web"}},
+		NoMatch: policy.NoMatchResponse{
+			Message:     "this cluster only runs approved workloads",
+			Reason:      "NotApproved",
+			Remediation: "request approval in the platform team's channel",
+		},
+	}})
+	markReady(t)
+
+	if response := serveReview(t, validate, admissionReview(t, podResource, "default", testPod(map[string]string{"team": "web"}), nil)); !response.Allowed {
+		t.Errorf("allowed pod: Allowed = false: %s", resultMessage(response))
+	}
+
+	response := serveReview(t, validate, admissionReview(t, podResource, "default", testPod(nil), nil))
+	if response.Allowed {
+		t.Fatal("pod no allow rule matches: Allowed = true, want false")
+	}
+	if got, want := resultMessage(response), "this cluster only runs approved workloads (request approval in the platform team's channel)"; got != want {
+		t.Errorf("Result.Message = %q, want %q", got, want)
+	}
+	if got, want := response.Result.Reason, metav1.StatusReason("NotApproved"); got != want {
+		t.Errorf("Result.Reason = %q, want %q", got, want)
+	}
+}
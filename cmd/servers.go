@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// shutdownTimeout is how long servers are given to finish the requests
+// they're handling when they're shut down.
+const shutdownTimeout = 10 * time.Second
+
+// server is an HTTP server, along with how to start it serving.
+type server struct {
+	name  string
+	http  *http.Server
+	serve func() error
+}
+
+// runServers runs every server until one of them fails or a signal is
+// received on stop, and then shuts all of them down together, so that the
+// webhook never keeps running with only some of its servers. It returns the
+// error that the first server to fail stopped with, or nil if it was told to
+// stop.
+func runServers(servers []server, stop <-chan os.Signal) error {
+	errs := make(chan error, len(servers))
+	for _, s := range servers {
+		s := s
+		go func() {
+			if err := s.serve(); err != http.ErrServerClosed {
+				errs <- fmt.Errorf("error serving %s: %w", s.name, err)
+			}
+		}()
+	}
+
+	var err error
+	select {
+	case err = <-errs:
+		logger.Printf("%v, shutting down", err)
+	case sig := <-stop:
+		logger.Printf("received %s, shutting down", sig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	for _, s := range servers {
+		if shutdownErr := s.http.Shutdown(ctx); shutdownErr != nil {
+			logger.Printf("error shutting down %s server: %v", s.name, shutdownErr)
+		}
+	}
+	return err
+}
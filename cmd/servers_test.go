@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+)
+
+// listeningServer returns a server serving on a free port, and a channel
+// that is closed once it has stopped serving.
+func listeningServer(t *testing.T, name string) (server, <-chan struct{}) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopped := make(chan struct{})
+	s := &http.Server{Handler: http.NotFoundHandler(), ErrorLog: logger}
+	return server{
+		name: name,
+		http: s,
+		serve: func() error {
+			defer close(stopped)
+			return s.Serve(listener)
+		},
+	}, stopped
+}
+
+func TestRunServers(t *testing.T) {
+	t.Run("server fails", func(t *testing.T) {
+		running, stopped := listeningServer(t, "running")
+		failing := server{
+			name:  "failing",
+			http:  &http.Server{},
+			serve: func() error { return errors.New("address already in use") },
+		}
+
+		err := runServers([]server{running, failing}, make(chan os.Signal))
+		if err == nil || err.Error() != "error serving failing: address already in use" {
+			t.Errorf("runServers() error = %v, want the failing server's error", err)
+		}
+		<-stopped
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		first, firstStopped := listeningServer(t, "first")
+		second, secondStopped := listeningServer(t, "second")
+		stop := make(chan os.Signal, 1)
+		stop <- syscall.SIGTERM
+
+		if err := runServers([]server{first, second}, stop); err != nil {
+			t.Errorf("runServers() error = %v, want nil", err)
+		}
+		<-firstStopped
+		<-secondStopped
+	})
+}
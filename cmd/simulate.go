@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"validating-webhook/policy"
+)
+
+var (
+	enableSimulate bool
+	simulateClient kubernetes.Interface
+)
+
+// simulateReport is what /simulate returns: how many of the namespace's
+// pods were evaluated, and which of them the current policy would reject.
+type simulateReport struct {
+	Namespace string               `json:"namespace"`
+	Evaluated int                  `json:"evaluated"`
+	Rejected  []simulatedRejection `json:"rejected,omitempty"`
+}
+
+// simulatedRejection is a pod that the current policy would reject.
+type simulatedRejection struct {
+	Pod     string   `json:"pod"`
+	Message string   `json:"message"`
+	Rules   []string `json:"rules"`
+}
+
+// simulate evaluates the current policy against the existing pods of the
+// namespace in the namespace query parameter, to show the impact of
+// enforcing a rule before it's enforced. Callers must send a bearer token
+// for a user the cluster allows to list the namespace's pods, since anyone
+// who can reach the webhook's port could otherwise read them.
+func simulate(w http.ResponseWriter, r *http.Request) {
+	logger.Printf("received message on simulate")
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		w.WriteHeader(405)
+		w.Write([]byte("simulate requires a GET with a namespace"))
+		return
+	}
+
+	namespace := r.URL.Query().Get("namespace")
+	if namespace == "" {
+		w.WriteHeader(400)
+		w.Write([]byte("namespace query parameter required"))
+		return
+	}
+
+	if status, err := authorizeSimulate(r.Context(), simulateClient, r, namespace); err != nil {
+		msg := fmt.Sprintf("not authorized to simulate policy in namespace %s: %v", namespace, err)
+		logger.Printf(msg)
+		w.WriteHeader(status)
+		w.Write([]byte(msg))
+		return
+	}
+
+	report, err := simulateNamespace(r.Context(), simulateClient, namespace)
+	if err != nil {
+		msg := fmt.Sprintf("error simulating policy in namespace %s: %v", namespace, err)
+		logger.Printf(msg)
+		w.WriteHeader(500)
+		w.Write([]byte(msg))
+		return
+	}
+
+	resp, err := json.Marshal(report)
+	if err != nil {
+		msg := fmt.Sprintf("error marshalling response json: %v", err)
+		logger.Printf(msg)
+		w.WriteHeader(500)
+		w.Write([]byte(msg))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// simulateNamespace lists the pods in the namespace, a page at a time, and
+// evaluates each of them against the current policy.
+func simulateNamespace(ctx context.Context, client kubernetes.Interface, namespace string) (simulateReport, error) {
+	report := simulateReport{Namespace: namespace}
+	listOptions := metav1.ListOptions{Limit: 500}
+	for {
+		pods, err := client.CoreV1().Pods(namespace).List(ctx, listOptions)
+		if err != nil {
+			return simulateReport{}, err
+		}
+
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			decision := policy.Evaluate(ctx, pod, policy.RequestMeta{Resource: previewResource, Namespace: namespace})
+			report.Evaluated++
+			if !decision.Allowed {
+				report.Rejected = append(report.Rejected, simulatedRejection{
+					Pod:     pod.Name,
+					Message: decision.Message,
+					Rules:   findingRules(decision.Findings),
+				})
+			}
+		}
+
+		if pods.Continue == "" {
+			return report, nil
+		}
+		listOptions.Continue = pods.Continue
+	}
+}
+
+// authorizeSimulate checks that the request's bearer token is for a user
+// that can list pods in the namespace, with a TokenReview and then a
+// SubjectAccessReview. If they can't, it returns the status to reply with.
+func authorizeSimulate(ctx context.Context, client kubernetes.Interface, r *http.Request, namespace string) (int, error) {
+	authorization := r.Header.Get("Authorization")
+	token := strings.TrimPrefix(authorization, "Bearer ")
+	if token == authorization || token == "" {
+		return http.StatusUnauthorized, errors.New("bearer token required")
+	}
+
+	tokenReview, err := client.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("error reviewing token: %w", err)
+	}
+	if !tokenReview.Status.Authenticated {
+		return http.StatusUnauthorized, errors.New("invalid bearer token")
+	}
+
+	user := tokenReview.Status.User
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	accessReview, err := client.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      "list",
+				Resource:  "pods",
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("error reviewing access: %w", err)
+	}
+	if !accessReview.Status.Allowed {
+		return http.StatusForbidden, fmt.Errorf("%s can't list pods", user.Username)
+	}
+	return http.StatusOK, nil
+}
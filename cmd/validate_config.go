@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"validating-webhook/policy"
+)
+
+var validateConfigCmd = &cobra.Command{
+	Use:   "validate-config",
+	Short: "Validate a policy configuration file",
+	Long: `Parse and validate a policy configuration file without starting the
+webhook server, exiting non-zero if there are any problems.
+
+Example:
+$ validating-webhook validate-config --config <config_file>`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if configFile == "" {
+			fmt.Println("--config required")
+			os.Exit(1)
+		}
+
+		cfg, err := policy.LoadConfig(configFile)
+		if err != nil {
+			if agg, ok := err.(utilerrors.Aggregate); ok {
+				for _, e := range agg.Errors() {
+					fmt.Println(e)
+				}
+			} else {
+				fmt.Println(err)
+			}
+			os.Exit(1)
+		}
+
+		if cfg.MigratedFrom != "" {
+			fmt.Printf("%s uses apiVersion %s, which was migrated to %s; update it to %s\n", configFile, cfg.MigratedFrom, policy.ConfigAPIVersion, policy.ConfigAPIVersion)
+		}
+		fmt.Printf("%s is valid\n", configFile)
+	},
+}
+
+func init() {
+	validateConfigCmd.Flags().StringVar(&configFile, "config", "", "Policy configuration file to validate")
+	rootCmd.AddCommand(validateConfigCmd)
+}
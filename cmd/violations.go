@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"encoding/json"
+
+	"validating-webhook/policy"
+)
+
+// violationsAnnotation is the audit annotation the violations document for
+// a rejection is returned in. The API server prefixes it with the webhook
+// name.
+const violationsAnnotation = "violations"
+
+// violationsDocument returns the JSON document listing every violation found
+// in the object, for tooling to parse.
+func violationsDocument(findings []policy.Finding) (string, error) {
+	doc, err := json.Marshal(struct {
+		Violations []policy.Violation `json:"violations"`
+	}{policy.Violations(findings)})
+	if err != nil {
+		return "", err
+	}
+	return string(doc), nil
+}
@@ -3,7 +3,12 @@ module validating-webhook
 go 1.16
 
 require (
+	github.com/prometheus/client_golang v1.11.0
 	github.com/spf13/cobra v1.2.1
+	github.com/spf13/pflag v1.0.5
+	github.com/tetratelabs/wazero v1.2.0
 	k8s.io/api v0.22.3
 	k8s.io/apimachinery v0.22.3
+	k8s.io/client-go v0.22.3
+	sigs.k8s.io/yaml v1.2.0
 )
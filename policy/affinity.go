@@ -0,0 +1,60 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// checkAntiAffinity rejects pods matched by the anti-affinity selector that
+// don't have a required podAntiAffinity term selecting pods like themselves,
+// so that they can't be co-located with each other.
+func (e *Engine) checkAntiAffinity(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	if !e.antiAffinitySelector.Matches(labels.Set(pod.Labels)) {
+		return nil, nil
+	}
+
+	if pod.Spec.Affinity != nil && pod.Spec.Affinity.PodAntiAffinity != nil {
+		for _, term := range pod.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+			selector, err := metav1.LabelSelectorAsSelector(term.LabelSelector)
+			if err != nil {
+				continue
+			}
+			if !selector.Empty() && selector.Matches(labels.Set(pod.Labels)) {
+				return nil, nil
+			}
+		}
+	}
+
+	return []Finding{{
+		Message: fmt.Sprintf("pods matching %s must set a required podAntiAffinity term that selects their own labels", e.antiAffinitySelector.String()),
+	}}, nil
+}
+
+// checkTopologySpread rejects pods with more than MaxTopologySpreadConstraints
+// topologySpreadConstraints, and warns about pods whose constraints are all
+// DoNotSchedule, since either can leave the pod with no node to run on.
+func (e *Engine) checkTopologySpread(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	constraints := pod.Spec.TopologySpreadConstraints
+	var findings []Finding
+	if count := len(constraints); count > e.opts.MaxTopologySpreadConstraints {
+		findings = append(findings, Finding{Message: fmt.Sprintf("has %d topologySpreadConstraints, more than the maximum of %d", count, e.opts.MaxTopologySpreadConstraints)})
+	}
+
+	strict := 0
+	for _, constraint := range constraints {
+		if constraint.WhenUnsatisfiable == corev1.DoNotSchedule {
+			strict++
+		}
+	}
+	if strict > 0 && strict == len(constraints) {
+		findings = append(findings, Finding{
+			Message: fmt.Sprintf("all %d topologySpreadConstraints are whenUnsatisfiable: %s, which can leave the pod unschedulable", strict, corev1.DoNotSchedule),
+			Warning: true,
+		})
+	}
+	return findings, nil
+}
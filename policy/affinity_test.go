@@ -0,0 +1,96 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckAntiAffinity(t *testing.T) {
+	antiAffinity := func(selector map[string]string) *corev1.Affinity {
+		return &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+				LabelSelector: &metav1.LabelSelector{MatchLabels: selector},
+				TopologyKey:   "kubernetes.io/hostname",
+			}},
+		}}
+	}
+	tests := []struct {
+		name     string
+		labels   map[string]string
+		affinity *corev1.Affinity
+		want     []string
+	}{
+		{
+			name:     "selects own labels",
+			labels:   map[string]string{"tier": "critical", "app": "db"},
+			affinity: antiAffinity(map[string]string{"app": "db"}),
+		},
+		{
+			name:     "selects other labels",
+			labels:   map[string]string{"tier": "critical", "app": "db"},
+			affinity: antiAffinity(map[string]string{"app": "web"}),
+			want:     []string{"anti-affinity: pods matching tier=critical must set a required podAntiAffinity term that selects their own labels"},
+		},
+		{
+			name:   "no anti-affinity",
+			labels: map[string]string{"tier": "critical"},
+			want:   []string{"anti-affinity: pods matching tier=critical must set a required podAntiAffinity term that selects their own labels"},
+		},
+		{
+			name:     "empty selector",
+			labels:   map[string]string{"tier": "critical"},
+			affinity: antiAffinity(nil),
+			want:     []string{"anti-affinity: pods matching tier=critical must set a required podAntiAffinity term that selects their own labels"},
+		},
+		{
+			name:   "not selected",
+			labels: map[string]string{"tier": "batch"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{AntiAffinitySelector: "tier=critical"}, nil)
+			pod := withMeta(testPod(corev1.PodSpec{Affinity: tt.affinity}), tt.labels, nil)
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestCheckTopologySpread(t *testing.T) {
+	constraint := func(when corev1.UnsatisfiableConstraintAction) corev1.TopologySpreadConstraint {
+		return corev1.TopologySpreadConstraint{MaxSkew: 1, TopologyKey: "zone", WhenUnsatisfiable: when}
+	}
+	tests := []struct {
+		name        string
+		constraints []corev1.TopologySpreadConstraint
+		want        []string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:        "mixed",
+			constraints: []corev1.TopologySpreadConstraint{constraint(corev1.DoNotSchedule), constraint(corev1.ScheduleAnyway)},
+		},
+		{
+			name:        "all strict",
+			constraints: []corev1.TopologySpreadConstraint{constraint(corev1.DoNotSchedule), constraint(corev1.DoNotSchedule)},
+			want:        []string{"topology-spread: warning: all 2 topologySpreadConstraints are whenUnsatisfiable: DoNotSchedule, which can leave the pod unschedulable"},
+		},
+		{
+			name:        "over max",
+			constraints: []corev1.TopologySpreadConstraint{constraint(corev1.ScheduleAnyway), constraint(corev1.ScheduleAnyway), constraint(corev1.ScheduleAnyway)},
+			want:        []string{"topology-spread: has 3 topologySpreadConstraints, more than the maximum of 2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{MaxTopologySpreadConstraints: 2}, nil)
+			assertFindings(t, e, testPod(corev1.PodSpec{TopologySpreadConstraints: tt.constraints}), RequestMeta{}, tt.want)
+		})
+	}
+}
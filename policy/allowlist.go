@@ -0,0 +1,56 @@
+package policy
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Allowlist is a set of allowed values that can be replaced while engines
+// are using it, such as when it is reloaded from a file.
+type Allowlist struct {
+	values atomic.Value
+}
+
+// NewAllowlist returns an allowlist of values.
+func NewAllowlist(values []string) *Allowlist {
+	a := &Allowlist{}
+	a.Set(values)
+	return a
+}
+
+// Set replaces the allowed values.
+func (a *Allowlist) Set(values []string) {
+	set := map[string]bool{}
+	for _, value := range values {
+		set[value] = true
+	}
+	a.values.Store(set)
+}
+
+// Allowed reports whether value is in the allowlist.
+func (a *Allowlist) Allowed(value string) bool {
+	return a.values.Load().(map[string]bool)[value]
+}
+
+// ReadAllowlistFile reads allowed values from a file with one value per
+// line. Blank lines and lines starting with # are ignored.
+func ReadAllowlistFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var values []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		values = append(values, line)
+	}
+	return values, scanner.Err()
+}
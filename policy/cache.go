@@ -0,0 +1,77 @@
+package policy
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultLookupCacheSize is the most lookups that are cached at once unless
+// Options.LookupCacheSize says otherwise.
+const DefaultLookupCacheSize = 10000
+
+// lookupCache caches the results of lookups against the API server for ttl,
+// so that every admission request doesn't cause another call. Errors are
+// never cached. At most size results are cached: once it's full, expired
+// results are dropped to make room, and if none have expired the oldest
+// one is.
+type lookupCache struct {
+	ttl     time.Duration
+	size    int
+	mu      sync.Mutex
+	entries map[string]lookupCacheEntry
+}
+
+type lookupCacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+func newLookupCache(ttl time.Duration, size int) *lookupCache {
+	if size <= 0 {
+		size = DefaultLookupCacheSize
+	}
+	return &lookupCache{ttl: ttl, size: size, entries: map[string]lookupCacheEntry{}}
+}
+
+// get returns the cached value for key, calling fetch to get it if there is
+// no unexpired value cached.
+func (c *lookupCache) get(key string, fetch func() (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.value, nil
+	}
+
+	value, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.size {
+		c.evict(time.Now())
+	}
+	c.entries[key] = lookupCacheEntry{value: value, expires: time.Now().Add(c.ttl)}
+	return value, nil
+}
+
+// evict makes room for another entry in the full cache, by dropping every
+// entry that has expired by now or, if none have, the oldest one. It must be
+// called with mu held.
+func (c *lookupCache) evict(now time.Time) {
+	oldest := ""
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+			continue
+		}
+		if oldest == "" || entry.expires.Before(c.entries[oldest].expires) {
+			oldest = key
+		}
+	}
+	if len(c.entries) >= c.size {
+		delete(c.entries, oldest)
+	}
+}
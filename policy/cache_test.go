@@ -0,0 +1,111 @@
+package policy
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// fetchValue returns a fetch func for the lookup cache that returns value,
+// counting its calls in calls.
+func fetchValue(value interface{}, calls *int) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		*calls++
+		return value, nil
+	}
+}
+
+// cachedKeys returns the keys the cache has entries for, sorted.
+func cachedKeys(c *lookupCache) []string {
+	var keys []string
+	for key := range c.entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestLookupCacheGet(t *testing.T) {
+	c := newLookupCache(time.Minute, 0)
+	calls := 0
+	for i := 0; i < 2; i++ {
+		value, err := c.get("key", fetchValue("value", &calls))
+		if err != nil || value != "value" {
+			t.Fatalf("get() = %v, %v, want value", value, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("fetched %d times, want 1", calls)
+	}
+
+	// Expired values are fetched again.
+	c.entries["key"] = lookupCacheEntry{value: "stale", expires: time.Now().Add(-time.Second)}
+	if value, _ := c.get("key", fetchValue("fresh", &calls)); value != "fresh" {
+		t.Errorf("get() of an expired value = %v, want fresh", value)
+	}
+
+	// Errors aren't cached.
+	if _, err := c.get("failing", func() (interface{}, error) { return nil, errors.New("not found") }); err == nil {
+		t.Error("get() error = nil, want the fetch error")
+	}
+	if _, ok := c.entries["failing"]; ok {
+		t.Error("get() cached the result of a failed fetch")
+	}
+}
+
+func TestLookupCacheEviction(t *testing.T) {
+	calls := 0
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		entries map[string]lookupCacheEntry
+		want    []string
+	}{
+		{
+			name: "expired entries",
+			entries: map[string]lookupCacheEntry{
+				"expired":   {expires: now.Add(-time.Second)},
+				"expired-2": {expires: now.Add(-time.Minute)},
+				"current":   {expires: now.Add(time.Minute)},
+			},
+			want: []string{"current", "new"},
+		},
+		{
+			name: "oldest entry",
+			entries: map[string]lookupCacheEntry{
+				"oldest": {expires: now.Add(time.Second)},
+				"newer":  {expires: now.Add(time.Minute)},
+				"newest": {expires: now.Add(time.Hour)},
+			},
+			want: []string{"new", "newer", "newest"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newLookupCache(time.Minute, 3)
+			c.entries = tt.entries
+			c.get("new", fetchValue("value", &calls))
+			if got := cachedKeys(c); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cached keys = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupCacheRefreshWhenFull(t *testing.T) {
+	calls := 0
+	c := newLookupCache(time.Minute, 2)
+	c.entries = map[string]lookupCacheEntry{
+		"a": {expires: time.Now().Add(-time.Second)},
+		"b": {expires: time.Now().Add(time.Minute)},
+	}
+	// Refreshing a key that's already cached doesn't need room for it.
+	c.get("a", fetchValue("value", &calls))
+	if got, want := cachedKeys(c), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cached keys = %q, want %q", got, want)
+	}
+}
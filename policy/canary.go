@@ -0,0 +1,35 @@
+package policy
+
+import (
+	"hash/fnv"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// canaryBucket returns which of 100 buckets the object falls in. It is a
+// hash of the object's UID, so the same object always lands in the same
+// bucket and gets the same treatment every time it's evaluated. Objects
+// without a UID are hashed by namespace and name instead.
+func canaryBucket(obj runtime.Object) int {
+	key := ""
+	if accessor, err := meta.Accessor(obj); err == nil {
+		key = string(accessor.GetUID())
+		if key == "" {
+			key = accessor.GetNamespace() + "/" + accessor.GetName() + accessor.GetGenerateName()
+		}
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % 100)
+}
+
+// enforces reports whether the rule's findings should reject the object,
+// rather than only be warned about because the object falls outside of the
+// rule's enforcement percentage.
+func (r rule) enforces(obj runtime.Object) bool {
+	if r.warnOnly {
+		return false
+	}
+	return r.enforcePercent >= 100 || canaryBucket(obj) < r.enforcePercent
+}
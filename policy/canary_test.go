@@ -0,0 +1,52 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestEnforcePercent(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test", UID: types.UID("0b7d9c43-5c1e-4a7e-9a53-51e3c3f4e2a1")}}
+	bucket := canaryBucket(pod)
+	if again := canaryBucket(pod.DeepCopy()); again != bucket {
+		t.Fatalf("canaryBucket() = %d then %d, want the same bucket every time", bucket, again)
+	}
+
+	tests := []struct {
+		name    string
+		percent int
+		want    []string
+	}{
+		{
+			name:    "enforced for none",
+			percent: 0,
+			want:    []string{"hello-label: warning: missing required hello label"},
+		},
+		{
+			name:    "enforced for all",
+			percent: 100,
+			want:    []string{"hello-label: missing required hello label"},
+		},
+		{
+			name:    "enforced for the object's bucket",
+			percent: bucket + 1,
+			want:    []string{"hello-label: missing required hello label"},
+		},
+		{
+			name:    "not enforced for the object's bucket",
+			percent: bucket,
+			want:    []string{"hello-label: warning: missing required hello label"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			percent := tt.percent
+			cfg := &Config{Rules: []RuleConfig{{Name: "hello-label", EnforcePercent: &percent}}}
+			assertFindings(t, newTestEngine(t, Options{}, cfg), pod, RequestMeta{}, tt.want)
+		})
+	}
+}
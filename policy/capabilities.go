@@ -0,0 +1,44 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// checkCapabilitiesAdd rejects containers that add any capabilities in
+// namespaces with the restricted namespace label, which are held to a
+// stricter posture than the rest of the cluster.
+func (e *Engine) checkCapabilitiesAdd(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	for _, container := range allContainers(pod) {
+		sc := container.SecurityContext
+		if sc == nil || sc.Capabilities == nil || len(sc.Capabilities.Add) == 0 {
+			continue
+		}
+		added := make([]string, 0, len(sc.Capabilities.Add))
+		for _, capability := range sc.Capabilities.Add {
+			added = append(added, string(capability))
+		}
+		findings = append(findings, Finding{
+			Container: container.Name,
+			Message:   fmt.Sprintf("adds capabilities %s, but no capabilities may be added in namespace %s (%s)", strings.Join(added, ", "), meta.Namespace, e.restrictedLabel),
+		})
+	}
+	// Only look up the namespace for pods that add capabilities.
+	if len(findings) == 0 {
+		return nil, nil
+	}
+
+	nsLabels, err := e.namespaceLabels(ctx, meta.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up namespace %s: %w", meta.Namespace, err)
+	}
+	value, ok := nsLabels[e.restrictedLabel.key]
+	if !ok || !e.restrictedLabel.matches(e.restrictedLabel.key, value) {
+		return nil, nil
+	}
+	return findings, nil
+}
@@ -0,0 +1,47 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCheckCapabilitiesAdd(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		testNamespace("restricted", map[string]string{"posture": "restricted"}),
+		testNamespace("baseline", nil),
+	)
+	tests := []struct {
+		name         string
+		namespace    string
+		capabilities *corev1.Capabilities
+		want         []string
+	}{
+		{
+			name:         "add in restricted namespace",
+			namespace:    "restricted",
+			capabilities: &corev1.Capabilities{Add: []corev1.Capability{"NET_ADMIN", "SYS_TIME"}},
+			want:         []string{"capabilities-add: container app: adds capabilities NET_ADMIN, SYS_TIME, but no capabilities may be added in namespace restricted (posture=restricted)"},
+		},
+		{
+			name:         "drop in restricted namespace",
+			namespace:    "restricted",
+			capabilities: &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+		},
+		{
+			name:         "add elsewhere",
+			namespace:    "baseline",
+			capabilities: &corev1.Capabilities{Add: []corev1.Capability{"NET_ADMIN"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{RestrictedNamespaceLabel: "posture=restricted", Client: client}, nil)
+			pod := testPod(corev1.PodSpec{Containers: []corev1.Container{{Name: "app", SecurityContext: &corev1.SecurityContext{Capabilities: tt.capabilities}}}})
+			pod.Namespace = tt.namespace
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
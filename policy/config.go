@@ -0,0 +1,272 @@
+package policy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// ConfigAPIVersion is the current version of the config schema.
+const ConfigAPIVersion = "webhook.trstringer.com/v1"
+
+// configAPIVersionV1Alpha1 is the original config schema, which configs
+// without an apiVersion are assumed to use.
+const configAPIVersionV1Alpha1 = "webhook.trstringer.com/v1alpha1"
+
+// Config is the policy configuration, which adjusts the bundled rules.
+type Config struct {
+	// APIVersion is the version of the config schema. It may be left empty
+	// for configs that are created in code rather than loaded from a file.
+	APIVersion string       `json:"apiVersion"`
+	Rules      []RuleConfig `json:"rules"`
+	// LabelFormats are the formats that pod label values must be in.
+	LabelFormats []LabelFormat `json:"labelFormats,omitempty"`
+	// RequiredVolumes are the volumes that every pod must have.
+	RequiredVolumes []RequiredVolume `json:"requiredVolumes,omitempty"`
+	// PriorityClassTiers restrict priority classes to namespace tiers.
+	PriorityClassTiers []PriorityClassTiers `json:"priorityClassTiers,omitempty"`
+	// Sidecar is the container injected into pods that opt in to it.
+	Sidecar *corev1.Container `json:"sidecar,omitempty"`
+	// FreezeWindows are the maintenance windows during which new pods are
+	// rejected in frozen namespaces.
+	FreezeWindows []FreezeWindow `json:"freezeWindows,omitempty"`
+	// DeprecatedAnnotations are deprecated pod annotations to warn about,
+	// in addition to the bundled ones.
+	DeprecatedAnnotations []DeprecatedAnnotation `json:"deprecatedAnnotations,omitempty"`
+	// RequesterRestrictions lock namespaces to the users and groups that
+	// are allowed to make changes in them.
+	RequesterRestrictions []RequesterRestriction `json:"requesterRestrictions,omitempty"`
+	// DefaultDeny, if set, rejects objects that none of its allow rules
+	// match.
+	DefaultDeny *DefaultDeny `json:"defaultDeny,omitempty"`
+
+	// MigratedFrom is the older apiVersion the config was migrated from when
+	// it was loaded, if any.
+	MigratedFrom string `json:"-"`
+}
+
+// Values for RuleConfig.Action.
+const (
+	ActionReject = "reject"
+	ActionWarn   = "warn"
+)
+
+// RuleConfig holds the settings for one of the bundled rules.
+type RuleConfig struct {
+	// Name is the name of the rule these settings apply to.
+	Name string `json:"name"`
+	// Priority controls evaluation order. Rules with a higher priority are
+	// evaluated first, so their messages appear first in rejections. Rules
+	// with equal priority keep their bundled order.
+	Priority int `json:"priority"`
+	// Action is what to do when the rule finds a problem: reject the object
+	// (the default), or only warn about it.
+	Action string `json:"action,omitempty"`
+	// EnforcePercent rolls out enforcement of the rule gradually. Only this
+	// percentage of objects, chosen deterministically by UID, are rejected by
+	// the rule; it only warns about the rest. Unset enforces it for all.
+	EnforcePercent *int `json:"enforcePercent,omitempty"`
+	// DocURL links to documentation on fixing the rule's violations, and is
+	// added to each of their messages. It is a template that can use
+	// {{ .Rule }}, {{ .Namespace }}, {{ .Name }} and {{ .Container }}.
+	DocURL string `json:"docURL,omitempty"`
+}
+
+// configV1Alpha1 is the v1alpha1 config schema. It must not be changed, so
+// that v1alpha1 configs keep being parsed the way they always were.
+type configV1Alpha1 struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Rules      []struct {
+		Name     string `json:"name"`
+		Priority int    `json:"priority"`
+		Action   string `json:"action,omitempty"`
+	} `json:"rules"`
+}
+
+// migrate converts the v1alpha1 config to the current version.
+func (c *configV1Alpha1) migrate() *Config {
+	cfg := &Config{APIVersion: ConfigAPIVersion, MigratedFrom: configAPIVersionV1Alpha1}
+	for _, rc := range c.Rules {
+		cfg.Rules = append(cfg.Rules, RuleConfig{Name: rc.Name, Priority: rc.Priority, Action: rc.Action})
+	}
+	return cfg
+}
+
+// LoadConfig reads, parses and validates the policy configuration at path.
+// Configs using an older supported apiVersion are migrated to the current
+// one. Unknown fields are treated as errors so that typos aren't silently
+// ignored.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config: %w", err)
+	}
+
+	// Find out which schema the config uses before parsing the rest of it.
+	var header struct {
+		APIVersion string `json:"apiVersion"`
+	}
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("error parsing config: %w", err)
+	}
+
+	cfg := &Config{}
+	switch header.APIVersion {
+	case ConfigAPIVersion:
+		if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+			return nil, fmt.Errorf("error parsing config: %w", err)
+		}
+	case "", configAPIVersionV1Alpha1:
+		old := &configV1Alpha1{}
+		if err := yaml.UnmarshalStrict(data, old); err != nil {
+			return nil, fmt.Errorf("error parsing %s config: %w", configAPIVersionV1Alpha1, err)
+		}
+		cfg = old.migrate()
+	default:
+		return nil, fmt.Errorf("unsupported config apiVersion %s: must be %s, or %s which is migrated", header.APIVersion, ConfigAPIVersion, configAPIVersionV1Alpha1)
+	}
+
+	if errs := cfg.Validate(); len(errs) > 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+
+	return cfg, nil
+}
+
+// Validate returns every problem with the configuration, rather than just
+// the first, so they can all be fixed at once.
+func (c *Config) Validate() []error {
+	var errs []error
+	if c.APIVersion != "" && c.APIVersion != ConfigAPIVersion {
+		errs = append(errs, fmt.Errorf("unsupported apiVersion %s: must be %s", c.APIVersion, ConfigAPIVersion))
+	}
+
+	seen := map[string]bool{}
+	for i, rc := range c.Rules {
+		switch {
+		case rc.Name == "":
+			errs = append(errs, fmt.Errorf("rules[%d]: name is required", i))
+		case seen[rc.Name]:
+			errs = append(errs, fmt.Errorf("rules[%d]: duplicate rule %s", i, rc.Name))
+		case !IsKnownRule(rc.Name):
+			errs = append(errs, fmt.Errorf("rules[%d]: unknown rule %s", i, rc.Name))
+		}
+		seen[rc.Name] = true
+
+		if rc.Action != "" && rc.Action != ActionReject && rc.Action != ActionWarn {
+			errs = append(errs, fmt.Errorf("rules[%d]: invalid action %s: must be %s or %s", i, rc.Action, ActionReject, ActionWarn))
+		}
+
+		if rc.DocURL != "" {
+			if _, err := parseDocURL(rc.DocURL); err != nil {
+				errs = append(errs, fmt.Errorf("rules[%d]: invalid docURL: %v", i, err))
+			}
+		}
+
+		if rc.EnforcePercent != nil && (*rc.EnforcePercent < 0 || *rc.EnforcePercent > 100) {
+			errs = append(errs, fmt.Errorf("rules[%d]: invalid enforcePercent %d: must be between 0 and 100", i, *rc.EnforcePercent))
+		}
+	}
+
+	for i, lf := range c.LabelFormats {
+		if lf.Label == "" {
+			errs = append(errs, fmt.Errorf("labelFormats[%d]: label is required", i))
+		}
+		if _, ok := labelFormats[lf.Format]; !ok {
+			errs = append(errs, fmt.Errorf("labelFormats[%d]: unknown format %q: must be one of %s", i, lf.Format, strings.Join(labelFormatNames(), ", ")))
+		}
+	}
+
+	for i, rv := range c.RequiredVolumes {
+		if rv.Name == "" {
+			errs = append(errs, fmt.Errorf("requiredVolumes[%d]: name is required", i))
+		}
+	}
+
+	seenClasses := map[string]bool{}
+	for i, pct := range c.PriorityClassTiers {
+		switch {
+		case pct.PriorityClass == "":
+			errs = append(errs, fmt.Errorf("priorityClassTiers[%d]: priorityClass is required", i))
+		case seenClasses[pct.PriorityClass]:
+			errs = append(errs, fmt.Errorf("priorityClassTiers[%d]: duplicate priority class %s", i, pct.PriorityClass))
+		}
+		seenClasses[pct.PriorityClass] = true
+
+		if len(pct.Tiers) == 0 {
+			errs = append(errs, fmt.Errorf("priorityClassTiers[%d]: at least one tier is required", i))
+		}
+	}
+
+	for i, fw := range c.FreezeWindows {
+		if fw.Name == "" {
+			errs = append(errs, fmt.Errorf("freezeWindows[%d]: name is required", i))
+		}
+		if _, err := parseCronSchedule(fw.Schedule); err != nil {
+			errs = append(errs, fmt.Errorf("freezeWindows[%d]: %v", i, err))
+		}
+		if fw.Duration.Duration <= 0 {
+			errs = append(errs, fmt.Errorf("freezeWindows[%d]: duration must be positive", i))
+		}
+	}
+
+	for i, da := range c.DeprecatedAnnotations {
+		if da.Annotation == "" {
+			errs = append(errs, fmt.Errorf("deprecatedAnnotations[%d]: annotation is required", i))
+		}
+		if da.Replacement == "" {
+			errs = append(errs, fmt.Errorf("deprecatedAnnotations[%d]: replacement is required", i))
+		}
+	}
+
+	seenNamespaces := map[string]bool{}
+	for i, rr := range c.RequesterRestrictions {
+		switch {
+		case rr.Namespace == "":
+			errs = append(errs, fmt.Errorf("requesterRestrictions[%d]: namespace is required", i))
+		case seenNamespaces[rr.Namespace]:
+			errs = append(errs, fmt.Errorf("requesterRestrictions[%d]: duplicate namespace %s", i, rr.Namespace))
+		}
+		seenNamespaces[rr.Namespace] = true
+
+		if len(rr.Users) == 0 && len(rr.Groups) == 0 {
+			errs = append(errs, fmt.Errorf("requesterRestrictions[%d]: at least one user or group is required", i))
+		}
+	}
+
+	if c.DefaultDeny != nil {
+		if len(c.DefaultDeny.Allow) == 0 {
+			errs = append(errs, fmt.Errorf("defaultDeny: at least one allow rule is required"))
+		}
+		seenAllowRules := map[string]bool{}
+		for i, ar := range c.DefaultDeny.Allow {
+			switch {
+			case ar.Name == "":
+				errs = append(errs, fmt.Errorf("defaultDeny.allow[%d]: name is required", i))
+			case seenAllowRules[ar.Name]:
+				errs = append(errs, fmt.Errorf("defaultDeny.allow[%d]: duplicate allow rule %s", i, ar.Name))
+			}
+			seenAllowRules[ar.Name] = true
+
+			if _, err := labels.Parse(ar.Selector); err != nil {
+				errs = append(errs, fmt.Errorf("defaultDeny.allow[%d]: invalid selector: %v", i, err))
+			}
+		}
+	}
+
+	if c.Sidecar != nil {
+		if c.Sidecar.Name == "" {
+			errs = append(errs, fmt.Errorf("sidecar: name is required"))
+		}
+		if c.Sidecar.Image == "" {
+			errs = append(errs, fmt.Errorf("sidecar: image is required"))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,110 @@
+package policy
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    *Config
+		wantErr string
+	}{
+		{
+			name:   "current",
+			config: "apiVersion: webhook.trstringer.com/v1\nrules:\n- name: host-pid\n  priority: 5\n  action: warn\n",
+			want:   &Config{APIVersion: ConfigAPIVersion, Rules: []RuleConfig{{Name: "host-pid", Priority: 5, Action: ActionWarn}}},
+		},
+		{
+			name:   "migrated",
+			config: "rules:\n- name: host-pid\n  priority: 5\n",
+			want:   &Config{APIVersion: ConfigAPIVersion, Rules: []RuleConfig{{Name: "host-pid", Priority: 5}}, MigratedFrom: configAPIVersionV1Alpha1},
+		},
+		{
+			name:    "unknown field",
+			config:  "apiVersion: webhook.trstringer.com/v1\nrules:\n- name: host-pid\n  prority: 5\n",
+			wantErr: "error parsing config",
+		},
+		{
+			name:    "unsupported apiVersion",
+			config:  "apiVersion: webhook.trstringer.com/v2\n",
+			wantErr: "unsupported config apiVersion webhook.trstringer.com/v2",
+		},
+		{
+			name:    "invalid",
+			config:  "apiVersion: webhook.trstringer.com/v1\nrules:\n- name: no-such-rule\n",
+			wantErr: "rules[0]: unknown rule no-such-rule",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := ioutil.WriteFile(path, []byte(tt.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadConfig(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadConfig() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	percent := 150
+	cfg := &Config{
+		Rules: []RuleConfig{
+			{Name: "host-pid", Action: "block"},
+			{Name: "host-pid", EnforcePercent: &percent},
+			{Name: "hello-label", DocURL: "{{ .Rule"},
+		},
+		LabelFormats:  []LabelFormat{{Format: "roman"}},
+		FreezeWindows: []FreezeWindow{{Name: "weekend", Schedule: "0 18 * *"}},
+		DefaultDeny: &DefaultDeny{Allow: []AllowRule{
+			{Selector: "team in (web"},
+			{Name: "web"},
+			{Name: "web"},
+		}},
+	}
+
+	var got []string
+	for _, err := range cfg.Validate() {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		"rules[0]: invalid action block: must be reject or warn",
+		"rules[1]: duplicate rule host-pid",
+		"rules[1]: invalid enforcePercent 150: must be between 0 and 100",
+		"rules[2]: invalid docURL:",
+		"labelFormats[0]: label is required",
+		`labelFormats[0]: unknown format "roman"`,
+		"freezeWindows[0]:",
+		"freezeWindows[0]: duration must be positive",
+		"defaultDeny.allow[0]: name is required",
+		"defaultDeny.allow[0]: invalid selector:",
+		"defaultDeny.allow[2]: duplicate allow rule web",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Validate() = %q, want errors starting with %q", got, want)
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("Validate()[%d] = %q, want it to start with %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DefaultDeny rejects every object that none of its allow rules match, for
+// positive-security policies that list what is allowed rather than what
+// isn't. Objects that an allow rule matches are still checked by the other
+// rules.
+type DefaultDeny struct {
+	// Allow are the allow rules. An object is allowed if any of them match.
+	Allow []AllowRule `json:"allow"`
+	// NoMatch is the response for objects that no allow rule matches.
+	NoMatch NoMatchResponse `json:"noMatch,omitempty"`
+}
+
+// AllowRule matches objects by resource, namespace and labels. Each field
+// that is left empty matches every object.
+type AllowRule struct {
+	// Name identifies the rule in rejections.
+	Name string `json:"name"`
+	// Resources are the resources, such as pods, that the rule matches.
+	Resources []string `json:"resources,omitempty"`
+	// Namespaces are the namespaces that the rule matches.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Selector is a label selector, such as team in (web, data), that the
+	// object's labels must match.
+	Selector string `json:"selector,omitempty"`
+}
+
+// NoMatchResponse customizes the rejection of objects that no allow rule
+// matches.
+type NoMatchResponse struct {
+	// Message replaces the default message, which names the allow rules.
+	Message string `json:"message,omitempty"`
+	// Reason is returned as the reason of the admission response's status,
+	// such as Forbidden, for clients that act on it.
+	Reason string `json:"reason,omitempty"`
+	// Remediation is guidance on how to get the object allowed, such as
+	// where to request a new allow rule. It is added to the message and to
+	// the violation.
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// allowRule is an allow rule with its selector parsed.
+type allowRule struct {
+	AllowRule
+	selector labels.Selector
+}
+
+// matches reports whether the rule matches the object.
+func (r allowRule) matches(accessor metav1.Object, meta RequestMeta) bool {
+	if len(r.Resources) > 0 && !contains(r.Resources, meta.Resource.Resource) {
+		return false
+	}
+	if len(r.Namespaces) > 0 && !contains(r.Namespaces, meta.Namespace) {
+		return false
+	}
+	return r.selector.Matches(labels.Set(accessor.GetLabels()))
+}
+
+// checkDefaultDeny rejects objects of any resource that no allow rule
+// matches, with the configured no-match response.
+func (e *Engine) checkDefaultDeny(ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+	accessor, err := apimeta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, r := range e.allowRules {
+		if r.matches(accessor, meta) {
+			return nil, nil
+		}
+		names = append(names, r.Name)
+	}
+
+	noMatch := e.defaultDeny.NoMatch
+	msg := noMatch.Message
+	if msg == "" {
+		msg = fmt.Sprintf("no allow rule matches this %s in namespace %s, and default-deny mode rejects everything else: the allow rules are %s", meta.Resource.Resource, meta.Namespace, strings.Join(names, ", "))
+	}
+	return []Finding{{Message: msg, Reason: noMatch.Reason, Remediation: noMatch.Remediation}}, nil
+}
@@ -0,0 +1,90 @@
+package policy
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// allowPlatformAndWeb allows anything in the platform's namespaces, and pods
+// of the web and data teams anywhere.
+var allowPlatformAndWeb = []AllowRule{
+	{Name: "platform", Namespaces: []string{"kube-system", "monitoring"}},
+	{Name: "web-pods", Resources: []string{"pods"}, Selector: "team in (web, data)"},
+}
+
+func TestCheckDefaultDeny(t *testing.T) {
+	inNamespace := func(pod *corev1.Pod, namespace string) *corev1.Pod {
+		pod.Namespace = namespace
+		return pod
+	}
+	tests := []struct {
+		name    string
+		noMatch NoMatchResponse
+		obj     runtime.Object
+		want    []string
+	}{
+		{
+			name: "matched by namespace",
+			obj:  inNamespace(testPod(corev1.PodSpec{}), "monitoring"),
+		},
+		{
+			name: "matched by resource and selector",
+			obj:  withMeta(testPod(corev1.PodSpec{}), map[string]string{"team": "web"}, nil),
+		},
+		{
+			name: "selector doesn't match",
+			obj:  withMeta(testPod(corev1.PodSpec{}), map[string]string{"team": "ml"}, nil),
+			want: []string{"default-deny: no allow rule matches this pods in namespace default, and default-deny mode rejects everything else: the allow rules are platform, web-pods"},
+		},
+		{
+			name: "resource doesn't match",
+			obj:  &corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Labels: map[string]string{"team": "web"}}},
+			want: []string{"default-deny: no allow rule matches this services in namespace default, and default-deny mode rejects everything else: the allow rules are platform, web-pods"},
+		},
+		{
+			name: "configured no-match response",
+			noMatch: NoMatchResponse{
+				Message:     "this cluster only runs approved workloads",
+				Reason:      "NotApproved",
+				Remediation: "request approval in the platform team's channel",
+			},
+			obj:  testPod(corev1.PodSpec{}),
+			want: []string{"default-deny: this cluster only runs approved workloads (request approval in the platform team's channel)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{}, &Config{DefaultDeny: &DefaultDeny{Allow: allowPlatformAndWeb, NoMatch: tt.noMatch}})
+			assertFindings(t, e, tt.obj, RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestDefaultDenyNoMatchResponse(t *testing.T) {
+	noMatch := NoMatchResponse{
+		Message:     "this cluster only runs approved workloads",
+		Reason:      "NotApproved",
+		Remediation: "request approval in the platform team's channel",
+	}
+	e := newTestEngine(t, Options{}, &Config{DefaultDeny: &DefaultDeny{Allow: allowPlatformAndWeb, NoMatch: noMatch}})
+	decision := e.Evaluate(context.Background(), testPod(corev1.PodSpec{}), RequestMeta{})
+
+	if decision.Allowed {
+		t.Fatal("Allowed = true, want false")
+	}
+	if want := "this cluster only runs approved workloads (request approval in the platform team's channel)"; decision.Message != want {
+		t.Errorf("Message = %q, want %q", decision.Message, want)
+	}
+	if decision.Reason != noMatch.Reason {
+		t.Errorf("Reason = %q, want %q", decision.Reason, noMatch.Reason)
+	}
+	violations := Violations(decision.Findings)
+	if len(violations) != 1 || violations[0].Message != noMatch.Message || violations[0].Remediation != noMatch.Remediation {
+		t.Errorf("violations = %+v, want one with the configured message and remediation", violations)
+	}
+}
@@ -0,0 +1,98 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// checkRevisionHistoryLimit rejects deployments that leave
+// revisionHistoryLimit unset when it is required, or set it above the
+// configured maximum, as every revision keeps an old ReplicaSet around.
+func (e *Engine) checkRevisionHistoryLimit(ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+	deployment := obj.(*appsv1.Deployment)
+	limit := deployment.Spec.RevisionHistoryLimit
+	switch {
+	case limit == nil && e.opts.RequireRevisionHistoryLimit:
+		return []Finding{{Message: "deployment must set spec.revisionHistoryLimit"}}, nil
+	case limit != nil && e.opts.MaxRevisionHistoryLimit > 0 && *limit > e.opts.MaxRevisionHistoryLimit:
+		return []Finding{{Message: fmt.Sprintf("deployment spec.revisionHistoryLimit (%d) exceeds the maximum of %d", *limit, e.opts.MaxRevisionHistoryLimit)}}, nil
+	}
+	return nil, nil
+}
+
+// allowRecreateAnnotation exempts deployments that set it to true from
+// requiring the RollingUpdate strategy, for workloads that can't run two
+// versions at once.
+const allowRecreateAnnotation = annotationPrefix + "allow-recreate"
+
+// checkDeploymentStrategy rejects deployments that use the Recreate
+// strategy, which takes every pod down before starting the new ones, unless
+// they're exempted, and rolling updates that let more pods than the
+// configured maximum be unavailable at once.
+func (e *Engine) checkDeploymentStrategy(ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+	deployment := obj.(*appsv1.Deployment)
+	strategy := deployment.Spec.Strategy
+
+	if strategy.Type == appsv1.RecreateDeploymentStrategyType {
+		if deployment.Annotations[allowRecreateAnnotation] == "true" {
+			return nil, nil
+		}
+		return []Finding{{Message: fmt.Sprintf("deployment spec.strategy.type must be RollingUpdate, not Recreate (or set the %s annotation to true)", allowRecreateAnnotation)}}, nil
+	}
+	if e.maxUnavailable == nil {
+		return nil, nil
+	}
+
+	// maxUnavailable defaults to 25%, and percentages are of the desired
+	// replicas, rounded down.
+	maxUnavailable := intstr.FromString("25%")
+	if strategy.RollingUpdate != nil && strategy.RollingUpdate.MaxUnavailable != nil {
+		maxUnavailable = *strategy.RollingUpdate.MaxUnavailable
+	}
+	replicas := 1
+	if deployment.Spec.Replicas != nil {
+		replicas = int(*deployment.Spec.Replicas)
+	}
+
+	unavailable, err := intstr.GetScaledValueFromIntOrPercent(&maxUnavailable, replicas, false)
+	if err != nil {
+		return []Finding{{Message: fmt.Sprintf("invalid spec.strategy.rollingUpdate.maxUnavailable: %v", err)}}, nil
+	}
+	limit, err := intstr.GetScaledValueFromIntOrPercent(e.maxUnavailable, replicas, false)
+	if err != nil {
+		return nil, err
+	}
+	if unavailable > limit {
+		return []Finding{{Message: fmt.Sprintf("deployment spec.strategy.rollingUpdate.maxUnavailable (%s, %d of %d replicas) exceeds the maximum of %s", maxUnavailable.String(), unavailable, replicas, e.maxUnavailable.String())}}, nil
+	}
+	return nil, nil
+}
+
+// defaultProgressDeadlineSeconds is the progressDeadlineSeconds the API
+// server sets on deployments that don't set it.
+const defaultProgressDeadlineSeconds int32 = 600
+
+// checkProgressDeadline rejects deployments that leave
+// progressDeadlineSeconds at the default when a deliberate value is
+// required, or set it above the configured maximum, as either can hide a
+// stuck rollout for a long time. Deployments are defaulted before they're
+// validated, so the default is indistinguishable from setting it to 600.
+func (e *Engine) checkProgressDeadline(ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+	deployment := obj.(*appsv1.Deployment)
+	deadline := defaultProgressDeadlineSeconds
+	if deployment.Spec.ProgressDeadlineSeconds != nil {
+		deadline = *deployment.Spec.ProgressDeadlineSeconds
+	}
+
+	switch {
+	case deadline == defaultProgressDeadlineSeconds && e.opts.RequireProgressDeadline:
+		return []Finding{{Message: fmt.Sprintf("deployment spec.progressDeadlineSeconds is the default (%d): set it to how long a rollout should take", deadline)}}, nil
+	case e.opts.MaxProgressDeadlineSeconds > 0 && deadline > e.opts.MaxProgressDeadlineSeconds:
+		return []Finding{{Message: fmt.Sprintf("deployment spec.progressDeadlineSeconds (%d) exceeds the maximum of %d", deadline, e.opts.MaxProgressDeadlineSeconds)}}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,146 @@
+package policy
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func int32Ptr(i int32) *int32 { return &i }
+
+func TestCheckRevisionHistoryLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  Options
+		limit *int32
+		want  []string
+	}{
+		{
+			name:  "set when required",
+			opts:  Options{RequireRevisionHistoryLimit: true},
+			limit: int32Ptr(3),
+		},
+		{
+			name: "unset when required",
+			opts: Options{RequireRevisionHistoryLimit: true},
+			want: []string{"revision-history-limit: deployment must set spec.revisionHistoryLimit"},
+		},
+		{
+			name:  "at max",
+			opts:  Options{MaxRevisionHistoryLimit: 5},
+			limit: int32Ptr(5),
+		},
+		{
+			name:  "over max",
+			opts:  Options{MaxRevisionHistoryLimit: 5},
+			limit: int32Ptr(10),
+			want:  []string{"revision-history-limit: deployment spec.revisionHistoryLimit (10) exceeds the maximum of 5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, tt.opts, nil)
+			deployment := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{RevisionHistoryLimit: tt.limit}}
+			assertFindings(t, e, deployment, RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestCheckDeploymentStrategy(t *testing.T) {
+	rollingUpdate := func(maxUnavailable intstr.IntOrString) appsv1.DeploymentStrategy {
+		return appsv1.DeploymentStrategy{
+			Type:          appsv1.RollingUpdateDeploymentStrategyType,
+			RollingUpdate: &appsv1.RollingUpdateDeployment{MaxUnavailable: &maxUnavailable},
+		}
+	}
+	tests := []struct {
+		name           string
+		maxUnavailable string
+		annotations    map[string]string
+		replicas       *int32
+		strategy       appsv1.DeploymentStrategy
+		want           []string
+	}{
+		{
+			name:     "recreate",
+			strategy: appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
+			want:     []string{"deployment-strategy: deployment spec.strategy.type must be RollingUpdate, not Recreate (or set the webhook.trstringer.com/allow-recreate annotation to true)"},
+		},
+		{
+			name:        "recreate exempted",
+			annotations: map[string]string{allowRecreateAnnotation: "true"},
+			strategy:    appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
+		},
+		{
+			name:           "under max unavailable",
+			maxUnavailable: "50%",
+			replicas:       int32Ptr(4),
+			strategy:       rollingUpdate(intstr.FromInt(2)),
+		},
+		{
+			name:           "over max unavailable",
+			maxUnavailable: "25%",
+			replicas:       int32Ptr(4),
+			strategy:       rollingUpdate(intstr.FromString("50%")),
+			want:           []string{"deployment-strategy: deployment spec.strategy.rollingUpdate.maxUnavailable (50%, 2 of 4 replicas) exceeds the maximum of 25%"},
+		},
+		{
+			name:           "default max unavailable",
+			maxUnavailable: "1",
+			replicas:       int32Ptr(8),
+			want:           []string{"deployment-strategy: deployment spec.strategy.rollingUpdate.maxUnavailable (25%, 2 of 8 replicas) exceeds the maximum of 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{ValidateDeploymentStrategy: true, MaxUnavailable: tt.maxUnavailable}, nil)
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations},
+				Spec:       appsv1.DeploymentSpec{Replicas: tt.replicas, Strategy: tt.strategy},
+			}
+			assertFindings(t, e, deployment, RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestCheckProgressDeadline(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		deadline *int32
+		want     []string
+	}{
+		{
+			name:     "deliberate",
+			opts:     Options{RequireProgressDeadline: true},
+			deadline: int32Ptr(300),
+		},
+		{
+			name: "default when deliberate is required",
+			opts: Options{RequireProgressDeadline: true},
+			want: []string{"progress-deadline: deployment spec.progressDeadlineSeconds is the default (600): set it to how long a rollout should take"},
+		},
+		{
+			name:     "over max",
+			opts:     Options{MaxProgressDeadlineSeconds: 900},
+			deadline: int32Ptr(1200),
+			want:     []string{"progress-deadline: deployment spec.progressDeadlineSeconds (1200) exceeds the maximum of 900"},
+		},
+		{
+			name: "default under max",
+			opts: Options{MaxProgressDeadlineSeconds: 900},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, tt.opts, nil)
+			deployment := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{ProgressDeadlineSeconds: tt.deadline}}
+			assertFindings(t, e, deployment, RequestMeta{}, tt.want)
+		})
+	}
+}
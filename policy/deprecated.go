@@ -0,0 +1,73 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DeprecatedAnnotation is a pod annotation that has been replaced by a
+// field.
+type DeprecatedAnnotation struct {
+	// Annotation is the deprecated annotation. A trailing * matches every
+	// annotation with the prefix before it.
+	Annotation string `json:"annotation"`
+	// Replacement is the field to use instead.
+	Replacement string `json:"replacement"`
+}
+
+// defaultDeprecatedAnnotations are the alpha and beta pod annotations that
+// have graduated to fields.
+var defaultDeprecatedAnnotations = []DeprecatedAnnotation{
+	{"seccomp.security.alpha.kubernetes.io/pod", "spec.securityContext.seccompProfile"},
+	{"container.seccomp.security.alpha.kubernetes.io/*", "securityContext.seccompProfile of the container"},
+	{"scheduler.alpha.kubernetes.io/critical-pod", "spec.priorityClassName: system-cluster-critical or system-node-critical"},
+	{"scheduler.alpha.kubernetes.io/tolerations", "spec.tolerations"},
+	{"scheduler.alpha.kubernetes.io/affinity", "spec.affinity"},
+	{"pod.alpha.kubernetes.io/init-containers", "spec.initContainers"},
+	{"pod.beta.kubernetes.io/init-containers", "spec.initContainers"},
+	{"security.alpha.kubernetes.io/sysctls", "spec.securityContext.sysctls"},
+	{"security.alpha.kubernetes.io/unsafe-sysctls", "spec.securityContext.sysctls"},
+	{"pod.beta.kubernetes.io/hostname", "spec.hostname"},
+	{"pod.beta.kubernetes.io/subdomain", "spec.subdomain"},
+}
+
+// checkDeprecatedAnnotations warns about pods that carry deprecated alpha
+// or beta annotations, naming the field that replaced each of them. The
+// bundled annotations are extended, or their replacements overridden, by
+// the config.
+func (e *Engine) checkDeprecatedAnnotations(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	keys := make([]string, 0, len(pod.Annotations))
+	for key := range pod.Annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var findings []Finding
+	for _, key := range keys {
+		if replacement, ok := e.deprecatedAnnotation(key); ok {
+			findings = append(findings, Finding{
+				Message: fmt.Sprintf("annotation %s is deprecated: use %s instead", key, replacement),
+				Warning: true,
+			})
+		}
+	}
+	return findings, nil
+}
+
+// deprecatedAnnotation returns the replacement for the annotation if it's
+// deprecated. Annotations from the config take precedence over the bundled
+// ones.
+func (e *Engine) deprecatedAnnotation(key string) (string, bool) {
+	for _, annotations := range [][]DeprecatedAnnotation{e.deprecations, defaultDeprecatedAnnotations} {
+		for _, da := range annotations {
+			if da.Annotation == key || (strings.HasSuffix(da.Annotation, "*") && strings.HasPrefix(key, strings.TrimSuffix(da.Annotation, "*"))) {
+				return da.Replacement, true
+			}
+		}
+	}
+	return "", false
+}
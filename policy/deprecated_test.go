@@ -0,0 +1,52 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCheckDeprecatedAnnotations(t *testing.T) {
+	cfg := &Config{DeprecatedAnnotations: []DeprecatedAnnotation{
+		{Annotation: "example.com/legacy-*", Replacement: "spec.example"},
+		{Annotation: "pod.beta.kubernetes.io/hostname", Replacement: "spec.hostname, set by the platform"},
+	}}
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        []string
+	}{
+		{
+			name:        "current",
+			annotations: map[string]string{"team": "payments"},
+		},
+		{
+			name: "bundled",
+			annotations: map[string]string{
+				"seccomp.security.alpha.kubernetes.io/pod":           "runtime/default",
+				"container.seccomp.security.alpha.kubernetes.io/app": "runtime/default",
+			},
+			want: []string{
+				"deprecated-annotations: warning: annotation container.seccomp.security.alpha.kubernetes.io/app is deprecated: use securityContext.seccompProfile of the container instead",
+				"deprecated-annotations: warning: annotation seccomp.security.alpha.kubernetes.io/pod is deprecated: use spec.securityContext.seccompProfile instead",
+			},
+		},
+		{
+			name:        "from config",
+			annotations: map[string]string{"example.com/legacy-mode": "on"},
+			want:        []string{"deprecated-annotations: warning: annotation example.com/legacy-mode is deprecated: use spec.example instead"},
+		},
+		{
+			name:        "overridden by config",
+			annotations: map[string]string{"pod.beta.kubernetes.io/hostname": "db"},
+			want:        []string{"deprecated-annotations: warning: annotation pod.beta.kubernetes.io/hostname is deprecated: use spec.hostname, set by the platform instead"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{WarnDeprecatedAnnotations: true}, cfg)
+			assertFindings(t, e, withMeta(testPod(corev1.PodSpec{}), nil, tt.annotations), RequestMeta{}, tt.want)
+		})
+	}
+}
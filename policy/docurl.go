@@ -0,0 +1,37 @@
+package policy
+
+import (
+	"strings"
+	"text/template"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// docURLContext is what a rule's doc URL template is rendered with.
+type docURLContext struct {
+	Rule      string
+	Namespace string
+	Name      string
+	Container string
+}
+
+// parseDocURL parses a rule's doc URL template.
+func parseDocURL(docURL string) (*template.Template, error) {
+	return template.New("docURL").Option("missingkey=error").Parse(docURL)
+}
+
+// renderDocURL renders the doc URL for a finding about the object. If it
+// can't be rendered, the finding gets no doc URL rather than a broken one.
+func renderDocURL(tmpl *template.Template, f Finding, obj runtime.Object, meta RequestMeta) string {
+	ctx := docURLContext{Rule: f.Rule, Namespace: meta.Namespace, Container: f.Container}
+	if accessor, err := apimeta.Accessor(obj); err == nil {
+		ctx.Name = accessor.GetName()
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, ctx); err != nil {
+		return ""
+	}
+	return b.String()
+}
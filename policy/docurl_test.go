@@ -0,0 +1,36 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDocURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		docURL string
+		want   []string
+	}{
+		{
+			name:   "rendered",
+			docURL: "https://docs.example.com/{{ .Rule }}?object={{ .Namespace }}/{{ .Name }}",
+			want:   []string{"hello-label: missing required hello label (see https://docs.example.com/hello-label?object=default/test)"},
+		},
+		{
+			name:   "unrenderable",
+			docURL: "https://docs.example.com/{{ .Team }}",
+			want:   []string{"hello-label: missing required hello label"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Rules: []RuleConfig{{Name: "hello-label", DocURL: tt.docURL}}}
+			e := newTestEngine(t, Options{}, cfg)
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+			assertFindings(t, e, pod, RequestMeta{Namespace: "default"}, tt.want)
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Engine evaluates objects against the rules enabled by its options and
+// config. An Engine is immutable once created, and safe for concurrent use.
+type Engine struct {
+	opts    Options
+	rules   []rule
+	lookups *lookupCache
+
+	skipLabels           []skipLabel
+	maxEmptyDirSize      *resource.Quantity
+	minPVCStorage        *resource.Quantity
+	maxPVCStorage        *resource.Quantity
+	maxImageSize         *resource.Quantity
+	registry             *registryClient
+	namePattern          *template.Template
+	placeholderPattern   *regexp.Regexp
+	containerNamePattern *regexp.Regexp
+	expensiveExecPattern *regexp.Regexp
+	mutableTagPattern    *regexp.Regexp
+	injectionPattern     *regexp.Regexp
+	publicHostPattern    *regexp.Regexp
+	secretEnvPattern     *regexp.Regexp
+	antiAffinitySelector labels.Selector
+	labelFormats         []LabelFormat
+	requiredVolumes      []RequiredVolume
+	priorityClassTiers   []PriorityClassTiers
+	fsGroupRanges        []idRange
+	runAsUserRanges      []idRange
+	runAsGroupRanges     []idRange
+	statefulLabel        *keyValue
+	spotTaints           []keyValue
+	spotNodeLabels       []keyValue
+	sensitiveLabel       *keyValue
+	maxUnavailable       *intstr.IntOrString
+	sidecar              *corev1.Container
+	quotaNamespaceLabel  *keyValue
+	bypassAnnotations    []keyValue
+	freezeNamespaceLabel *keyValue
+	freezeWindows        []FreezeWindow
+	freezeSchedules      []cronSchedule
+	productionLabel      *keyValue
+	noExecNamespaceLabel *keyValue
+	deprecations         []DeprecatedAnnotation
+	lockedNamespaces     []RequesterRestriction
+	hugePagesNodeLabel   *keyValue
+	restrictedLabel      *keyValue
+	immutableFields      [][]string
+	gpuNamespaceLabel    *keyValue
+	defaultDeny          *DefaultDeny
+	allowRules           []allowRule
+}
+
+// NewEngine creates an engine that evaluates the rules enabled by opts, with
+// the settings from cfg applied to them. cfg may be nil.
+func NewEngine(opts Options, cfg *Config) (*Engine, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	if errs := cfg.Validate(); len(errs) > 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+
+	e := &Engine{
+		opts:               opts,
+		lookups:            newLookupCache(opts.LookupCacheTTL, opts.LookupCacheSize),
+		labelFormats:       cfg.LabelFormats,
+		requiredVolumes:    cfg.RequiredVolumes,
+		priorityClassTiers: cfg.PriorityClassTiers,
+		sidecar:            cfg.Sidecar,
+		freezeWindows:      cfg.FreezeWindows,
+		deprecations:       cfg.DeprecatedAnnotations,
+		lockedNamespaces:   cfg.RequesterRestrictions,
+		defaultDeny:        cfg.DefaultDeny,
+	}
+	if cfg.DefaultDeny != nil {
+		for _, ar := range cfg.DefaultDeny.Allow {
+			// The selector was checked when cfg was validated.
+			selector, _ := labels.Parse(ar.Selector)
+			e.allowRules = append(e.allowRules, allowRule{AllowRule: ar, selector: selector})
+		}
+	}
+	if err := e.complete(); err != nil {
+		return nil, err
+	}
+	var pluginRules []rule
+	if opts.PluginDir != "" {
+		var err error
+		if pluginRules, err = e.loadPlugins(opts.PluginDir); err != nil {
+			return nil, err
+		}
+	}
+	e.rules = e.buildRuleset(cfg, pluginRules)
+
+	return e, nil
+}
+
+// evaluate runs every rule for the resource and operation against the
+// object and returns all of the findings, tagged with the name of the rule
+// that produced them. With ShortCircuit set, evaluation stops at the first
+// rejection and only that violation is returned along with any warnings
+// found before it.
+// Rules that can't be evaluated don't stop it with DecisionDefault set,
+// since whether it applies depends on whether the rest can. Evaluation also
+// stops if PipelineTimeout is hit, with the findings for
+// PipelineTimeoutDecision. The findings are cacheable unless a dynamic rule
+// was evaluated or a rule couldn't be.
+func (e *Engine) evaluate(ctx context.Context, obj runtime.Object, meta RequestMeta) (findings []Finding, cacheable bool) {
+	var evaluated int
+	var errs []string
+	cacheable = true
+	for _, r := range e.rules {
+		if r.resource != meta.Resource && (r.resource != anyResource || isSubresource(meta.Resource)) {
+			continue
+		}
+		if meta.Operation == OperationUpdate && !r.onUpdate {
+			continue
+		}
+
+		start := time.Now()
+		ruleFindings, timedOut, err := e.runRule(ctx, r, obj, meta)
+		if e.opts.ObserveRule != nil {
+			e.opts.ObserveRule(r.name, time.Since(start))
+		}
+		if timedOut {
+			return e.pipelineTimeoutFindings(findings, r.name), false
+		}
+		evaluated++
+		if r.dynamic {
+			cacheable = false
+		}
+		if err != nil {
+			cacheable = false
+			e.opts.Logger.Printf("error evaluating rule %s: %v", r.name, err)
+			errs = append(errs, fmt.Sprintf("%s: %v", r.name, err))
+			// The error still counts toward DecisionDefault when it's
+			// ignored.
+			if e.opts.FailurePolicy == FailurePolicyIgnore {
+				continue
+			}
+			ruleFindings = []Finding{{Message: fmt.Sprintf("unable to evaluate rule %s: %v", r.name, err)}}
+		}
+
+		enforced := r.enforces(obj)
+		for _, f := range ruleFindings {
+			f.Rule = r.name
+			f.Warning = f.Warning || !enforced
+			if r.docURL != nil {
+				f.DocURL = renderDocURL(r.docURL, f, obj, meta)
+			}
+			findings = append(findings, f)
+			if e.stopsEvaluation(f, err) {
+				return findings, cacheable
+			}
+		}
+	}
+
+	if e.opts.DecisionDefault != "" && evaluated > 0 && len(errs) == evaluated {
+		return e.defaultDecisionFindings(errs), false
+	}
+	return findings, cacheable
+}
+
+// stopsEvaluation reports whether evaluation stops at the finding, which
+// the rule's check returned along with err. Only rejections stop it, and
+// only with ShortCircuit set. A rejection because the rule couldn't be
+// evaluated doesn't stop it with DecisionDefault set: the default decision
+// applies only if none of the rules can be evaluated, which isn't known
+// until the rest have been tried.
+func (e *Engine) stopsEvaluation(f Finding, err error) bool {
+	if !e.opts.ShortCircuit || f.Warning {
+		return false
+	}
+	return err == nil || e.opts.DecisionDefault == ""
+}
+
+// runRule runs the rule's check. With PipelineTimeout set, timedOut is set
+// if the pipeline's deadline passed before the check started or by the time
+// it returned, and whatever the check returned is dropped, since it may
+// have been cut short. The check runs on the caller's goroutine, so the
+// deadline only bounds checks that honor ctx, as every rule's must.
+func (e *Engine) runRule(ctx context.Context, r rule, obj runtime.Object, meta RequestMeta) (findings []Finding, timedOut bool, err error) {
+	if e.opts.PipelineTimeout <= 0 {
+		findings, err = r.check(e, ctx, obj, meta)
+		return findings, false, err
+	}
+	if ctx.Err() != nil {
+		return nil, true, nil
+	}
+
+	findings, err = r.check(e, ctx, obj, meta)
+	if ctx.Err() != nil {
+		return nil, true, nil
+	}
+	return findings, false, err
+}
+
+// pipelineTimeoutRule is the rule that findings from PipelineTimeoutDecision
+// are tagged with.
+const pipelineTimeoutRule = "pipeline-timeout"
+
+// pipelineTimeoutFindings returns the findings for PipelineTimeoutDecision,
+// for objects whose evaluation timed out at the rule, given the findings of
+// the rules evaluated before it.
+func (e *Engine) pipelineTimeoutFindings(findings []Finding, rule string) []Finding {
+	e.opts.Logger.Printf("evaluation timed out after %s at rule %s", e.opts.PipelineTimeout, rule)
+	msg := fmt.Sprintf("evaluation timed out after %s, before rule %s finished", e.opts.PipelineTimeout, rule)
+	switch e.opts.PipelineTimeoutDecision {
+	case DecisionDefaultDeny:
+		return append(findings, Finding{Rule: pipelineTimeoutRule, Message: msg})
+	case DecisionDefaultWarn:
+		warnings := make([]Finding, 0, len(findings)+1)
+		for _, f := range findings {
+			f.Warning = true
+			warnings = append(warnings, f)
+		}
+		return append(warnings, Finding{Rule: pipelineTimeoutRule, Message: msg, Warning: true})
+	}
+	return nil
+}
+
+// decisionDefaultRule is the rule that findings from DecisionDefault are
+// tagged with.
+const decisionDefaultRule = "decision-default"
+
+// defaultDecisionFindings returns the findings for DecisionDefault, for
+// objects none of whose rules could be evaluated.
+func (e *Engine) defaultDecisionFindings(errs []string) []Finding {
+	msg := fmt.Sprintf("unable to evaluate any rule: %s", strings.Join(errs, "; "))
+	switch e.opts.DecisionDefault {
+	case DecisionDefaultDeny:
+		return []Finding{{Rule: decisionDefaultRule, Message: msg}}
+	case DecisionDefaultWarn:
+		return []Finding{{Rule: decisionDefaultRule, Message: msg, Warning: true}}
+	}
+	return nil
+}
@@ -0,0 +1,339 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// fakeRule returns a rule for pods whose check returns the findings and err.
+func fakeRule(name string, err error, findings ...Finding) rule {
+	return rule{
+		name:           name,
+		enforcePercent: 100,
+		resource:       podResource,
+		check: func(e *Engine, ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+			return findings, err
+		},
+	}
+}
+
+// engineWithRules creates an engine from opts that evaluates only rules, in
+// order.
+func engineWithRules(t *testing.T, opts Options, rules ...rule) *Engine {
+	t.Helper()
+	e := newTestEngine(t, opts, nil)
+	e.rules = rules
+	return e
+}
+
+var (
+	errLookup      = errors.New("lookup failed")
+	erroringRule   = fakeRule("erroring", errLookup)
+	erroringRule2  = fakeRule("erroring-2", errLookup)
+	rejectingRule  = fakeRule("rejecting", nil, Finding{Message: "rejected"})
+	rejectingRule2 = fakeRule("rejecting-2", nil, Finding{Message: "rejected again"})
+	warningRule    = fakeRule("warning", nil, Finding{Message: "warned", Warning: true})
+)
+
+func TestShortCircuit(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  Options
+		rules []rule
+		want  []string
+	}{
+		{
+			name:  "every rejection without short circuit",
+			rules: []rule{rejectingRule, rejectingRule2},
+			want:  []string{"rejecting: rejected", "rejecting-2: rejected again"},
+		},
+		{
+			name:  "stops at the first rejection",
+			opts:  Options{ShortCircuit: true},
+			rules: []rule{warningRule, rejectingRule, rejectingRule2},
+			want:  []string{"warning: warning: warned", "rejecting: rejected"},
+		},
+		{
+			name:  "stops at a rule that can't be evaluated",
+			opts:  Options{ShortCircuit: true},
+			rules: []rule{erroringRule, rejectingRule},
+			want:  []string{"erroring: unable to evaluate rule erroring: lookup failed"},
+		},
+		{
+			name:  "continues past a rule that can't be evaluated with decision default",
+			opts:  Options{ShortCircuit: true, DecisionDefault: DecisionDefaultDeny},
+			rules: []rule{erroringRule, rejectingRule, rejectingRule2},
+			want:  []string{"erroring: unable to evaluate rule erroring: lookup failed", "rejecting: rejected"},
+		},
+		{
+			name:  "decision default once no rule can be evaluated",
+			opts:  Options{ShortCircuit: true, DecisionDefault: DecisionDefaultDeny},
+			rules: []rule{erroringRule, erroringRule2},
+			want:  []string{"decision-default: unable to evaluate any rule: erroring: lookup failed; erroring-2: lookup failed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := engineWithRules(t, tt.opts, tt.rules...)
+			assertFindings(t, e, testPod(corev1.PodSpec{}), RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestDecisionDefault(t *testing.T) {
+	unableToEvaluate := []string{"erroring: unable to evaluate rule erroring: lookup failed", "erroring-2: unable to evaluate rule erroring-2: lookup failed"}
+	tests := []struct {
+		name            string
+		failurePolicy   string
+		decisionDefault string
+		rules           []rule
+		wantAllowed     bool
+		want            []string
+	}{
+		{
+			name:          "fail without decision default",
+			failurePolicy: FailurePolicyFail,
+			rules:         []rule{erroringRule, erroringRule2},
+			want:          unableToEvaluate,
+		},
+		{
+			name:          "ignore without decision default",
+			failurePolicy: FailurePolicyIgnore,
+			rules:         []rule{erroringRule, erroringRule2},
+			wantAllowed:   true,
+		},
+		{
+			name:            "fail with allow",
+			failurePolicy:   FailurePolicyFail,
+			decisionDefault: DecisionDefaultAllow,
+			rules:           []rule{erroringRule, erroringRule2},
+			wantAllowed:     true,
+		},
+		{
+			name:            "ignore with allow",
+			failurePolicy:   FailurePolicyIgnore,
+			decisionDefault: DecisionDefaultAllow,
+			rules:           []rule{erroringRule, erroringRule2},
+			wantAllowed:     true,
+		},
+		{
+			name:            "fail with deny",
+			failurePolicy:   FailurePolicyFail,
+			decisionDefault: DecisionDefaultDeny,
+			rules:           []rule{erroringRule, erroringRule2},
+			want:            []string{"decision-default: unable to evaluate any rule: erroring: lookup failed; erroring-2: lookup failed"},
+		},
+		{
+			// Ignored errors still count, so the object isn't allowed just
+			// because every rule was ignored.
+			name:            "ignore with deny",
+			failurePolicy:   FailurePolicyIgnore,
+			decisionDefault: DecisionDefaultDeny,
+			rules:           []rule{erroringRule, erroringRule2},
+			want:            []string{"decision-default: unable to evaluate any rule: erroring: lookup failed; erroring-2: lookup failed"},
+		},
+		{
+			name:            "fail with allow with warning",
+			failurePolicy:   FailurePolicyFail,
+			decisionDefault: DecisionDefaultWarn,
+			rules:           []rule{erroringRule, erroringRule2},
+			wantAllowed:     true,
+			want:            []string{"decision-default: warning: unable to evaluate any rule: erroring: lookup failed; erroring-2: lookup failed"},
+		},
+		{
+			name:            "ignore with allow with warning",
+			failurePolicy:   FailurePolicyIgnore,
+			decisionDefault: DecisionDefaultWarn,
+			rules:           []rule{erroringRule, erroringRule2},
+			wantAllowed:     true,
+			want:            []string{"decision-default: warning: unable to evaluate any rule: erroring: lookup failed; erroring-2: lookup failed"},
+		},
+		{
+			name:            "fail with deny when some rules are evaluated",
+			failurePolicy:   FailurePolicyFail,
+			decisionDefault: DecisionDefaultDeny,
+			rules:           []rule{erroringRule, warningRule, erroringRule2},
+			want:            []string{unableToEvaluate[0], "warning: warning: warned", unableToEvaluate[1]},
+		},
+		{
+			name:            "ignore with deny when some rules are evaluated",
+			failurePolicy:   FailurePolicyIgnore,
+			decisionDefault: DecisionDefaultDeny,
+			rules:           []rule{erroringRule, warningRule, erroringRule2},
+			wantAllowed:     true,
+			want:            []string{"warning: warning: warned"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := engineWithRules(t, Options{FailurePolicy: tt.failurePolicy, DecisionDefault: tt.decisionDefault}, tt.rules...)
+			decision := e.Evaluate(context.Background(), testPod(corev1.PodSpec{}), RequestMeta{})
+			if decision.Allowed != tt.wantAllowed {
+				t.Errorf("Allowed = %t, want %t", decision.Allowed, tt.wantAllowed)
+			}
+			if got := findingStrings(decision.Findings); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findings = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPipelineTimeout(t *testing.T) {
+	// slowRule honors ctx, returning once the pipeline's deadline passes.
+	slowRule := fakeRule("slow", nil)
+	slowRule.check = func(e *Engine, ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	// lateRule doesn't notice the deadline, and returns a finding after it.
+	lateRule := fakeRule("late", nil)
+	lateRule.check = func(e *Engine, ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+		time.Sleep(20 * time.Millisecond)
+		return []Finding{{Message: "too late"}}, nil
+	}
+
+	tests := []struct {
+		name        string
+		decision    string
+		rules       []rule
+		wantAllowed bool
+		want        []string
+	}{
+		{
+			name:     "deny",
+			decision: DecisionDefaultDeny,
+			rules:    []rule{warningRule, slowRule, rejectingRule},
+			want:     []string{"warning: warning: warned", "pipeline-timeout: evaluation timed out after 10ms, before rule slow finished"},
+		},
+		{
+			name:        "allow",
+			decision:    DecisionDefaultAllow,
+			rules:       []rule{warningRule, slowRule, rejectingRule},
+			wantAllowed: true,
+		},
+		{
+			name:        "allow with warning",
+			decision:    DecisionDefaultWarn,
+			rules:       []rule{rejectingRule, slowRule, rejectingRule2},
+			wantAllowed: true,
+			want:        []string{"rejecting: warning: rejected", "pipeline-timeout: warning: evaluation timed out after 10ms, before rule slow finished"},
+		},
+		{
+			name:     "rule that returns after the deadline",
+			decision: DecisionDefaultDeny,
+			rules:    []rule{lateRule, rejectingRule},
+			want:     []string{"pipeline-timeout: evaluation timed out after 10ms, before rule late finished"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := engineWithRules(t, Options{PipelineTimeout: 10 * time.Millisecond, PipelineTimeoutDecision: tt.decision}, tt.rules...)
+			decision := e.Evaluate(context.Background(), testPod(corev1.PodSpec{}), RequestMeta{})
+			if !decision.TimedOut {
+				t.Error("TimedOut = false, want true")
+			}
+			if decision.Cacheable {
+				t.Error("Cacheable = true, want false")
+			}
+			if decision.Allowed != tt.wantAllowed {
+				t.Errorf("Allowed = %t, want %t", decision.Allowed, tt.wantAllowed)
+			}
+			if got := findingStrings(decision.Findings); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findings = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecisionCacheable(t *testing.T) {
+	dynamicRule := fakeRule("dynamic", nil)
+	dynamicRule.dynamic = true
+	serviceRule := dynamicRule
+	serviceRule.resource = serviceResource
+
+	tests := []struct {
+		name  string
+		opts  Options
+		rules []rule
+		pod   *corev1.Pod
+		want  bool
+	}{
+		{
+			name:  "static rules",
+			rules: []rule{rejectingRule, warningRule},
+			want:  true,
+		},
+		{
+			name:  "dynamic rule",
+			rules: []rule{rejectingRule, dynamicRule},
+		},
+		{
+			name:  "dynamic rule for another resource",
+			rules: []rule{rejectingRule, serviceRule},
+			want:  true,
+		},
+		{
+			name:  "dynamic rule after short circuit",
+			opts:  Options{ShortCircuit: true},
+			rules: []rule{rejectingRule, dynamicRule},
+			want:  true,
+		},
+		{
+			name:  "rule that can't be evaluated",
+			rules: []rule{rejectingRule, erroringRule},
+		},
+		{
+			name:  "rule that can't be evaluated with ignore",
+			opts:  Options{FailurePolicy: FailurePolicyIgnore},
+			rules: []rule{erroringRule},
+		},
+		{
+			name:  "skipped object",
+			opts:  Options{SkipObjectLabels: []string{"validation=skip"}},
+			rules: []rule{dynamicRule},
+			pod:   withMeta(testPod(corev1.PodSpec{}), map[string]string{"validation": "skip"}, nil),
+			want:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := engineWithRules(t, tt.opts, tt.rules...)
+			pod := tt.pod
+			if pod == nil {
+				pod = testPod(corev1.PodSpec{})
+			}
+			if got := e.Evaluate(context.Background(), pod, RequestMeta{}).Cacheable; got != tt.want {
+				t.Errorf("Cacheable = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDynamicRules(t *testing.T) {
+	// Rules that look up other objects or depend on the time must be
+	// dynamic, so that their decisions aren't cached.
+	for _, name := range []string{"pull-secrets-exist", "namespace-environment", "freeze-window", "configmap-owners", "image-size", "root-image-user", "pod-exec"} {
+		found := false
+		for _, r := range rules {
+			if r.name == name {
+				found = true
+				if !r.dynamic {
+					t.Errorf("rule %s isn't dynamic", name)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("no rule %s", name)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// checkEnvironment rejects pods whose environment label doesn't match the
+// environment label of their namespace, such as a prod pod in a dev
+// namespace. Pods without the label aren't checked. Namespaces without the
+// label are only rejected when RejectMissingNamespaceEnvironment is set.
+func (e *Engine) checkEnvironment(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	podEnvironment, ok := pod.Labels[e.opts.EnvironmentLabel]
+	if !ok {
+		return nil, nil
+	}
+
+	nsLabels, err := e.namespaceLabels(ctx, meta.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up namespace %s: %w", meta.Namespace, err)
+	}
+
+	nsEnvironment, ok := nsLabels[e.opts.EnvironmentLabel]
+	switch {
+	case !ok && e.opts.RejectMissingNamespaceEnvironment:
+		return []Finding{{Message: fmt.Sprintf("namespace %s has no %s label to match the pod's %s against", meta.Namespace, e.opts.EnvironmentLabel, podEnvironment)}}, nil
+	case ok && nsEnvironment != podEnvironment:
+		return []Finding{{Message: fmt.Sprintf("pod %s %s does not match namespace %s %s %s", e.opts.EnvironmentLabel, podEnvironment, meta.Namespace, e.opts.EnvironmentLabel, nsEnvironment)}}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,65 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCheckEnvironment(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		testNamespace("prod", map[string]string{"env": "prod"}),
+		testNamespace("unlabeled", nil),
+	)
+	tests := []struct {
+		name          string
+		rejectMissing bool
+		namespace     string
+		labels        map[string]string
+		want          []string
+	}{
+		{
+			name:      "matches",
+			namespace: "prod",
+			labels:    map[string]string{"env": "prod"},
+		},
+		{
+			name:      "doesn't match",
+			namespace: "prod",
+			labels:    map[string]string{"env": "dev"},
+			want:      []string{"namespace-environment: pod env dev does not match namespace prod env prod"},
+		},
+		{
+			name:      "pod without label",
+			namespace: "prod",
+		},
+		{
+			name:      "namespace without label",
+			namespace: "unlabeled",
+			labels:    map[string]string{"env": "dev"},
+		},
+		{
+			name:          "namespace without label rejected",
+			rejectMissing: true,
+			namespace:     "unlabeled",
+			labels:        map[string]string{"env": "dev"},
+			want:          []string{"namespace-environment: namespace unlabeled has no env label to match the pod's dev against"},
+		},
+		{
+			name:      "namespace doesn't exist",
+			namespace: "missing",
+			labels:    map[string]string{"env": "dev"},
+			want:      []string{`namespace-environment: unable to evaluate rule namespace-environment: error looking up namespace missing: namespaces "missing" not found`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{VerifyEnvironment: true, EnvironmentLabel: "env", RejectMissingNamespaceEnvironment: tt.rejectMissing, Client: client}, nil)
+			pod := withMeta(testPod(corev1.PodSpec{}), tt.labels, nil)
+			pod.Namespace = tt.namespace
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
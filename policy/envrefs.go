@@ -0,0 +1,119 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// checkEnvRefsExist rejects containers with required environment variables
+// from ConfigMap or Secret keys that don't exist in the pod's namespace,
+// which would otherwise only surface later as CreateContainerConfigError.
+// References marked optional aren't checked.
+func (e *Engine) checkEnvRefsExist(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	for _, container := range allContainers(pod) {
+		for _, env := range container.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+
+			if ref := env.ValueFrom.ConfigMapKeyRef; ref != nil && (ref.Optional == nil || !*ref.Optional) {
+				keys, err := e.configMapKeys(ctx, meta.Namespace, ref.Name)
+				if err != nil {
+					return nil, fmt.Errorf("error looking up config map %s: %w", ref.Name, err)
+				}
+				if msg := missingKeyMessage("config map", ref.Name, ref.Key, meta.Namespace, keys); msg != "" {
+					findings = append(findings, Finding{
+						Container: container.Name,
+						Message:   fmt.Sprintf("env %s: %s", env.Name, msg),
+					})
+				}
+			}
+
+			if ref := env.ValueFrom.SecretKeyRef; ref != nil && (ref.Optional == nil || !*ref.Optional) {
+				keys, err := e.secretKeys(ctx, meta.Namespace, ref.Name)
+				if err != nil {
+					return nil, fmt.Errorf("error looking up secret %s: %w", ref.Name, err)
+				}
+				if msg := missingKeyMessage("secret", ref.Name, ref.Key, meta.Namespace, keys); msg != "" {
+					findings = append(findings, Finding{
+						Container: container.Name,
+						Message:   fmt.Sprintf("env %s: %s", env.Name, msg),
+					})
+				}
+			}
+		}
+	}
+	return findings, nil
+}
+
+// missingKeyMessage describes why the key of the object can't be used, or
+// returns an empty string if it can. keys is nil if the object doesn't exist.
+func missingKeyMessage(kind, name, key, namespace string, keys map[string]bool) string {
+	switch {
+	case keys == nil:
+		return fmt.Sprintf("%s %s does not exist in namespace %s", kind, name, namespace)
+	case !keys[key]:
+		return fmt.Sprintf("%s %s has no key %s", kind, name, key)
+	}
+	return ""
+}
+
+// configMapKeys returns the keys of the config map, or nil if it doesn't
+// exist, using the lookup cache. Only config maps that exist are cached, as
+// not found errors aren't, so that pods are admitted as soon as their
+// missing config maps are created.
+func (e *Engine) configMapKeys(ctx context.Context, namespace, name string) (map[string]bool, error) {
+	keys, err := e.lookups.get(fmt.Sprintf("configmap-keys/%s/%s", namespace, name), func() (interface{}, error) {
+		configMap, err := e.opts.Client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		keys := map[string]bool{}
+		for key := range configMap.Data {
+			keys[key] = true
+		}
+		for key := range configMap.BinaryData {
+			keys[key] = true
+		}
+		return keys, nil
+	})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return keys.(map[string]bool), nil
+}
+
+// secretKeys returns the keys of the secret, or nil if it doesn't exist,
+// using the lookup cache. Like config maps, only secrets that exist are
+// cached.
+func (e *Engine) secretKeys(ctx context.Context, namespace, name string) (map[string]bool, error) {
+	keys, err := e.lookups.get(fmt.Sprintf("secret-keys/%s/%s", namespace, name), func() (interface{}, error) {
+		secret, err := e.opts.Client.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		keys := map[string]bool{}
+		for key := range secret.Data {
+			keys[key] = true
+		}
+		for key := range secret.StringData {
+			keys[key] = true
+		}
+		return keys, nil
+	})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return keys.(map[string]bool), nil
+}
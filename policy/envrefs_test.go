@@ -0,0 +1,114 @@
+package policy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCheckEnvRefsExist(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "default"}, Data: map[string]string{"host": "db"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "default"}, Data: map[string][]byte{"password": nil}},
+	)
+	configMapRef := func(name, key string, optional *bool) *corev1.EnvVarSource {
+		return &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: key, Optional: optional}}
+	}
+	secretRef := func(name, key string) *corev1.EnvVarSource {
+		return &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: key}}
+	}
+	tests := []struct {
+		name      string
+		valueFrom *corev1.EnvVarSource
+		want      []string
+	}{
+		{
+			name:      "config map key exists",
+			valueFrom: configMapRef("config", "host", nil),
+		},
+		{
+			name:      "config map key missing",
+			valueFrom: configMapRef("config", "port", nil),
+			want:      []string{"env-refs-exist: container app: env VAR: config map config has no key port"},
+		},
+		{
+			name:      "config map missing",
+			valueFrom: configMapRef("other", "host", nil),
+			want:      []string{"env-refs-exist: container app: env VAR: config map other does not exist in namespace default"},
+		},
+		{
+			name:      "optional config map missing",
+			valueFrom: configMapRef("other", "host", boolPtr(true)),
+		},
+		{
+			name:      "secret key exists",
+			valueFrom: secretRef("creds", "password"),
+		},
+		{
+			name:      "secret key missing",
+			valueFrom: secretRef("creds", "token"),
+			want:      []string{"env-refs-exist: container app: env VAR: secret creds has no key token"},
+		},
+		{
+			name:      "secret missing",
+			valueFrom: secretRef("other", "token"),
+			want:      []string{"env-refs-exist: container app: env VAR: secret other does not exist in namespace default"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{VerifyEnvRefsExist: true, Client: client}, nil)
+			pod := testPod(corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Env: []corev1.EnvVar{{Name: "VAR", ValueFrom: tt.valueFrom}}}}})
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestCheckEnvRefsExistAfterCreation(t *testing.T) {
+	tests := []struct {
+		name      string
+		valueFrom *corev1.EnvVarSource
+		create    func(client *fake.Clientset) error
+		want      string
+	}{
+		{
+			name:      "config map",
+			valueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "config"}, Key: "host"}},
+			create: func(client *fake.Clientset) error {
+				configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "default"}, Data: map[string]string{"host": "db"}}
+				_, err := client.CoreV1().ConfigMaps("default").Create(context.Background(), configMap, metav1.CreateOptions{})
+				return err
+			},
+			want: "env-refs-exist: container app: env VAR: config map config does not exist in namespace default",
+		},
+		{
+			name:      "secret",
+			valueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "creds"}, Key: "password"}},
+			create: func(client *fake.Clientset) error {
+				secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "default"}, Data: map[string][]byte{"password": nil}}
+				_, err := client.CoreV1().Secrets("default").Create(context.Background(), secret, metav1.CreateOptions{})
+				return err
+			},
+			want: "env-refs-exist: container app: env VAR: secret creds does not exist in namespace default",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			e := newTestEngine(t, Options{VerifyEnvRefsExist: true, Client: client, LookupCacheTTL: time.Hour}, nil)
+			pod := testPod(corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Env: []corev1.EnvVar{{Name: "VAR", ValueFrom: tt.valueFrom}}}}})
+			assertFindings(t, e, pod, RequestMeta{}, []string{tt.want})
+
+			if err := tt.create(client); err != nil {
+				t.Fatal(err)
+			}
+			assertFindings(t, e, pod, RequestMeta{}, nil)
+		})
+	}
+}
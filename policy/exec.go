@@ -0,0 +1,26 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// checkPodExec rejects exec and attach requests for pods in namespaces with
+// the no-exec namespace label, such as production ones, where changes must
+// go through the pod spec rather than a shell in a running container.
+func (e *Engine) checkPodExec(ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+	nsLabels, err := e.namespaceLabels(ctx, meta.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up namespace %s: %w", meta.Namespace, err)
+	}
+	value, ok := nsLabels[e.noExecNamespaceLabel.key]
+	if !ok || !e.noExecNamespaceLabel.matches(e.noExecNamespaceLabel.key, value) {
+		return nil, nil
+	}
+
+	action := strings.TrimPrefix(meta.Resource.Resource, "pods/")
+	return []Finding{{Message: fmt.Sprintf("%s into pods isn't allowed in namespace %s (%s)", action, meta.Namespace, e.noExecNamespaceLabel)}}, nil
+}
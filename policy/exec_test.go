@@ -0,0 +1,57 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCheckPodExec(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		testNamespace("prod", map[string]string{"exec": "forbidden"}),
+		testNamespace("dev", nil),
+	)
+	tests := []struct {
+		name      string
+		resource  string
+		namespace string
+		want      []string
+	}{
+		{
+			name:      "exec in no-exec namespace",
+			resource:  "pods/exec",
+			namespace: "prod",
+			want:      []string{"pod-exec: exec into pods isn't allowed in namespace prod (exec=forbidden)"},
+		},
+		{
+			name:      "attach in no-exec namespace",
+			resource:  "pods/attach",
+			namespace: "prod",
+			want:      []string{"pod-attach: attach into pods isn't allowed in namespace prod (exec=forbidden)"},
+		},
+		{
+			name:      "exec elsewhere",
+			resource:  "pods/exec",
+			namespace: "dev",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{NoExecNamespaceLabel: "exec=forbidden", Client: client}, nil)
+			meta := RequestMeta{Resource: podResource, Namespace: tt.namespace}
+			meta.Resource.Resource = tt.resource
+			obj, _ := NewObject(meta.Resource)
+			assertFindings(t, e, obj, meta, tt.want)
+		})
+	}
+}
+
+func TestSubresourcesSkipObjectRules(t *testing.T) {
+	// Rules for every resource, such as object-size, aren't evaluated
+	// against the options of a subresource request.
+	e := newTestEngine(t, Options{MaxObjectSize: 1}, nil)
+	meta := RequestMeta{Resource: podExecResource, Namespace: "default"}
+	assertFindings(t, e, &corev1.PodExecOptions{}, meta, nil)
+}
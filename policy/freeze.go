@@ -0,0 +1,202 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FreezeWindow is a recurring maintenance window, such as every weekend,
+// during which new pods are rejected in frozen namespaces.
+type FreezeWindow struct {
+	// Name identifies the window in rejections.
+	Name string `json:"name"`
+	// Schedule is when the window starts, as a cron expression of minute,
+	// hour, day of month, month and day of week in UTC, e.g. "0 18 * * 5"
+	// for every Friday at 18:00.
+	Schedule string `json:"schedule"`
+	// Duration is how long the window lasts after it starts, e.g. 60h.
+	Duration metav1.Duration `json:"duration"`
+}
+
+// systemNamespaces are never frozen, so that the cluster itself can keep
+// running during a freeze.
+var systemNamespaces = []string{"kube-system", "kube-public", "kube-node-lease"}
+
+// checkFreezeWindow rejects pods created during a maintenance window in
+// namespaces with the freeze namespace label, such as production ones.
+// System namespaces aren't frozen, and pods annotated as a break-glass
+// change are let through. Only creation is frozen: other operations, such as
+// updates of pods that are already running, aren't checked.
+func (e *Engine) checkFreezeWindow(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	if meta.Operation != "" && meta.Operation != OperationCreate {
+		return nil, nil
+	}
+	window, start, active := e.activeFreezeWindow(e.opts.Now())
+	if !active || contains(systemNamespaces, meta.Namespace) || pod.Annotations[breakGlassAnnotation] == "true" {
+		return nil, nil
+	}
+
+	nsLabels, err := e.namespaceLabels(ctx, meta.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up namespace %s: %w", meta.Namespace, err)
+	}
+	value, ok := nsLabels[e.freezeNamespaceLabel.key]
+	if !ok || !e.freezeNamespaceLabel.matches(e.freezeNamespaceLabel.key, value) {
+		return nil, nil
+	}
+
+	end := start.Add(window.Duration.Duration)
+	return []Finding{{Message: fmt.Sprintf("namespace %s is frozen during maintenance window %s, from %s until %s (set the %s annotation to true for an emergency change)", meta.Namespace, window.Name, start.Format(time.RFC3339), end.Format(time.RFC3339), breakGlassAnnotation)}}, nil
+}
+
+// activeFreezeWindow returns the freeze window that now is in, if any, and
+// when it started.
+func (e *Engine) activeFreezeWindow(now time.Time) (FreezeWindow, time.Time, bool) {
+	now = now.UTC()
+	for i, window := range e.freezeWindows {
+		// The window is active if it last started within its duration of now.
+		if start, ok := e.freezeSchedules[i].latest(now, now.Add(-window.Duration.Duration)); ok {
+			return window, start, true
+		}
+	}
+	return FreezeWindow{}, time.Time{}, false
+}
+
+// cronSchedule is a parsed cron expression. Each field holds the values it
+// matches.
+type cronSchedule struct {
+	minutes, hours, daysOfMonth, months, daysOfWeek map[int]bool
+	// anyDayOfMonth and anyDayOfWeek are set when the field is *. When
+	// both day fields are restricted, a time matches if either does, as in
+	// cron.
+	anyDayOfMonth, anyDayOfWeek bool
+}
+
+// parseCronSchedule parses a cron expression of minute, hour, day of month,
+// month and day of week. Each field is *, a value, a range such as 1-5, or
+// a list of them separated by commas, and * and ranges can have a step such
+// as */15. Day of week 7 is Sunday, like 0.
+func parseCronSchedule(expr string) (cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return cronSchedule{}, fmt.Errorf("invalid schedule %q: must have 5 fields", expr)
+	}
+
+	bounds := []struct {
+		name     string
+		min, max int
+	}{
+		{"minute", 0, 59},
+		{"hour", 0, 23},
+		{"day of month", 1, 31},
+		{"month", 1, 12},
+		{"day of week", 0, 7},
+	}
+	values := make([]map[int]bool, len(fields))
+	for i, field := range fields {
+		v, err := parseCronField(field, bounds[i].min, bounds[i].max)
+		if err != nil {
+			return cronSchedule{}, fmt.Errorf("invalid schedule %q: %s: %w", expr, bounds[i].name, err)
+		}
+		values[i] = v
+	}
+	if values[4][7] {
+		values[4][0] = true
+	}
+
+	return cronSchedule{
+		minutes:       values[0],
+		hours:         values[1],
+		daysOfMonth:   values[2],
+		months:        values[3],
+		daysOfWeek:    values[4],
+		anyDayOfMonth: fields[2] == "*",
+		anyDayOfWeek:  fields[4] == "*",
+	}, nil
+}
+
+// parseCronField returns the values from min to max that the field matches.
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	values := map[int]bool{}
+	for _, item := range strings.Split(field, ",") {
+		step := 1
+		parts := strings.SplitN(item, "/", 2)
+		if len(parts) == 2 {
+			var err error
+			if step, err = strconv.Atoi(parts[1]); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step %q", parts[1])
+			}
+		}
+
+		low, high := min, max
+		if parts[0] != "*" {
+			bounds := strings.SplitN(parts[0], "-", 2)
+			var err error
+			if low, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value %q", bounds[0])
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value %q", bounds[1])
+				}
+			} else if len(parts) == 2 {
+				// A single value with a step, such as 5/15, runs from the
+				// value to the maximum.
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return nil, fmt.Errorf("%q is out of range %d-%d", item, min, max)
+		}
+
+		for v := low; v <= high; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+// matches reports whether the schedule runs at the minute t is in.
+func (s cronSchedule) matches(t time.Time) bool {
+	return s.minutes[t.Minute()] && s.hours[t.Hour()] && s.months[int(t.Month())] && s.matchesDay(t)
+}
+
+// matchesDay reports whether the schedule runs on the day t is in.
+func (s cronSchedule) matchesDay(t time.Time) bool {
+	dayOfMonth, dayOfWeek := s.daysOfMonth[t.Day()], s.daysOfWeek[int(t.Weekday())]
+	if !s.anyDayOfMonth && !s.anyDayOfWeek {
+		return dayOfMonth || dayOfWeek
+	}
+	return dayOfMonth && dayOfWeek
+}
+
+// latest returns the last time, at or before the UTC time t, that the
+// schedule ran, if it ran after earliest. Rather than trying every minute,
+// it skips back over whole months, days and hours that the schedule doesn't
+// run in, so it takes at most a few hundred steps per year between earliest
+// and t.
+func (s cronSchedule) latest(t, earliest time.Time) (time.Time, bool) {
+	t = t.Truncate(time.Minute)
+	for t.After(earliest) {
+		switch {
+		case !s.months[int(t.Month())]:
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC).Add(-time.Minute)
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Add(-time.Minute)
+		case !s.hours[t.Hour()]:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, time.UTC).Add(-time.Minute)
+		case !s.minutes[t.Minute()]:
+			t = t.Add(-time.Minute)
+		default:
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
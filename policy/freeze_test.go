@@ -0,0 +1,236 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// weekendFreeze is a freeze window from every Friday at 18:00 UTC until
+// Monday at 06:00 UTC.
+var weekendFreeze = FreezeWindow{Name: "weekend", Schedule: "0 18 * * 5", Duration: metav1.Duration{Duration: 60 * time.Hour}}
+
+func TestCheckFreezeWindow(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		testNamespace("prod", map[string]string{"freeze": "true"}),
+		testNamespace("kube-system", map[string]string{"freeze": "true"}),
+		testNamespace("dev", nil),
+	)
+	saturday := time.Date(2021, time.November, 6, 12, 0, 0, 0, time.UTC)
+	tuesday := time.Date(2021, time.November, 9, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		now         time.Time
+		namespace   string
+		operation   string
+		annotations map[string]string
+		want        []string
+	}{
+		{
+			name:      "frozen",
+			now:       saturday,
+			namespace: "prod",
+			want:      []string{"freeze-window: namespace prod is frozen during maintenance window weekend, from 2021-11-05T18:00:00Z until 2021-11-08T06:00:00Z (set the webhook.trstringer.com/break-glass annotation to true for an emergency change)"},
+		},
+		{
+			name:      "outside window",
+			now:       tuesday,
+			namespace: "prod",
+		},
+		{
+			name:      "unlabeled namespace",
+			now:       saturday,
+			namespace: "dev",
+		},
+		{
+			name:      "system namespace",
+			now:       saturday,
+			namespace: "kube-system",
+		},
+		{
+			name:        "break glass",
+			now:         saturday,
+			namespace:   "prod",
+			annotations: map[string]string{breakGlassAnnotation: "true"},
+		},
+		{
+			name:      "update",
+			now:       saturday,
+			namespace: "prod",
+			operation: OperationUpdate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{FreezeNamespaceLabel: "freeze=true", Client: client, Now: func() time.Time { return tt.now }}
+			e := newTestEngine(t, opts, &Config{FreezeWindows: []FreezeWindow{weekendFreeze}})
+			pod := withMeta(testPod(corev1.PodSpec{}), nil, tt.annotations)
+			pod.Namespace = tt.namespace
+			assertFindings(t, e, pod, RequestMeta{Operation: tt.operation}, tt.want)
+		})
+	}
+}
+
+func TestParseCronSchedule(t *testing.T) {
+	tests := []struct {
+		expr    string
+		matches []time.Time
+		misses  []time.Time
+		wantErr bool
+	}{
+		{
+			expr:    "0 18 * * 5",
+			matches: []time.Time{time.Date(2021, time.November, 5, 18, 0, 0, 0, time.UTC)},
+			misses:  []time.Time{time.Date(2021, time.November, 5, 18, 1, 0, 0, time.UTC), time.Date(2021, time.November, 6, 18, 0, 0, 0, time.UTC)},
+		},
+		{
+			expr:    "*/15 9-17 * * 1-5",
+			matches: []time.Time{time.Date(2021, time.November, 8, 9, 45, 0, 0, time.UTC)},
+			misses:  []time.Time{time.Date(2021, time.November, 8, 9, 50, 0, 0, time.UTC), time.Date(2021, time.November, 7, 9, 45, 0, 0, time.UTC)},
+		},
+		{
+			// Sunday is 7 as well as 0.
+			expr:    "0 0 * * 7",
+			matches: []time.Time{time.Date(2021, time.November, 7, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			// When both day fields are restricted, either matches.
+			expr:    "0 0 1 * 1",
+			matches: []time.Time{time.Date(2021, time.November, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, time.November, 8, 0, 0, 0, 0, time.UTC)},
+			misses:  []time.Time{time.Date(2021, time.November, 2, 0, 0, 0, 0, time.UTC)},
+		},
+		{expr: "0 18 * *", wantErr: true},
+		{expr: "60 * * * *", wantErr: true},
+		{expr: "*/0 * * * *", wantErr: true},
+		{expr: "5-1 * * * *", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := parseCronSchedule(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCronSchedule() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			for _, m := range tt.matches {
+				if !schedule.matches(m) {
+					t.Errorf("matches(%s) = false, want true", m)
+				}
+			}
+			for _, m := range tt.misses {
+				if schedule.matches(m) {
+					t.Errorf("matches(%s) = true, want false", m)
+				}
+			}
+		})
+	}
+}
+
+func TestActiveFreezeWindow(t *testing.T) {
+	newYear := FreezeWindow{Name: "new-year", Schedule: "0 0 1 1 *", Duration: metav1.Duration{Duration: 40 * 24 * time.Hour}}
+	firstOrMonday := FreezeWindow{Name: "first-or-monday", Schedule: "30 6 1 * 1", Duration: metav1.Duration{Duration: time.Hour}}
+	tests := []struct {
+		name      string
+		window    FreezeWindow
+		now       time.Time
+		wantStart time.Time
+	}{
+		{
+			name:      "at start",
+			window:    weekendFreeze,
+			now:       time.Date(2021, time.November, 5, 18, 0, 0, 0, time.UTC),
+			wantStart: time.Date(2021, time.November, 5, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "just before start",
+			window: weekendFreeze,
+			now:    time.Date(2021, time.November, 5, 17, 59, 59, 0, time.UTC),
+		},
+		{
+			name:      "just before end",
+			window:    weekendFreeze,
+			now:       time.Date(2021, time.November, 8, 5, 59, 59, 0, time.UTC),
+			wantStart: time.Date(2021, time.November, 5, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "at end",
+			window: weekendFreeze,
+			now:    time.Date(2021, time.November, 8, 6, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "in another time zone",
+			window:    weekendFreeze,
+			now:       time.Date(2021, time.November, 5, 19, 0, 0, 0, time.FixedZone("CET", 3600)),
+			wantStart: time.Date(2021, time.November, 5, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "started in a previous month and year",
+			window:    newYear,
+			now:       time.Date(2021, time.February, 9, 23, 59, 0, 0, time.UTC),
+			wantStart: time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "ended in a previous month",
+			window: newYear,
+			now:    time.Date(2021, time.February, 10, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "day of week when day of month doesn't match",
+			window:    firstOrMonday,
+			now:       time.Date(2021, time.November, 8, 7, 29, 0, 0, time.UTC),
+			wantStart: time.Date(2021, time.November, 8, 6, 30, 0, 0, time.UTC),
+		},
+		{
+			name:      "day of month when day of week doesn't match",
+			window:    firstOrMonday,
+			now:       time.Date(2021, time.December, 1, 6, 30, 0, 0, time.UTC),
+			wantStart: time.Date(2021, time.December, 1, 6, 30, 0, 0, time.UTC),
+		},
+		{
+			name:   "neither day",
+			window: firstOrMonday,
+			now:    time.Date(2021, time.November, 9, 6, 45, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{}, &Config{FreezeWindows: []FreezeWindow{tt.window}})
+			window, start, ok := e.activeFreezeWindow(tt.now)
+			if ok != !tt.wantStart.IsZero() {
+				t.Fatalf("activeFreezeWindow(%s) active = %t, want %t", tt.now, ok, !tt.wantStart.IsZero())
+			}
+			if ok && (window.Name != tt.window.Name || !start.Equal(tt.wantStart)) {
+				t.Errorf("activeFreezeWindow(%s) = %s from %s, want %s from %s", tt.now, window.Name, start, tt.window.Name, tt.wantStart)
+			}
+		})
+	}
+}
+
+func TestCronScheduleLatest(t *testing.T) {
+	// latest skips whole months, days and hours, so compare it with trying
+	// every minute for a mix of schedules.
+	exprs := []string{"0 18 * * 5", "*/15 9-17 * * 1-5", "30 6 1 * 1", "0 0 29 2 *", "59 23 31 * *"}
+	now := time.Date(2021, time.November, 8, 6, 0, 0, 0, time.UTC)
+	earliest := now.Add(-45 * 24 * time.Hour)
+	for _, expr := range exprs {
+		schedule, err := parseCronSchedule(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want time.Time
+		for m := now; m.After(earliest); m = m.Add(-time.Minute) {
+			if schedule.matches(m) {
+				want = m
+				break
+			}
+		}
+		got, ok := schedule.latest(now, earliest)
+		if ok != !want.IsZero() || !got.Equal(want) {
+			t.Errorf("%s: latest() = %s, %t, want %s", expr, got, ok, want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// checkHPAReplicas rejects horizontal pod autoscalers whose replica bounds
+// don't make sense: minReplicas above maxReplicas, minReplicas of 0 unless
+// the cluster has the HPAScaleToZero feature enabled, or maxReplicas above
+// the cluster's cap.
+func (e *Engine) checkHPAReplicas(ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+	hpa := obj.(*autoscalingv1.HorizontalPodAutoscaler)
+
+	// minReplicas defaults to 1.
+	minReplicas := int32(1)
+	if hpa.Spec.MinReplicas != nil {
+		minReplicas = *hpa.Spec.MinReplicas
+	}
+	maxReplicas := hpa.Spec.MaxReplicas
+
+	var findings []Finding
+	if minReplicas > maxReplicas {
+		findings = append(findings, Finding{Message: fmt.Sprintf("minReplicas (%d) is more than maxReplicas (%d)", minReplicas, maxReplicas)})
+	}
+	if minReplicas == 0 && !e.opts.AllowHPAScaleToZero {
+		findings = append(findings, Finding{Message: "minReplicas must be at least 1, since scaling to zero requires the HPAScaleToZero feature"})
+	}
+	if e.opts.MaxHPAReplicas > 0 && maxReplicas > e.opts.MaxHPAReplicas {
+		findings = append(findings, Finding{Message: fmt.Sprintf("maxReplicas (%d) exceeds the cluster maximum of %d", maxReplicas, e.opts.MaxHPAReplicas)})
+	}
+	return findings, nil
+}
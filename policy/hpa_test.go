@@ -0,0 +1,60 @@
+package policy
+
+import (
+	"testing"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+)
+
+func TestCheckHPAReplicas(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		minReplicas *int32
+		maxReplicas int32
+		want        []string
+	}{
+		{
+			name:        "valid",
+			minReplicas: int32Ptr(2),
+			maxReplicas: 10,
+		},
+		{
+			name:        "default min",
+			maxReplicas: 1,
+		},
+		{
+			name:        "min over max",
+			minReplicas: int32Ptr(5),
+			maxReplicas: 3,
+			want:        []string{"hpa-replicas: minReplicas (5) is more than maxReplicas (3)"},
+		},
+		{
+			name:        "scale to zero",
+			minReplicas: int32Ptr(0),
+			maxReplicas: 3,
+			want:        []string{"hpa-replicas: minReplicas must be at least 1, since scaling to zero requires the HPAScaleToZero feature"},
+		},
+		{
+			name:        "scale to zero allowed",
+			opts:        Options{AllowHPAScaleToZero: true},
+			minReplicas: int32Ptr(0),
+			maxReplicas: 3,
+		},
+		{
+			name:        "over cluster max",
+			opts:        Options{MaxHPAReplicas: 50},
+			maxReplicas: 100,
+			want:        []string{"hpa-replicas: maxReplicas (100) exceeds the cluster maximum of 50"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.ValidateHPAReplicas = true
+			e := newTestEngine(t, tt.opts, nil)
+			hpa := &autoscalingv1.HorizontalPodAutoscaler{Spec: autoscalingv1.HorizontalPodAutoscalerSpec{MinReplicas: tt.minReplicas, MaxReplicas: tt.maxReplicas}}
+			assertFindings(t, e, hpa, RequestMeta{}, tt.want)
+		})
+	}
+}
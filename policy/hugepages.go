@@ -0,0 +1,88 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// checkHugePages rejects containers that request huge pages without a limit
+// equal to the request, which Kubernetes requires, and warns about pods that
+// request huge pages but aren't scheduled onto nodes that have them. With a
+// huge pages node label, the pod must select or tolerate it; otherwise any
+// nodeSelector, node affinity or toleration is taken as a hint.
+func (e *Engine) checkHugePages(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	requestsHugePages := false
+	for _, container := range allContainers(pod) {
+		for name, request := range container.Resources.Requests {
+			if !strings.HasPrefix(string(name), corev1.ResourceHugePagesPrefix) {
+				continue
+			}
+			requestsHugePages = true
+			limit, ok := container.Resources.Limits[name]
+			if !ok {
+				findings = append(findings, Finding{
+					Container: container.Name,
+					Message:   fmt.Sprintf("requests %s %s without a limit, but huge pages requests must equal their limits", request.String(), name),
+				})
+			} else if request.Cmp(limit) != 0 {
+				findings = append(findings, Finding{
+					Container: container.Name,
+					Message:   fmt.Sprintf("requests %s %s with a limit of %s, but huge pages requests must equal their limits", request.String(), name, limit.String()),
+				})
+			}
+		}
+		for name := range container.Resources.Limits {
+			if strings.HasPrefix(string(name), corev1.ResourceHugePagesPrefix) {
+				requestsHugePages = true
+			}
+		}
+	}
+
+	if requestsHugePages && !e.schedulesOnHugePagesNodes(pod) {
+		msg := "requests huge pages without a nodeSelector, node affinity or toleration for nodes that have them"
+		if e.hugePagesNodeLabel != nil {
+			msg = fmt.Sprintf("requests huge pages without selecting or tolerating huge pages node label %s", e.hugePagesNodeLabel)
+		}
+		findings = append(findings, Finding{Message: msg, Warning: true})
+	}
+	return findings, nil
+}
+
+// schedulesOnHugePagesNodes reports whether the pod is scheduled onto nodes
+// that have huge pages.
+func (e *Engine) schedulesOnHugePagesNodes(pod *corev1.Pod) bool {
+	var nodeAffinity *corev1.NodeAffinity
+	if pod.Spec.Affinity != nil {
+		nodeAffinity = pod.Spec.Affinity.NodeAffinity
+	}
+
+	label := e.hugePagesNodeLabel
+	if label == nil {
+		return len(pod.Spec.NodeSelector) > 0 || nodeAffinity != nil || len(pod.Spec.Tolerations) > 0
+	}
+
+	for key, value := range pod.Spec.NodeSelector {
+		if label.matches(key, value) {
+			return true
+		}
+	}
+	for _, toleration := range pod.Spec.Tolerations {
+		if toleration.Key == label.key {
+			return true
+		}
+	}
+	if nodeAffinity != nil && nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		for _, term := range nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+			for _, expr := range term.MatchExpressions {
+				if expr.Key == label.key && selectsNodeLabel(expr, *label) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
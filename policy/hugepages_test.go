@@ -0,0 +1,82 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestCheckHugePages(t *testing.T) {
+	hugePages := corev1.ResourceName(corev1.ResourceHugePagesPrefix + "2Mi")
+	resources := func(request, limit string) corev1.ResourceRequirements {
+		var r corev1.ResourceRequirements
+		if request != "" {
+			r.Requests = corev1.ResourceList{hugePages: resource.MustParse(request)}
+		}
+		if limit != "" {
+			r.Limits = corev1.ResourceList{hugePages: resource.MustParse(limit)}
+		}
+		return r
+	}
+	tests := []struct {
+		name      string
+		nodeLabel string
+		resources corev1.ResourceRequirements
+		spec      corev1.PodSpec
+		want      []string
+	}{
+		{
+			name:      "request equals limit",
+			resources: resources("100Mi", "100Mi"),
+			spec:      corev1.PodSpec{NodeSelector: map[string]string{"hugepages": "true"}},
+		},
+		{
+			name:      "no limit",
+			resources: resources("100Mi", ""),
+			spec:      corev1.PodSpec{NodeSelector: map[string]string{"hugepages": "true"}},
+			want:      []string{"hugepages: container app: requests 100Mi hugepages-2Mi without a limit, but huge pages requests must equal their limits"},
+		},
+		{
+			name:      "request under limit",
+			resources: resources("50Mi", "100Mi"),
+			spec:      corev1.PodSpec{NodeSelector: map[string]string{"hugepages": "true"}},
+			want:      []string{"hugepages: container app: requests 50Mi hugepages-2Mi with a limit of 100Mi, but huge pages requests must equal their limits"},
+		},
+		{
+			name:      "not scheduled onto huge pages nodes",
+			resources: resources("", "100Mi"),
+			want:      []string{"hugepages: warning: requests huge pages without a nodeSelector, node affinity or toleration for nodes that have them"},
+		},
+		{
+			name:      "node label selected",
+			nodeLabel: "hugepages=true",
+			resources: resources("100Mi", "100Mi"),
+			spec:      corev1.PodSpec{NodeSelector: map[string]string{"hugepages": "true"}},
+		},
+		{
+			name:      "node label tolerated",
+			nodeLabel: "hugepages=true",
+			resources: resources("100Mi", "100Mi"),
+			spec:      corev1.PodSpec{Tolerations: []corev1.Toleration{{Key: "hugepages", Operator: corev1.TolerationOpExists}}},
+		},
+		{
+			name:      "other node label selected",
+			nodeLabel: "hugepages=true",
+			resources: resources("100Mi", "100Mi"),
+			spec:      corev1.PodSpec{NodeSelector: map[string]string{"pool": "general"}},
+			want:      []string{"hugepages: warning: requests huge pages without selecting or tolerating huge pages node label hugepages=true"},
+		},
+		{
+			name: "no huge pages",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{ValidateHugePages: true, HugePagesNodeLabel: tt.nodeLabel}, nil)
+			tt.spec.Containers = []corev1.Container{{Name: "app", Resources: tt.resources}}
+			assertFindings(t, e, testPod(tt.spec), RequestMeta{}, tt.want)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// idRange is an inclusive range of user or group IDs.
+type idRange struct {
+	min, max int64
+}
+
+func (r idRange) String() string {
+	return fmt.Sprintf("%d-%d", r.min, r.max)
+}
+
+// parseIDRanges parses ranges of IDs given as min-max, or a single ID.
+func parseIDRanges(values []string) ([]idRange, error) {
+	var ranges []idRange
+	for _, value := range values {
+		parts := strings.SplitN(value, "-", 2)
+		min, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ID range %q", value)
+		}
+		max := min
+		if len(parts) == 2 {
+			if max, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid ID range %q", value)
+			}
+		}
+		if min < 0 || max < min {
+			return nil, fmt.Errorf("invalid ID range %q", value)
+		}
+		ranges = append(ranges, idRange{min: min, max: max})
+	}
+	return ranges, nil
+}
+
+// inIDRanges reports whether id is in any of the ranges. There are no
+// restrictions without any ranges.
+func inIDRanges(ranges []idRange, id int64) bool {
+	if len(ranges) == 0 {
+		return true
+	}
+	for _, r := range ranges {
+		if id >= r.min && id <= r.max {
+			return true
+		}
+	}
+	return false
+}
+
+// formatIDRanges formats ranges for messages, e.g. 1000-1999, 5000-5999.
+func formatIDRanges(ranges []idRange) string {
+	s := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		s = append(s, r.String())
+	}
+	return strings.Join(s, ", ")
+}
+
+// checkIDRanges rejects pods that set an fsGroup, runAsUser or runAsGroup
+// outside of the allowed ranges, like PodSecurityPolicy's MustRunAs, so that
+// tenants can't take on the IDs that own other tenants' storage. IDs that
+// aren't set aren't checked.
+func (e *Engine) checkIDRanges(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	check := func(container, field string, id *int64, ranges []idRange) {
+		if id != nil && !inIDRanges(ranges, *id) {
+			findings = append(findings, Finding{
+				Container: container,
+				Message:   fmt.Sprintf("%s %d is outside of the allowed ranges %s", field, *id, formatIDRanges(ranges)),
+			})
+		}
+	}
+
+	if psc := pod.Spec.SecurityContext; psc != nil {
+		check("", "securityContext.fsGroup", psc.FSGroup, e.fsGroupRanges)
+		check("", "securityContext.runAsUser", psc.RunAsUser, e.runAsUserRanges)
+		check("", "securityContext.runAsGroup", psc.RunAsGroup, e.runAsGroupRanges)
+	}
+	for _, container := range allContainers(pod) {
+		if sc := container.SecurityContext; sc != nil {
+			check(container.Name, "securityContext.runAsUser", sc.RunAsUser, e.runAsUserRanges)
+			check(container.Name, "securityContext.runAsGroup", sc.RunAsGroup, e.runAsGroupRanges)
+		}
+	}
+	return findings, nil
+}
@@ -0,0 +1,77 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCheckIDRanges(t *testing.T) {
+	opts := Options{FSGroupRanges: []string{"1000-1999"}, RunAsUserRanges: []string{"1000-1999", "5000"}}
+	tests := []struct {
+		name               string
+		podSecurityContext *corev1.PodSecurityContext
+		securityContext    *corev1.SecurityContext
+		want               []string
+	}{
+		{
+			name:               "in ranges",
+			podSecurityContext: &corev1.PodSecurityContext{FSGroup: int64Ptr(1000), RunAsUser: int64Ptr(1999)},
+			securityContext:    &corev1.SecurityContext{RunAsUser: int64Ptr(5000)},
+		},
+		{
+			name: "unset",
+		},
+		{
+			name:               "pod fsGroup out of range",
+			podSecurityContext: &corev1.PodSecurityContext{FSGroup: int64Ptr(2000)},
+			want:               []string{"id-ranges: securityContext.fsGroup 2000 is outside of the allowed ranges 1000-1999"},
+		},
+		{
+			name:            "container runAsUser out of range",
+			securityContext: &corev1.SecurityContext{RunAsUser: int64Ptr(0)},
+			want:            []string{"id-ranges: container app: securityContext.runAsUser 0 is outside of the allowed ranges 1000-1999, 5000-5000"},
+		},
+		{
+			name:            "unrestricted runAsGroup",
+			securityContext: &corev1.SecurityContext{RunAsGroup: int64Ptr(0)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, opts, nil)
+			pod := testPod(corev1.PodSpec{
+				SecurityContext: tt.podSecurityContext,
+				Containers:      []corev1.Container{{Name: "app", SecurityContext: tt.securityContext}},
+			})
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestParseIDRanges(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    idRange
+		wantErr bool
+	}{
+		{value: "1000-1999", want: idRange{min: 1000, max: 1999}},
+		{value: "5000", want: idRange{min: 5000, max: 5000}},
+		{value: "2000-1000", wantErr: true},
+		{value: "-1", wantErr: true},
+		{value: "a-b", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			ranges, err := parseIDRanges([]string{tt.value})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseIDRanges() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !tt.wantErr && ranges[0] != tt.want {
+				t.Errorf("parseIDRanges() = %v, want %v", ranges[0], tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// checkImageSize rejects containers whose image is bigger than the
+// configured maximum, for nodes with limited disk. The size is the
+// compressed size listed in the image's manifest, which is looked up in the
+// image's registry.
+func (e *Engine) checkImageSize(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	for _, container := range allContainers(pod) {
+		size, err := e.imageSize(ctx, container.Image)
+		if err != nil {
+			return nil, fmt.Errorf("error looking up size of image %s: %w", container.Image, err)
+		}
+		if size > e.maxImageSize.Value() {
+			findings = append(findings, Finding{
+				Container: container.Name,
+				Message:   fmt.Sprintf("image %s (%s) exceeds the maximum image size of %s", container.Image, formatBytes(size), e.maxImageSize.String()),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// imageSize returns the size of the image, using the lookup cache.
+func (e *Engine) imageSize(ctx context.Context, image string) (int64, error) {
+	size, err := e.lookups.get(fmt.Sprintf("image/%s", image), func() (interface{}, error) {
+		ref, err := parseImageReference(image)
+		if err != nil {
+			return nil, err
+		}
+		return e.registry.imageSize(ctx, ref)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return size.(int64), nil
+}
+
+// formatBytes formats a number of bytes with a binary unit, e.g. 1.5Gi.
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d bytes", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ci", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,65 @@
+package policy
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCheckImageSize(t *testing.T) {
+	registry := newTestRegistry(t, map[string]testImage{
+		"app:small": {layers: []int64{1000}},
+		"app:big":   {layers: []int64{1 << 20, 1 << 20}},
+	})
+
+	tests := []struct {
+		name  string
+		image string
+		want  []string
+	}{
+		{
+			name:  "under max",
+			image: registry + "/app:small",
+		},
+		{
+			name:  "over max",
+			image: registry + "/app:big",
+			want:  []string{"image-size: container app: image " + registry + "/app:big (2.0Mi) exceeds the maximum image size of 1Mi"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{MaxImageSize: "1Mi"}, nil)
+			pod := testPod(corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: tt.image}}})
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestCheckImageSizeLookupError(t *testing.T) {
+	registry := newTestRegistry(t, nil)
+	e := newTestEngine(t, Options{MaxImageSize: "1Mi"}, nil)
+	pod := testPod(corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: registry + "/app:missing"}}})
+	if _, err := e.checkImageSize(context.Background(), pod, RequestMeta{}); err == nil {
+		t.Error("checkImageSize() error = nil, want an error for an image that isn't in the registry")
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		bytes int64
+		want  string
+	}{
+		{bytes: 512, want: "512 bytes"},
+		{bytes: 1536, want: "1.5Ki"},
+		{bytes: 3 << 30, want: "3.0Gi"},
+	}
+
+	for _, tt := range tests {
+		if got := formatBytes(tt.bytes); got != tt.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", tt.bytes, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// checkImmutableFields rejects updates of objects of any resource that
+// change one of the immutable fields, such as spec.serviceAccountName,
+// beyond the fields Kubernetes itself makes immutable. Objects that aren't
+// being updated, and so have no old object, aren't checked.
+func (e *Engine) checkImmutableFields(ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+	if meta.OldObject == nil {
+		return nil, nil
+	}
+
+	newFields, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, fmt.Errorf("error converting object: %w", err)
+	}
+	oldFields, err := runtime.DefaultUnstructuredConverter.ToUnstructured(meta.OldObject)
+	if err != nil {
+		return nil, fmt.Errorf("error converting old object: %w", err)
+	}
+
+	var findings []Finding
+	for _, path := range e.immutableFields {
+		oldValue, _, _ := unstructured.NestedFieldNoCopy(oldFields, path...)
+		newValue, _, _ := unstructured.NestedFieldNoCopy(newFields, path...)
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		findings = append(findings, Finding{Message: fmt.Sprintf("field %s is immutable, but it was changed from %s to %s", strings.Join(path, "."), fieldValueString(oldValue), fieldValueString(newValue))})
+	}
+	return findings, nil
+}
+
+// fieldValueString returns the field value as JSON, or <unset> if the field
+// isn't set.
+func fieldValueString(value interface{}) string {
+	if value == nil {
+		return "<unset>"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
@@ -0,0 +1,79 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCheckImmutableFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		old    *corev1.Pod
+		update func(pod *corev1.Pod)
+		want   []string
+	}{
+		{
+			name:   "create",
+			update: func(pod *corev1.Pod) { pod.Spec.ServiceAccountName = "admin" },
+		},
+		{
+			name:   "unchanged",
+			old:    testPod(corev1.PodSpec{ServiceAccountName: "web"}),
+			update: func(pod *corev1.Pod) { pod.Spec.ServiceAccountName = "web" },
+		},
+		{
+			name:   "unprotected field changed",
+			old:    testPod(corev1.PodSpec{ServiceAccountName: "web"}),
+			update: func(pod *corev1.Pod) { pod.Spec.ServiceAccountName, pod.Spec.NodeName = "web", "node-2" },
+		},
+		{
+			name:   "protected field changed",
+			old:    testPod(corev1.PodSpec{ServiceAccountName: "web"}),
+			update: func(pod *corev1.Pod) { pod.Spec.ServiceAccountName = "admin" },
+			want:   []string{`immutable-fields: field spec.serviceAccountName is immutable, but it was changed from "web" to "admin"`},
+		},
+		{
+			name:   "protected field set",
+			old:    testPod(corev1.PodSpec{}),
+			update: func(pod *corev1.Pod) { pod.Spec.ServiceAccountName = "admin" },
+			want:   []string{`immutable-fields: field spec.serviceAccountName is immutable, but it was changed from <unset> to "admin"`},
+		},
+		{
+			name:   "protected field unset",
+			old:    testPod(corev1.PodSpec{ServiceAccountName: "web"}),
+			update: func(pod *corev1.Pod) { pod.Spec.ServiceAccountName = "" },
+			want:   []string{`immutable-fields: field spec.serviceAccountName is immutable, but it was changed from "web" to <unset>`},
+		},
+		{
+			name:   "protected label changed",
+			old:    withMeta(testPod(corev1.PodSpec{}), map[string]string{"team": "web"}, nil),
+			update: func(pod *corev1.Pod) { pod.Labels["team"] = "data" },
+			want:   []string{`immutable-fields: field metadata.labels.team is immutable, but it was changed from "web" to "data"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{ImmutableFields: []string{"spec.serviceAccountName", "metadata.labels.team"}}, nil)
+			pod := testPod(corev1.PodSpec{})
+			if tt.old != nil {
+				pod = tt.old.DeepCopy()
+			}
+			if tt.update != nil {
+				tt.update(pod)
+			}
+			meta := RequestMeta{}
+			if tt.old != nil {
+				meta.Operation, meta.OldObject = OperationUpdate, tt.old
+			}
+			assertFindings(t, e, pod, meta, tt.want)
+		})
+	}
+}
+
+func TestImmutableFieldsOption(t *testing.T) {
+	if _, err := NewEngine(Options{ImmutableFields: []string{"spec..serviceAccountName"}}, nil); err == nil {
+		t.Error("NewEngine() error = nil, want an error for an empty path element")
+	}
+}
@@ -0,0 +1,76 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// checkIngressTLS rejects ingresses with rules for public hosts, those that
+// match the public host pattern, that aren't covered by a TLS entry, so that
+// public endpoints are always served over HTTPS.
+func (e *Engine) checkIngressTLS(ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+	ingress := obj.(*networkingv1.Ingress)
+
+	var findings []Finding
+	for _, ingressRule := range ingress.Spec.Rules {
+		host := ingressRule.Host
+		if host == "" || !e.publicHostPattern.MatchString(host) || ingressHasTLS(ingress, host) {
+			continue
+		}
+		findings = append(findings, Finding{
+			Message: fmt.Sprintf("public host %s must be listed in a spec.tls entry", host),
+		})
+	}
+	return findings, nil
+}
+
+// legacyIngressClassAnnotation is the deprecated annotation that set an
+// ingress's class before spec.ingressClassName.
+const legacyIngressClassAnnotation = "kubernetes.io/ingress.class"
+
+// checkIngressClass rejects ingresses whose class, in spec.ingressClassName
+// or the legacy annotation, isn't allowed, so that they aren't served by
+// unsupported or insecure ingress controllers. Ingresses that don't set a
+// class use the cluster's default, which is only allowed with
+// AllowDefaultIngressClass set.
+func (e *Engine) checkIngressClass(ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+	ingress := obj.(*networkingv1.Ingress)
+	allowed := strings.Join(e.opts.AllowedIngressClasses, ", ")
+
+	var findings []Finding
+	className := ingress.Spec.IngressClassName
+	if className != nil && !contains(e.opts.AllowedIngressClasses, *className) {
+		findings = append(findings, Finding{Message: fmt.Sprintf("ingress class %s in spec.ingressClassName is not allowed, must be one of %s", *className, allowed)})
+	}
+	annotationClass, hasAnnotation := ingress.Annotations[legacyIngressClassAnnotation]
+	if hasAnnotation && !contains(e.opts.AllowedIngressClasses, annotationClass) {
+		findings = append(findings, Finding{Message: fmt.Sprintf("ingress class %s in annotation %s is not allowed, must be one of %s", annotationClass, legacyIngressClassAnnotation, allowed)})
+	}
+
+	if className == nil && !hasAnnotation && !e.opts.AllowDefaultIngressClass {
+		findings = append(findings, Finding{Message: fmt.Sprintf("ingress class must be set in spec.ingressClassName to one of %s", allowed)})
+	}
+	return findings, nil
+}
+
+// ingressHasTLS reports whether one of the ingress's TLS entries covers the
+// host, either exactly or with a wildcard such as *.example.com.
+func ingressHasTLS(ingress *networkingv1.Ingress, host string) bool {
+	for _, tls := range ingress.Spec.TLS {
+		for _, tlsHost := range tls.Hosts {
+			if tlsHost == host {
+				return true
+			}
+			if strings.HasPrefix(tlsHost, "*.") {
+				if i := strings.Index(host, "."); i > 0 && host[i:] == tlsHost[1:] {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
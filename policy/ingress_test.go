@@ -0,0 +1,99 @@
+package policy
+
+import (
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func stringPtr(s string) *string { return &s }
+
+func TestCheckIngressTLS(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+		tls  []networkingv1.IngressTLS
+		want []string
+	}{
+		{
+			name: "public host with TLS",
+			host: "shop.example.com",
+			tls:  []networkingv1.IngressTLS{{Hosts: []string{"shop.example.com"}}},
+		},
+		{
+			name: "public host with wildcard TLS",
+			host: "shop.example.com",
+			tls:  []networkingv1.IngressTLS{{Hosts: []string{"*.example.com"}}},
+		},
+		{
+			name: "public host without TLS",
+			host: "shop.example.com",
+			tls:  []networkingv1.IngressTLS{{Hosts: []string{"*.shop.example.com"}}},
+			want: []string{"ingress-tls: public host shop.example.com must be listed in a spec.tls entry"},
+		},
+		{
+			name: "internal host",
+			host: "shop.internal",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{PublicHostPattern: `\.example\.com$`}, nil)
+			ingress := &networkingv1.Ingress{Spec: networkingv1.IngressSpec{
+				TLS:   tt.tls,
+				Rules: []networkingv1.IngressRule{{Host: tt.host}},
+			}}
+			assertFindings(t, e, ingress, RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestCheckIngressClass(t *testing.T) {
+	tests := []struct {
+		name         string
+		allowDefault bool
+		className    *string
+		annotation   string
+		want         []string
+	}{
+		{
+			name:      "allowed class",
+			className: stringPtr("nginx"),
+		},
+		{
+			name:      "class not allowed",
+			className: stringPtr("traefik"),
+			want:      []string{"ingress-class: ingress class traefik in spec.ingressClassName is not allowed, must be one of nginx, internal"},
+		},
+		{
+			name:       "annotation not allowed",
+			annotation: "traefik",
+			want:       []string{"ingress-class: ingress class traefik in annotation kubernetes.io/ingress.class is not allowed, must be one of nginx, internal"},
+		},
+		{
+			name:       "allowed annotation",
+			annotation: "internal",
+		},
+		{
+			name: "default class not allowed",
+			want: []string{"ingress-class: ingress class must be set in spec.ingressClassName to one of nginx, internal"},
+		},
+		{
+			name:         "default class allowed",
+			allowDefault: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{AllowedIngressClasses: []string{"nginx", "internal"}, AllowDefaultIngressClass: tt.allowDefault}, nil)
+			ingress := &networkingv1.Ingress{Spec: networkingv1.IngressSpec{IngressClassName: tt.className}}
+			if tt.annotation != "" {
+				ingress.ObjectMeta = metav1.ObjectMeta{Annotations: map[string]string{legacyIngressClassAnnotation: tt.annotation}}
+			}
+			assertFindings(t, e, ingress, RequestMeta{}, tt.want)
+		})
+	}
+}
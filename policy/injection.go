@@ -0,0 +1,61 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DefaultInjectionPattern matches characters that can inject into logs or
+// shell commands: newlines, null bytes and shell metacharacters.
+const DefaultInjectionPattern = "[\\r\\n\\x00`$;|&<>]"
+
+// checkMetadataInjection rejects objects of any resource whose labels or
+// annotations that are to be sanitized, because they feed into downstream
+// systems such as logs or scripts, contain injection characters. A
+// sanitized key ending in * covers every key with that prefix.
+func (e *Engine) checkMetadataInjection(ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+	accessor, err := apimeta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, metadata := range []struct {
+		kind   string
+		values map[string]string
+	}{
+		{"label", accessor.GetLabels()},
+		{"annotation", accessor.GetAnnotations()},
+	} {
+		keys := make([]string, 0, len(metadata.values))
+		for key := range metadata.values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if !e.sanitized(key) {
+				continue
+			}
+			if match := e.injectionPattern.FindString(metadata.values[key]); match != "" {
+				findings = append(findings, Finding{Message: fmt.Sprintf("%s %s contains %q, which isn't allowed in sanitized metadata", metadata.kind, key, match)})
+			}
+		}
+	}
+	return findings, nil
+}
+
+// sanitized reports whether the label or annotation key is to be sanitized.
+func (e *Engine) sanitized(key string) bool {
+	for _, sanitize := range e.opts.SanitizeMetadataKeys {
+		if sanitize == key || (strings.HasSuffix(sanitize, "*") && strings.HasPrefix(key, strings.TrimSuffix(sanitize, "*"))) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCheckMetadataInjection(t *testing.T) {
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		want        []string
+	}{
+		{
+			name:        "clean",
+			annotations: map[string]string{"example.com/description": "the web frontend"},
+		},
+		{
+			name:        "newline",
+			annotations: map[string]string{"example.com/description": "web\nINFO: fake log line"},
+			want:        []string{`metadata-injection: annotation example.com/description contains "\n", which isn't allowed in sanitized metadata`},
+		},
+		{
+			name:   "shell metacharacter in label",
+			labels: map[string]string{"owner": "alice;rm"},
+			want:   []string{`metadata-injection: label owner contains ";", which isn't allowed in sanitized metadata`},
+		},
+		{
+			name:        "not sanitized",
+			annotations: map[string]string{"other": "a;b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{SanitizeMetadataKeys: []string{"owner", "example.com/*"}}, nil)
+			assertFindings(t, e, withMeta(testPod(corev1.PodSpec{}), tt.labels, tt.annotations), RequestMeta{}, tt.want)
+		})
+	}
+}
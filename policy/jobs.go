@@ -0,0 +1,28 @@
+package policy
+
+import (
+	"context"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// checkJobTTL rejects jobs that don't set ttlSecondsAfterFinished, so that
+// finished jobs and their pods are garbage collected rather than piling up.
+func (e *Engine) checkJobTTL(ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+	job := obj.(*batchv1.Job)
+	if job.Spec.TTLSecondsAfterFinished == nil {
+		return []Finding{{Message: "job must set spec.ttlSecondsAfterFinished"}}, nil
+	}
+	return nil, nil
+}
+
+// checkCronJobTTL rejects cron jobs whose job template doesn't set
+// ttlSecondsAfterFinished, for the same reason as checkJobTTL.
+func (e *Engine) checkCronJobTTL(ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+	cronJob := obj.(*batchv1.CronJob)
+	if cronJob.Spec.JobTemplate.Spec.TTLSecondsAfterFinished == nil {
+		return []Finding{{Message: "cron job must set spec.jobTemplate.spec.ttlSecondsAfterFinished"}}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,44 @@
+package policy
+
+import (
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestCheckJobTTL(t *testing.T) {
+	tests := []struct {
+		name string
+		obj  runtime.Object
+		want []string
+	}{
+		{
+			name: "job with TTL",
+			obj:  &batchv1.Job{Spec: batchv1.JobSpec{TTLSecondsAfterFinished: int32Ptr(3600)}},
+		},
+		{
+			name: "job without TTL",
+			obj:  &batchv1.Job{},
+			want: []string{"job-ttl: job must set spec.ttlSecondsAfterFinished"},
+		},
+		{
+			name: "cron job with TTL",
+			obj: &batchv1.CronJob{Spec: batchv1.CronJobSpec{JobTemplate: batchv1.JobTemplateSpec{
+				Spec: batchv1.JobSpec{TTLSecondsAfterFinished: int32Ptr(3600)},
+			}}},
+		},
+		{
+			name: "cron job without TTL",
+			obj:  &batchv1.CronJob{},
+			want: []string{"cronjob-ttl: cron job must set spec.jobTemplate.spec.ttlSecondsAfterFinished"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{RequireJobTTL: true}, nil)
+			assertFindings(t, e, tt.obj, RequestMeta{}, tt.want)
+		})
+	}
+}
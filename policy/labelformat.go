@@ -0,0 +1,75 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// LabelFormat requires the value of a label, if the label is set, to be in
+// one of the built-in formats.
+type LabelFormat struct {
+	// Label is the key of the label to check.
+	Label string `json:"label"`
+	// Format is the name of the format the value must be in.
+	Format string `json:"format"`
+}
+
+// labelFormat is a built-in format that label values can be required to be in.
+type labelFormat struct {
+	description string
+	pattern     *regexp.Regexp
+}
+
+// labelFormats are the built-in formats, by the name they are selected with.
+var labelFormats = map[string]labelFormat{
+	"git-sha": {
+		description: "a 40 character hex git commit SHA",
+		pattern:     regexp.MustCompile(`^[0-9a-f]{40}$`),
+	},
+	"git-short-sha": {
+		description: "a 7 to 40 character hex git commit SHA",
+		pattern:     regexp.MustCompile(`^[0-9a-f]{7,40}$`),
+	},
+	"semver": {
+		description: "a semantic version such as 1.2.3 or v1.2.3-rc.1",
+		pattern:     regexp.MustCompile(`^v?(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)(-[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?$`),
+	},
+	"uuid": {
+		description: "a lowercase UUID",
+		pattern:     regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`),
+	},
+}
+
+// labelFormatNames returns the names of the built-in formats, sorted.
+func labelFormatNames() []string {
+	names := make([]string, 0, len(labelFormats))
+	for name := range labelFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkLabelFormats rejects pods with labels whose values aren't in the
+// format the config requires for them. Labels that aren't set are left to
+// the rules that require labels.
+func (e *Engine) checkLabelFormats(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	for _, lf := range e.labelFormats {
+		value, ok := pod.Labels[lf.Label]
+		if !ok {
+			continue
+		}
+		format := labelFormats[lf.Format]
+		if !format.pattern.MatchString(value) {
+			findings = append(findings, Finding{
+				Message: fmt.Sprintf("label %s value %q must be %s (%s)", lf.Label, value, format.description, lf.Format),
+			})
+		}
+	}
+	return findings, nil
+}
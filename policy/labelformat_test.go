@@ -0,0 +1,42 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCheckLabelFormats(t *testing.T) {
+	cfg := &Config{LabelFormats: []LabelFormat{
+		{Label: "commit", Format: "git-sha"},
+		{Label: "version", Format: "semver"},
+	}}
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   []string
+	}{
+		{
+			name:   "valid",
+			labels: map[string]string{"commit": "0123456789abcdef0123456789abcdef01234567", "version": "v1.2.3-rc.1"},
+		},
+		{
+			name: "unset",
+		},
+		{
+			name:   "invalid",
+			labels: map[string]string{"commit": "0123456", "version": "1.2"},
+			want: []string{
+				`label-format: label commit value "0123456" must be a 40 character hex git commit SHA (git-sha)`,
+				`label-format: label version value "1.2" must be a semantic version such as 1.2.3 or v1.2.3-rc.1 (semver)`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{}, cfg)
+			assertFindings(t, e, withMeta(testPod(corev1.PodSpec{}), tt.labels, nil), RequestMeta{}, tt.want)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// checkLimitConsistency warns about containers whose memory or
+// ephemeral-storage limit is more than LimitRatio times the total requests
+// of the whole pod, which is usually a typo such as 10Gi instead of 1Gi.
+func (e *Engine) checkLimitConsistency(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	for _, resourceName := range []corev1.ResourceName{corev1.ResourceMemory, corev1.ResourceEphemeralStorage} {
+		var totalRequests int64
+		for _, container := range pod.Spec.Containers {
+			if request, ok := container.Resources.Requests[resourceName]; ok {
+				totalRequests += request.Value()
+			}
+		}
+		if totalRequests == 0 {
+			continue
+		}
+
+		for _, container := range pod.Spec.Containers {
+			limit, ok := container.Resources.Limits[resourceName]
+			if !ok {
+				continue
+			}
+			if ratio := float64(limit.Value()) / float64(totalRequests); ratio > e.opts.LimitRatio {
+				findings = append(findings, Finding{
+					Container: container.Name,
+					Message:   fmt.Sprintf("%s limit (%s) is %.1fx the pod's total %s requests, which may be a typo", resourceName, limit.String(), ratio, resourceName),
+					Warning:   true,
+				})
+			}
+		}
+	}
+	return findings, nil
+}
+
+// checkMaxEnvVars rejects containers with more than MaxEnvVars environment
+// variables, which usually means configuration has been generated out of
+// control. Both literal values and valueFrom references are counted.
+func (e *Engine) checkMaxEnvVars(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	for _, container := range allContainers(pod) {
+		if count := len(container.Env); count > e.opts.MaxEnvVars {
+			findings = append(findings, Finding{
+				Container: container.Name,
+				Message:   fmt.Sprintf("has %d environment variables, more than the maximum of %d", count, e.opts.MaxEnvVars),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// checkMaxVolumeMounts rejects containers with more than MaxVolumeMounts
+// volume mounts, which usually means mounts are being generated out of
+// control.
+func (e *Engine) checkMaxVolumeMounts(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	for _, container := range allContainers(pod) {
+		if count := len(container.VolumeMounts); count > e.opts.MaxVolumeMounts {
+			findings = append(findings, Finding{
+				Container: container.Name,
+				Message:   fmt.Sprintf("has %d volumeMounts, more than the maximum of %d", count, e.opts.MaxVolumeMounts),
+			})
+		}
+	}
+	return findings, nil
+}
@@ -0,0 +1,114 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestCheckLimitConsistency(t *testing.T) {
+	tests := []struct {
+		name       string
+		containers []corev1.Container
+		want       []string
+	}{
+		{
+			name: "within ratio",
+			containers: []corev1.Container{{
+				Name: "app",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+					Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+				},
+			}},
+		},
+		{
+			name: "over ratio",
+			containers: []corev1.Container{{
+				Name: "app",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+					Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("10Gi")},
+				},
+			}},
+			want: []string{"limit-consistency: warning: container app: memory limit (10Gi) is 10.0x the pod's total memory requests, which may be a typo"},
+		},
+		{
+			name: "requests across containers",
+			containers: []corev1.Container{
+				{Name: "app", Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceEphemeralStorage: resource.MustParse("1Gi")}}},
+				{Name: "sidecar", Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceEphemeralStorage: resource.MustParse("1Gi")},
+					Limits:   corev1.ResourceList{corev1.ResourceEphemeralStorage: resource.MustParse("8Gi")},
+				}},
+			},
+		},
+		{
+			name: "no requests",
+			containers: []corev1.Container{{
+				Name:      "app",
+				Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("10Gi")}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{LimitRatio: 4}, nil)
+			assertFindings(t, e, testPod(corev1.PodSpec{Containers: tt.containers}), RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestCheckMaxEnvVars(t *testing.T) {
+	tests := []struct {
+		name string
+		env  []corev1.EnvVar
+		want []string
+	}{
+		{
+			name: "at max",
+			env:  []corev1.EnvVar{{Name: "A"}, {Name: "B", ValueFrom: &corev1.EnvVarSource{}}},
+		},
+		{
+			name: "over max",
+			env:  []corev1.EnvVar{{Name: "A"}, {Name: "B"}, {Name: "C", ValueFrom: &corev1.EnvVarSource{}}},
+			want: []string{"max-env-vars: container app: has 3 environment variables, more than the maximum of 2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{MaxEnvVars: 2}, nil)
+			pod := testPod(corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Env: tt.env}}})
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestCheckMaxVolumeMounts(t *testing.T) {
+	tests := []struct {
+		name   string
+		mounts []corev1.VolumeMount
+		want   []string
+	}{
+		{
+			name:   "at max",
+			mounts: []corev1.VolumeMount{{Name: "a"}, {Name: "b"}},
+		},
+		{
+			name:   "over max",
+			mounts: []corev1.VolumeMount{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+			want:   []string{"max-volume-mounts: container init: has 3 volumeMounts, more than the maximum of 2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{MaxVolumeMounts: 2}, nil)
+			pod := testPod(corev1.PodSpec{InitContainers: []corev1.Container{{Name: "init", VolumeMounts: tt.mounts}}})
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
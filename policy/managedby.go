@@ -0,0 +1,41 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// managedByLabel is the well-known label naming the tool that manages an
+// object.
+const managedByLabel = "app.kubernetes.io/managed-by"
+
+// breakGlassAnnotation exempts objects that set it to true from requiring a
+// managed-by label, for emergency changes made by hand.
+const breakGlassAnnotation = annotationPrefix + "break-glass"
+
+// checkManagedBy rejects objects of any resource whose managed-by label
+// isn't one of the allowed tools, such as objects created by hand rather
+// than by a GitOps tool, unless they're annotated as a break-glass change.
+func (e *Engine) checkManagedBy(ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+	accessor, err := apimeta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+	if accessor.GetAnnotations()[breakGlassAnnotation] == "true" {
+		return nil, nil
+	}
+
+	allowed := strings.Join(e.opts.AllowedManagedBy, ", ")
+	managedBy, ok := accessor.GetLabels()[managedByLabel]
+	switch {
+	case !ok:
+		return []Finding{{Message: fmt.Sprintf("missing required %s label: must be one of %s (or set the %s annotation to true)", managedByLabel, allowed, breakGlassAnnotation)}}, nil
+	case !contains(e.opts.AllowedManagedBy, managedBy):
+		return []Finding{{Message: fmt.Sprintf("%s label %s is not allowed: must be one of %s (or set the %s annotation to true)", managedByLabel, managedBy, allowed, breakGlassAnnotation)}}, nil
+	}
+	return nil, nil
+}
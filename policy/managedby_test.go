@@ -0,0 +1,44 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckManagedBy(t *testing.T) {
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		want        []string
+	}{
+		{
+			name:   "allowed",
+			labels: map[string]string{managedByLabel: "argocd"},
+		},
+		{
+			name:   "not allowed",
+			labels: map[string]string{managedByLabel: "kubectl"},
+			want:   []string{"managed-by: app.kubernetes.io/managed-by label kubectl is not allowed: must be one of argocd, flux (or set the webhook.trstringer.com/break-glass annotation to true)"},
+		},
+		{
+			name: "missing",
+			want: []string{"managed-by: missing required app.kubernetes.io/managed-by label: must be one of argocd, flux (or set the webhook.trstringer.com/break-glass annotation to true)"},
+		},
+		{
+			name:        "break glass",
+			annotations: map[string]string{breakGlassAnnotation: "true"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{AllowedManagedBy: []string{"argocd", "flux"}}, nil)
+			// Rules for every resource apply to services as well as pods.
+			service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Labels: tt.labels, Annotations: tt.annotations}}
+			assertFindings(t, e, service, RequestMeta{}, tt.want)
+		})
+	}
+}
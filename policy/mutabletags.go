@@ -0,0 +1,48 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DefaultMutableTagPattern matches image tags that are commonly moved to
+// newer images.
+const DefaultMutableTagPattern = `^(latest|main|master|dev|develop|stable)$`
+
+// checkMutableTags rejects images with a mutable tag, such as latest, in
+// namespaces with the production namespace label, since the image a pod
+// runs could then change underneath it. Images pinned by digest are never
+// mutable. Namespaces without the label aren't checked.
+func (e *Engine) checkMutableTags(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	for _, container := range allContainers(pod) {
+		ref, err := parseImageReference(container.Image)
+		if err != nil || strings.Contains(ref.reference, ":") {
+			// Invalid images are rejected by the API server, and digests
+			// contain a colon, e.g. sha256:...
+			continue
+		}
+		if e.mutableTagPattern.MatchString(ref.reference) {
+			findings = append(findings, Finding{
+				Container: container.Name,
+				Message:   fmt.Sprintf("image %s uses mutable tag %s, which isn't allowed in production namespace %s: pin a version or digest", container.Image, ref.reference, meta.Namespace),
+			})
+		}
+	}
+	if len(findings) == 0 {
+		return nil, nil
+	}
+
+	nsLabels, err := e.namespaceLabels(ctx, meta.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up namespace %s: %w", meta.Namespace, err)
+	}
+	value, ok := nsLabels[e.productionLabel.key]
+	if !ok || !e.productionLabel.matches(e.productionLabel.key, value) {
+		return nil, nil
+	}
+	return findings, nil
+}
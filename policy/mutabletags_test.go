@@ -0,0 +1,58 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCheckMutableTags(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		testNamespace("prod", map[string]string{"env": "production"}),
+		testNamespace("dev", nil),
+	)
+	tests := []struct {
+		name      string
+		namespace string
+		image     string
+		want      []string
+	}{
+		{
+			name:      "latest in production",
+			namespace: "prod",
+			image:     "nginx:latest",
+			want:      []string{"mutable-tags: container app: image nginx:latest uses mutable tag latest, which isn't allowed in production namespace prod: pin a version or digest"},
+		},
+		{
+			name:      "implicit latest in production",
+			namespace: "prod",
+			image:     "nginx",
+			want:      []string{"mutable-tags: container app: image nginx uses mutable tag latest, which isn't allowed in production namespace prod: pin a version or digest"},
+		},
+		{
+			name:      "version in production",
+			namespace: "prod",
+			image:     "nginx:1.21",
+		},
+		{
+			name:      "digest in production",
+			namespace: "prod",
+			image:     "nginx@sha256:0123456789abcdef",
+		},
+		{
+			name:      "latest elsewhere",
+			namespace: "dev",
+			image:     "nginx:latest",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{ProductionNamespaceLabel: "env=production", Client: client}, nil)
+			pod := testPod(corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: tt.image}}})
+			pod.Namespace = tt.namespace
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// namespaceLabels returns the labels of the namespace, using the lookup
+// cache.
+func (e *Engine) namespaceLabels(ctx context.Context, name string) (map[string]string, error) {
+	nsLabels, err := e.lookups.get(fmt.Sprintf("namespace/%s", name), func() (interface{}, error) {
+		namespace, err := e.opts.Client.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return namespace.Labels, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nsLabels.(map[string]string), nil
+}
@@ -0,0 +1,74 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// parseNamePattern parses the name pattern template. The template is
+// rendered with the object's labels, so that conventions can refer to them,
+// e.g. ^{{ .Labels.team }}- requires names to start with the team label.
+func parseNamePattern(pattern string) (*template.Template, error) {
+	return template.New("name-pattern").Option("missingkey=error").Parse(pattern)
+}
+
+// checkNameConvention rejects pods whose name doesn't match the configured
+// naming convention. Pods that only set generateName have the prefix
+// checked instead, as that is the only part of the name the user controls.
+func (e *Engine) checkNameConvention(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	name, field := pod.Name, "name"
+	if name == "" {
+		name, field = pod.GenerateName, "generateName"
+	}
+
+	// Label values are quoted so that they are matched literally when they
+	// are substituted into the pattern.
+	labels := map[string]string{}
+	for key, value := range pod.Labels {
+		labels[key] = regexp.QuoteMeta(value)
+	}
+
+	var pattern bytes.Buffer
+	if err := e.namePattern.Execute(&pattern, map[string]interface{}{"Labels": labels}); err != nil {
+		return []Finding{{Message: fmt.Sprintf("unable to apply naming convention %s: %v", e.opts.NamePattern, err)}}, nil
+	}
+
+	re, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return []Finding{{Message: fmt.Sprintf("naming convention %s is not a valid regex: %v", pattern.String(), err)}}, nil
+	}
+
+	if !re.MatchString(name) {
+		return []Finding{{Message: fmt.Sprintf("%s %q does not match naming convention %s", field, name, pattern.String())}}, nil
+	}
+	return nil, nil
+}
+
+// checkContainerNames rejects pods with more than one container, across init
+// containers and containers, with the same name, which the API server would
+// reject less clearly, and containers whose names don't match the container
+// naming convention, if there is one.
+func (e *Engine) checkContainerNames(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	seen := map[string]bool{}
+	for _, container := range allContainers(pod) {
+		if seen[container.Name] {
+			findings = append(findings, Finding{
+				Message: fmt.Sprintf("container name %s is used more than once", container.Name),
+			})
+		}
+		seen[container.Name] = true
+
+		if e.containerNamePattern != nil && !e.containerNamePattern.MatchString(container.Name) {
+			findings = append(findings, Finding{
+				Message: fmt.Sprintf("container name %q does not match naming convention %s", container.Name, e.containerNamePattern.String()),
+			})
+		}
+	}
+	return findings, nil
+}
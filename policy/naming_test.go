@@ -0,0 +1,96 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckNameConvention(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		meta    metav1.ObjectMeta
+		want    []string
+	}{
+		{
+			name:    "matches",
+			pattern: "^web-",
+			meta:    metav1.ObjectMeta{Name: "web-1"},
+		},
+		{
+			name:    "doesn't match",
+			pattern: "^web-",
+			meta:    metav1.ObjectMeta{Name: "api-1"},
+			want:    []string{`name-convention: name "api-1" does not match naming convention ^web-`},
+		},
+		{
+			name:    "generateName",
+			pattern: "^web-",
+			meta:    metav1.ObjectMeta{GenerateName: "api-"},
+			want:    []string{`name-convention: generateName "api-" does not match naming convention ^web-`},
+		},
+		{
+			name:    "label",
+			pattern: "^{{ .Labels.team }}-",
+			meta:    metav1.ObjectMeta{Name: "a.b-web", Labels: map[string]string{"team": "a.b"}},
+		},
+		{
+			name:    "label value is quoted",
+			pattern: "^{{ .Labels.team }}-",
+			meta:    metav1.ObjectMeta{Name: "axb-web", Labels: map[string]string{"team": "a.b"}},
+			want:    []string{`name-convention: name "axb-web" does not match naming convention ^a\.b-`},
+		},
+		{
+			name:    "missing label",
+			pattern: "^{{ .Labels.team }}-",
+			meta:    metav1.ObjectMeta{Name: "web"},
+			want:    []string{`name-convention: unable to apply naming convention ^{{ .Labels.team }}-: template: name-pattern:1:11: executing "name-pattern" at <.Labels.team>: map has no entry for key "team"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{NamePattern: tt.pattern}, nil)
+			pod := withMeta(&corev1.Pod{ObjectMeta: tt.meta}, map[string]string{"hello": "there"}, nil)
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestCheckContainerNames(t *testing.T) {
+	tests := []struct {
+		name       string
+		pattern    string
+		containers []string
+		want       []string
+	}{
+		{
+			name:       "unique",
+			containers: []string{"init", "app"},
+		},
+		{
+			name:       "duplicate",
+			containers: []string{"app", "app"},
+			want:       []string{"container-names: container name app is used more than once"},
+		},
+		{
+			name:       "pattern",
+			pattern:    "^[a-z]+$",
+			containers: []string{"init", "app-2"},
+			want:       []string{`container-names: container name "app-2" does not match naming convention ^[a-z]+$`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{ValidateContainerNames: true, ContainerNamePattern: tt.pattern}, nil)
+			pod := testPod(corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: tt.containers[0]}},
+				Containers:     []corev1.Container{{Name: tt.containers[1]}},
+			})
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// checkNetworkPolicyPermissive rejects network policies in restricted
+// namespaces that select every pod and then allow all ingress or egress
+// traffic, which is no restriction at all.
+func (e *Engine) checkNetworkPolicyPermissive(ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+	networkPolicy := obj.(*networkingv1.NetworkPolicy)
+	if !contains(e.opts.RestrictedNetworkNamespaces, meta.Namespace) {
+		return nil, nil
+	}
+	if len(networkPolicy.Spec.PodSelector.MatchLabels) > 0 || len(networkPolicy.Spec.PodSelector.MatchExpressions) > 0 {
+		return nil, nil
+	}
+
+	// Ingress is always a policy type when none are set, egress only is
+	// when there are egress rules.
+	ingress, egress := len(networkPolicy.Spec.PolicyTypes) == 0, len(networkPolicy.Spec.PolicyTypes) == 0 && len(networkPolicy.Spec.Egress) > 0
+	for _, policyType := range networkPolicy.Spec.PolicyTypes {
+		switch policyType {
+		case networkingv1.PolicyTypeIngress:
+			ingress = true
+		case networkingv1.PolicyTypeEgress:
+			egress = true
+		}
+	}
+
+	var findings []Finding
+	if ingress {
+		for _, ingressRule := range networkPolicy.Spec.Ingress {
+			if len(ingressRule.From) == 0 && len(ingressRule.Ports) == 0 {
+				findings = append(findings, Finding{
+					Message: fmt.Sprintf("network policy %s allows all ingress traffic to every pod in restricted namespace %s", networkPolicy.Name, meta.Namespace),
+				})
+				break
+			}
+		}
+	}
+	if egress {
+		for _, egressRule := range networkPolicy.Spec.Egress {
+			if len(egressRule.To) == 0 && len(egressRule.Ports) == 0 {
+				findings = append(findings, Finding{
+					Message: fmt.Sprintf("network policy %s allows all egress traffic from every pod in restricted namespace %s", networkPolicy.Name, meta.Namespace),
+				})
+				break
+			}
+		}
+	}
+	return findings, nil
+}
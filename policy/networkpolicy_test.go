@@ -0,0 +1,64 @@
+package policy
+
+import (
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckNetworkPolicyPermissive(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		spec      networkingv1.NetworkPolicySpec
+		want      []string
+	}{
+		{
+			name:      "allow all ingress",
+			namespace: "restricted",
+			spec:      networkingv1.NetworkPolicySpec{Ingress: []networkingv1.NetworkPolicyIngressRule{{}}},
+			want:      []string{"network-policy-permissive: network policy allow-all allows all ingress traffic to every pod in restricted namespace restricted"},
+		},
+		{
+			name:      "allow all egress",
+			namespace: "restricted",
+			spec: networkingv1.NetworkPolicySpec{
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+				Egress:      []networkingv1.NetworkPolicyEgressRule{{}},
+			},
+			want: []string{"network-policy-permissive: network policy allow-all allows all egress traffic from every pod in restricted namespace restricted"},
+		},
+		{
+			name:      "restricted ingress",
+			namespace: "restricted",
+			spec: networkingv1.NetworkPolicySpec{Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}}},
+			}}},
+		},
+		{
+			name:      "selects some pods",
+			namespace: "restricted",
+			spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				Ingress:     []networkingv1.NetworkPolicyIngressRule{{}},
+			},
+		},
+		{
+			name:      "unrestricted namespace",
+			namespace: "dev",
+			spec:      networkingv1.NetworkPolicySpec{Ingress: []networkingv1.NetworkPolicyIngressRule{{}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{RestrictedNetworkNamespaces: []string{"restricted"}}, nil)
+			networkPolicy := &networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "allow-all", Namespace: tt.namespace},
+				Spec:       tt.spec,
+			}
+			assertFindings(t, e, networkPolicy, RequestMeta{}, tt.want)
+		})
+	}
+}
@@ -0,0 +1,499 @@
+package policy
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Values for Options.FailurePolicy, which mirror the failurePolicy field of
+// the ValidatingWebhookConfiguration.
+const (
+	FailurePolicyFail   = "Fail"
+	FailurePolicyIgnore = "Ignore"
+)
+
+// Values for Options.DecisionDefault.
+const (
+	DecisionDefaultAllow = "allow"
+	DecisionDefaultDeny  = "deny"
+	DecisionDefaultWarn  = "allow-with-warning"
+)
+
+// Options configure an Engine. The zero value only enables the rules that
+// are enabled by default.
+type Options struct {
+	// ShortCircuit stops evaluation at the first rejection, so only that
+	// violation is reported, instead of reporting every violation.
+	ShortCircuit bool
+	// FailurePolicy is how to handle rules that can't be evaluated, such as
+	// when a lookup fails. It defaults to FailurePolicyFail.
+	FailurePolicy string
+	// DecisionDefault is the decision when every rule for an object fails
+	// to be evaluated, such as when the API server can't be reached, which
+	// overrides FailurePolicy for those errors. That includes
+	// FailurePolicyIgnore: rules it ignores still count as failed, so
+	// DecisionDefaultDeny rejects an object none of whose rules could be
+	// evaluated rather than allowing it. If only some rules fail, or it's
+	// empty, each error is handled by FailurePolicy.
+	DecisionDefault string
+	// PipelineTimeout bounds how long evaluating all of an object's rules
+	// combined may take, so that a slow rule can't make the webhook time out
+	// from the API server's perspective. Rules are stopped by cancelling
+	// their context. 0 is no bound.
+	PipelineTimeout time.Duration
+	// PipelineTimeoutDecision is the decision when PipelineTimeout is hit
+	// before every rule has been evaluated, one of the values for
+	// DecisionDefault. It defaults to DecisionDefaultDeny.
+	PipelineTimeoutDecision string
+	// DisabledRules are the names of rules to turn off, regardless of any
+	// other option or config.
+	DisabledRules []string
+	// SkipObjectLabels exempt objects carrying any of these labels, given as
+	// key=value, from every rule.
+	SkipObjectLabels []string
+	// MaxWarnings caps how many warnings are returned, after duplicates are
+	// removed, with a summary of how many more there were. 0 is no cap.
+	MaxWarnings int
+	// LookupCacheTTL is how long lookups of cluster objects are cached.
+	LookupCacheTTL time.Duration
+	// LookupCacheSize is the most lookups that are cached at once. It
+	// defaults to DefaultLookupCacheSize.
+	LookupCacheSize int
+	// Client is used by rules that look up other objects in the cluster. It
+	// is required if any of them are enabled.
+	Client kubernetes.Interface
+	// Logger is used to log evaluation errors. It defaults to the standard
+	// logger.
+	Logger *log.Logger
+	// ObserveRule, if set, is called with how long each rule took every time
+	// one is evaluated, e.g. to measure the cost of each rule.
+	ObserveRule func(rule string, elapsed time.Duration)
+	// Now returns the current time, for rules that depend on it such as
+	// freeze windows. It defaults to time.Now.
+	Now func() time.Time
+
+	// Rule options. Each of these enables or configures one of the bundled
+	// rules, and is documented by the webhook flag of the same name.
+	EnforceProbesTimeout               bool
+	ProbeInitialDelayFloor             int32
+	NamePattern                        string
+	MaxEmptyDirSize                    string
+	RequireEmptyDirSizeLimit           bool
+	ForbidPrivilegeEscalation          bool
+	RequireExplicitPrivilegeEscalation bool
+	RequireAppArmor                    bool
+	RequireReadOnlyRootFilesystem      bool
+	VerifyPullSecretsExist             bool
+	RequireRevisionHistoryLimit        bool
+	MaxRevisionHistoryLimit            int32
+	RestrictedNetworkNamespaces        []string
+	LimitRatio                         float64
+	TeamLabel                          string
+	TeamAllowlist                      *Allowlist
+	AntiAffinitySelector               string
+	VerifyEnvironment                  bool
+	EnvironmentLabel                   string
+	RejectMissingNamespaceEnvironment  bool
+	LoadBalancerAnnotations            []string
+	MaxEnvVars                         int
+	ForbidHostPID                      bool
+	ForbidHostIPC                      bool
+	RequireSecurityContext             bool
+	MinPVCStorage                      string
+	MaxPVCStorage                      string
+	RejectUnresolvedPlaceholders       bool
+	PlaceholderPattern                 string
+	MaxImageSize                       string
+	RegistryConfigFile                 string
+	AllowedSchedulers                  []string
+	AllowDefaultScheduler              bool
+	PublicHostPattern                  string
+	VerifyPriorityClassTier            bool
+	TierLabel                          string
+	RejectRootImages                   bool
+	ServiceSelectorLabels              []string
+	RequireJobTTL                      bool
+	FSGroupRanges                      []string
+	RunAsUserRanges                    []string
+	RunAsGroupRanges                   []string
+	ValidateContainerNames             bool
+	ContainerNamePattern               string
+	VerifyEnvRefsExist                 bool
+	StatefulLabel                      string
+	SpotNodeTaints                     []string
+	SpotNodeLabels                     []string
+	ValidateHPAReplicas                bool
+	MaxHPAReplicas                     int32
+	AllowHPAScaleToZero                bool
+	SensitiveDataLabel                 string
+	PluginDir                          string
+	ValidateDeploymentStrategy         bool
+	MaxUnavailable                     string
+	MaxObjectSize                      int64
+	InjectSidecar                      bool
+	WarnExpensiveLivenessExec          bool
+	ExpensiveExecPattern               string
+	QuotaEnforcedNamespaceLabel        string
+	QuotaBypassAnnotations             []string
+	AllowedManagedBy                   []string
+	ValidateProbePorts                 bool
+	FreezeNamespaceLabel               string
+	RequireProgressDeadline            bool
+	MaxProgressDeadlineSeconds         int32
+	MaxVolumeMounts                    int
+	ParallelStartupLabel               string
+	ProductionNamespaceLabel           string
+	MutableTagPattern                  string
+	WarnDeprecatedAnnotations          bool
+	RequireImagePullPolicy             bool
+	SanitizeMetadataKeys               []string
+	InjectionPattern                   string
+	NoExecNamespaceLabel               string
+	WarnOverlappingMounts              bool
+	ValidateHugePages                  bool
+	HugePagesNodeLabel                 string
+	WarnQOSMismatch                    bool
+	RestrictedNamespaceLabel           string
+	ImmutableFields                    []string
+	RequireEmptyDirStorageLimits       bool
+	AllowedIngressClasses              []string
+	AllowDefaultIngressClass           bool
+	GPUNamespaceLabel                  string
+	ConfigMapOwnerLabel                string
+	MaxTopologySpreadConstraints       int
+	SecretEnvPattern                   string
+}
+
+// RequiresClient reports whether any enabled rule needs Client.
+func (o *Options) RequiresClient() bool {
+	return o.VerifyPullSecretsExist || o.VerifyEnvironment || o.VerifyPriorityClassTier || o.VerifyEnvRefsExist || o.QuotaEnforcedNamespaceLabel != "" || o.FreezeNamespaceLabel != "" || o.ProductionNamespaceLabel != "" || o.NoExecNamespaceLabel != "" || o.RestrictedNamespaceLabel != "" || o.GPUNamespaceLabel != "" || o.ConfigMapOwnerLabel != ""
+}
+
+// complete checks the options that can't be validated by their type alone,
+// and parses them into the values used by the rules.
+func (e *Engine) complete() error {
+	switch e.opts.FailurePolicy {
+	case "":
+		e.opts.FailurePolicy = FailurePolicyFail
+	case FailurePolicyFail, FailurePolicyIgnore:
+	default:
+		return fmt.Errorf("invalid failure policy %s: must be %s or %s", e.opts.FailurePolicy, FailurePolicyFail, FailurePolicyIgnore)
+	}
+
+	switch e.opts.DecisionDefault {
+	case "", DecisionDefaultAllow, DecisionDefaultDeny, DecisionDefaultWarn:
+	default:
+		return fmt.Errorf("invalid decision default %s: must be %s, %s or %s", e.opts.DecisionDefault, DecisionDefaultAllow, DecisionDefaultDeny, DecisionDefaultWarn)
+	}
+
+	switch e.opts.PipelineTimeoutDecision {
+	case "":
+		e.opts.PipelineTimeoutDecision = DecisionDefaultDeny
+	case DecisionDefaultAllow, DecisionDefaultDeny, DecisionDefaultWarn:
+	default:
+		return fmt.Errorf("invalid pipeline timeout decision %s: must be %s, %s or %s", e.opts.PipelineTimeoutDecision, DecisionDefaultAllow, DecisionDefaultDeny, DecisionDefaultWarn)
+	}
+	if e.opts.PipelineTimeout < 0 {
+		return fmt.Errorf("invalid pipeline timeout %s: must not be negative", e.opts.PipelineTimeout)
+	}
+
+	if e.opts.Logger == nil {
+		e.opts.Logger = log.Default()
+	}
+
+	if e.opts.Now == nil {
+		e.opts.Now = time.Now
+	}
+
+	for _, name := range e.opts.DisabledRules {
+		if !IsKnownRule(name) {
+			return fmt.Errorf("cannot disable unknown rule %s", name)
+		}
+	}
+
+	skipLabels, err := parseSkipLabels(e.opts.SkipObjectLabels)
+	if err != nil {
+		return err
+	}
+	e.skipLabels = skipLabels
+
+	if e.opts.RequiresClient() && e.opts.Client == nil {
+		return fmt.Errorf("a kubernetes client is required by the enabled rules")
+	}
+
+	if e.opts.TeamAllowlist != nil && e.opts.TeamLabel == "" {
+		return fmt.Errorf("a team label is required with a team allowlist")
+	}
+
+	if e.opts.VerifyEnvironment && e.opts.EnvironmentLabel == "" {
+		return fmt.Errorf("an environment label is required to verify environments")
+	}
+
+	if e.opts.VerifyPriorityClassTier && e.opts.TierLabel == "" {
+		return fmt.Errorf("a tier label is required to verify priority class tiers")
+	}
+
+	if e.fsGroupRanges, err = parseIDRanges(e.opts.FSGroupRanges); err != nil {
+		return fmt.Errorf("invalid fsGroup ranges: %w", err)
+	}
+	if e.runAsUserRanges, err = parseIDRanges(e.opts.RunAsUserRanges); err != nil {
+		return fmt.Errorf("invalid runAsUser ranges: %w", err)
+	}
+	if e.runAsGroupRanges, err = parseIDRanges(e.opts.RunAsGroupRanges); err != nil {
+		return fmt.Errorf("invalid runAsGroup ranges: %w", err)
+	}
+
+	if e.opts.StatefulLabel != "" {
+		statefulLabels, err := parseKeyValues([]string{e.opts.StatefulLabel})
+		if err != nil {
+			return fmt.Errorf("invalid stateful label: %w", err)
+		}
+		e.statefulLabel = &statefulLabels[0]
+		if e.spotTaints, err = parseKeyValues(e.opts.SpotNodeTaints); err != nil {
+			return fmt.Errorf("invalid spot node taint: %w", err)
+		}
+		if e.spotNodeLabels, err = parseKeyValues(e.opts.SpotNodeLabels); err != nil {
+			return fmt.Errorf("invalid spot node label: %w", err)
+		}
+		if len(e.spotTaints) == 0 && len(e.spotNodeLabels) == 0 {
+			return fmt.Errorf("spot node taints or labels are required with a stateful label")
+		}
+	}
+
+	if e.opts.SensitiveDataLabel != "" {
+		sensitiveLabels, err := parseKeyValues([]string{e.opts.SensitiveDataLabel})
+		if err != nil {
+			return fmt.Errorf("invalid sensitive data label: %w", err)
+		}
+		e.sensitiveLabel = &sensitiveLabels[0]
+	}
+
+	if e.opts.MaxUnavailable != "" {
+		maxUnavailable := intstr.Parse(e.opts.MaxUnavailable)
+		if value, err := intstr.GetScaledValueFromIntOrPercent(&maxUnavailable, 100, false); err != nil {
+			return fmt.Errorf("invalid max unavailable: %w", err)
+		} else if value < 0 {
+			return fmt.Errorf("invalid max unavailable %s: must not be negative", e.opts.MaxUnavailable)
+		}
+		e.maxUnavailable = &maxUnavailable
+	}
+
+	if e.opts.InjectSidecar && e.sidecar == nil {
+		return fmt.Errorf("a sidecar is required in the config to inject sidecars")
+	}
+
+	if e.opts.QuotaEnforcedNamespaceLabel != "" {
+		quotaLabels, err := parseKeyValues([]string{e.opts.QuotaEnforcedNamespaceLabel})
+		if err != nil {
+			return fmt.Errorf("invalid quota-enforced namespace label: %w", err)
+		}
+		e.quotaNamespaceLabel = &quotaLabels[0]
+		annotations := e.opts.QuotaBypassAnnotations
+		if annotations == nil {
+			annotations = DefaultQuotaBypassAnnotations
+		}
+		if e.bypassAnnotations, err = parseKeyValues(annotations); err != nil {
+			return fmt.Errorf("invalid quota bypass annotation: %w", err)
+		}
+	}
+
+	if e.opts.FreezeNamespaceLabel != "" {
+		freezeLabels, err := parseKeyValues([]string{e.opts.FreezeNamespaceLabel})
+		if err != nil {
+			return fmt.Errorf("invalid freeze namespace label: %w", err)
+		}
+		e.freezeNamespaceLabel = &freezeLabels[0]
+	}
+	for _, window := range e.freezeWindows {
+		schedule, err := parseCronSchedule(window.Schedule)
+		if err != nil {
+			return fmt.Errorf("invalid freeze window %s: %w", window.Name, err)
+		}
+		e.freezeSchedules = append(e.freezeSchedules, schedule)
+	}
+
+	if e.opts.ProductionNamespaceLabel != "" {
+		productionLabels, err := parseKeyValues([]string{e.opts.ProductionNamespaceLabel})
+		if err != nil {
+			return fmt.Errorf("invalid production namespace label: %w", err)
+		}
+		e.productionLabel = &productionLabels[0]
+		pattern := e.opts.MutableTagPattern
+		if pattern == "" {
+			pattern = DefaultMutableTagPattern
+		}
+		if e.mutableTagPattern, err = regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid mutable tag pattern: %w", err)
+		}
+	}
+
+	if e.opts.NoExecNamespaceLabel != "" {
+		noExecLabels, err := parseKeyValues([]string{e.opts.NoExecNamespaceLabel})
+		if err != nil {
+			return fmt.Errorf("invalid no-exec namespace label: %w", err)
+		}
+		e.noExecNamespaceLabel = &noExecLabels[0]
+	}
+
+	if e.opts.HugePagesNodeLabel != "" {
+		hugePagesLabels, err := parseKeyValues([]string{e.opts.HugePagesNodeLabel})
+		if err != nil {
+			return fmt.Errorf("invalid huge pages node label: %w", err)
+		}
+		e.hugePagesNodeLabel = &hugePagesLabels[0]
+	}
+
+	if e.opts.RestrictedNamespaceLabel != "" {
+		restrictedLabels, err := parseKeyValues([]string{e.opts.RestrictedNamespaceLabel})
+		if err != nil {
+			return fmt.Errorf("invalid restricted namespace label: %w", err)
+		}
+		e.restrictedLabel = &restrictedLabels[0]
+	}
+
+	if e.opts.GPUNamespaceLabel != "" {
+		gpuLabels, err := parseKeyValues([]string{e.opts.GPUNamespaceLabel})
+		if err != nil {
+			return fmt.Errorf("invalid GPU namespace label: %w", err)
+		}
+		e.gpuNamespaceLabel = &gpuLabels[0]
+	}
+
+	for _, field := range e.opts.ImmutableFields {
+		path := strings.Split(field, ".")
+		if contains(path, "") {
+			return fmt.Errorf("invalid immutable field %q: must be a path such as spec.serviceAccountName", field)
+		}
+		e.immutableFields = append(e.immutableFields, path)
+	}
+
+	if e.opts.AntiAffinitySelector != "" {
+		selector, err := labels.Parse(e.opts.AntiAffinitySelector)
+		if err != nil {
+			return fmt.Errorf("invalid anti-affinity selector: %w", err)
+		}
+		e.antiAffinitySelector = selector
+	}
+
+	if e.opts.MaxEmptyDirSize != "" {
+		sizeLimit, err := resource.ParseQuantity(e.opts.MaxEmptyDirSize)
+		if err != nil {
+			return fmt.Errorf("invalid max emptyDir size: %w", err)
+		}
+		e.maxEmptyDirSize = &sizeLimit
+	}
+
+	if e.opts.MinPVCStorage != "" {
+		storage, err := resource.ParseQuantity(e.opts.MinPVCStorage)
+		if err != nil {
+			return fmt.Errorf("invalid min PVC storage: %w", err)
+		}
+		e.minPVCStorage = &storage
+	}
+
+	if e.opts.MaxPVCStorage != "" {
+		storage, err := resource.ParseQuantity(e.opts.MaxPVCStorage)
+		if err != nil {
+			return fmt.Errorf("invalid max PVC storage: %w", err)
+		}
+		e.maxPVCStorage = &storage
+	}
+
+	if e.minPVCStorage != nil && e.maxPVCStorage != nil && e.minPVCStorage.Cmp(*e.maxPVCStorage) > 0 {
+		return fmt.Errorf("min PVC storage %s is more than max PVC storage %s", e.minPVCStorage.String(), e.maxPVCStorage.String())
+	}
+
+	if e.opts.MaxImageSize != "" {
+		size, err := resource.ParseQuantity(e.opts.MaxImageSize)
+		if err != nil {
+			return fmt.Errorf("invalid max image size: %w", err)
+		}
+		e.maxImageSize = &size
+	}
+
+	if e.maxImageSize != nil || e.opts.RejectRootImages {
+		registry, err := newRegistryClient(e.opts.RegistryConfigFile)
+		if err != nil {
+			return err
+		}
+		e.registry = registry
+	}
+
+	if e.opts.RejectUnresolvedPlaceholders {
+		pattern := e.opts.PlaceholderPattern
+		if pattern == "" {
+			pattern = DefaultPlaceholderPattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid placeholder pattern: %w", err)
+		}
+		e.placeholderPattern = re
+	}
+
+	if e.opts.WarnExpensiveLivenessExec {
+		pattern := e.opts.ExpensiveExecPattern
+		if pattern == "" {
+			pattern = DefaultExpensiveExecPattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid expensive exec pattern: %w", err)
+		}
+		e.expensiveExecPattern = re
+	}
+
+	if len(e.opts.SanitizeMetadataKeys) > 0 {
+		pattern := e.opts.InjectionPattern
+		if pattern == "" {
+			pattern = DefaultInjectionPattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid injection pattern: %w", err)
+		}
+		e.injectionPattern = re
+	}
+
+	if e.opts.SecretEnvPattern != "" {
+		re, err := regexp.Compile(e.opts.SecretEnvPattern)
+		if err != nil {
+			return fmt.Errorf("invalid secret env pattern: %w", err)
+		}
+		e.secretEnvPattern = re
+	}
+
+	if e.opts.PublicHostPattern != "" {
+		re, err := regexp.Compile(e.opts.PublicHostPattern)
+		if err != nil {
+			return fmt.Errorf("invalid public host pattern: %w", err)
+		}
+		e.publicHostPattern = re
+	}
+
+	if e.opts.ContainerNamePattern != "" {
+		re, err := regexp.Compile(e.opts.ContainerNamePattern)
+		if err != nil {
+			return fmt.Errorf("invalid container name pattern: %w", err)
+		}
+		e.containerNamePattern = re
+	}
+
+	if e.opts.NamePattern != "" {
+		tmpl, err := parseNamePattern(e.opts.NamePattern)
+		if err != nil {
+			return fmt.Errorf("invalid name pattern: %w", err)
+		}
+		e.namePattern = tmpl
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// checkConfigMapOwners warns about pods that use config maps, in env or
+// volumes, whose owner label doesn't match the pod's, so that ownership of
+// a workload and its configuration stays consistent. Pods without the label
+// aren't checked. A required config map that doesn't exist is an error, so
+// that it's handled by the failure policy.
+func (e *Engine) checkConfigMapOwners(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	owner, ok := pod.Labels[e.opts.ConfigMapOwnerLabel]
+	if !ok {
+		return nil, nil
+	}
+
+	var findings []Finding
+	for _, ref := range configMapRefs(pod) {
+		configMapLabels, err := e.configMapLabels(ctx, meta.Namespace, ref.name)
+		if err != nil {
+			return nil, fmt.Errorf("error looking up config map %s: %w", ref.name, err)
+		}
+		if configMapLabels == nil {
+			if ref.optional {
+				continue
+			}
+			return nil, fmt.Errorf("config map %s does not exist in namespace %s", ref.name, meta.Namespace)
+		}
+
+		configMapOwner, ok := configMapLabels[e.opts.ConfigMapOwnerLabel]
+		switch {
+		case !ok:
+			findings = append(findings, Finding{
+				Message: fmt.Sprintf("config map %s has no %s label, but the pod's is %s", ref.name, e.opts.ConfigMapOwnerLabel, owner),
+				Warning: true,
+			})
+		case configMapOwner != owner:
+			findings = append(findings, Finding{
+				Message: fmt.Sprintf("config map %s has %s label %s, but the pod's is %s", ref.name, e.opts.ConfigMapOwnerLabel, configMapOwner, owner),
+				Warning: true,
+			})
+		}
+	}
+	return findings, nil
+}
+
+// configMapRef is a reference from a pod to a config map.
+type configMapRef struct {
+	name string
+	// optional is set if every reference to the config map is optional.
+	optional bool
+}
+
+// configMapRefs returns the config maps the pod uses in env, envFrom and
+// volumes, in the order they're first referenced.
+func configMapRefs(pod *corev1.Pod) []configMapRef {
+	var refs []configMapRef
+	seen := map[string]int{}
+	add := func(name string, optional *bool) {
+		isOptional := optional != nil && *optional
+		if i, ok := seen[name]; ok {
+			refs[i].optional = refs[i].optional && isOptional
+			return
+		}
+		seen[name] = len(refs)
+		refs = append(refs, configMapRef{name: name, optional: isOptional})
+	}
+
+	for _, container := range allContainers(pod) {
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.ConfigMapKeyRef != nil {
+				add(env.ValueFrom.ConfigMapKeyRef.Name, env.ValueFrom.ConfigMapKeyRef.Optional)
+			}
+		}
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.ConfigMapRef != nil {
+				add(envFrom.ConfigMapRef.Name, envFrom.ConfigMapRef.Optional)
+			}
+		}
+	}
+	for _, volume := range pod.Spec.Volumes {
+		if volume.ConfigMap != nil {
+			add(volume.ConfigMap.Name, volume.ConfigMap.Optional)
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.ConfigMap != nil {
+					add(source.ConfigMap.Name, source.ConfigMap.Optional)
+				}
+			}
+		}
+	}
+	return refs
+}
+
+// configMapLabels returns the labels of the config map, or nil if it doesn't
+// exist, using the lookup cache.
+func (e *Engine) configMapLabels(ctx context.Context, namespace, name string) (map[string]string, error) {
+	configMapLabels, err := e.lookups.get(fmt.Sprintf("configmap-labels/%s/%s", namespace, name), func() (interface{}, error) {
+		configMap, err := e.opts.Client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return map[string]string(nil), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Config maps without labels still exist, so they must not be nil.
+		if configMap.Labels == nil {
+			return map[string]string{}, nil
+		}
+		return configMap.Labels, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return configMapLabels.(map[string]string), nil
+}
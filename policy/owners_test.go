@@ -0,0 +1,75 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCheckConfigMapOwners(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "payments-config", Namespace: "default", Labels: map[string]string{"owner": "payments"}}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "search-config", Namespace: "default", Labels: map[string]string{"owner": "search"}}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "shared-config", Namespace: "default"}},
+	)
+	envFrom := func(name string, optional *bool) []corev1.EnvFromSource {
+		return []corev1.EnvFromSource{{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Optional: optional}}}
+	}
+	volume := func(name string) []corev1.Volume {
+		return []corev1.Volume{{Name: "config", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: name}}}}}
+	}
+	owned := map[string]string{"owner": "payments"}
+	tests := []struct {
+		name    string
+		labels  map[string]string
+		envFrom []corev1.EnvFromSource
+		volumes []corev1.Volume
+		want    []string
+	}{
+		{
+			name:    "same owner",
+			labels:  owned,
+			envFrom: envFrom("payments-config", nil),
+		},
+		{
+			name:    "different owner",
+			labels:  owned,
+			volumes: volume("search-config"),
+			want:    []string{"configmap-owners: warning: config map search-config has owner label search, but the pod's is payments"},
+		},
+		{
+			name:    "no owner",
+			labels:  owned,
+			envFrom: envFrom("shared-config", nil),
+			want:    []string{"configmap-owners: warning: config map shared-config has no owner label, but the pod's is payments"},
+		},
+		{
+			name:    "pod without owner",
+			envFrom: envFrom("search-config", nil),
+		},
+		{
+			name:    "optional and missing",
+			labels:  owned,
+			envFrom: envFrom("missing", boolPtr(true)),
+		},
+		{
+			name:    "required and missing",
+			labels:  owned,
+			envFrom: envFrom("missing", nil),
+			want:    []string{"configmap-owners: unable to evaluate rule configmap-owners: config map missing does not exist in namespace default"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{ConfigMapOwnerLabel: "owner", Client: client}, nil)
+			pod := withMeta(testPod(corev1.PodSpec{
+				Volumes:    tt.volumes,
+				Containers: []corev1.Container{{Name: "app", EnvFrom: tt.envFrom}},
+			}), tt.labels, nil)
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DefaultPlaceholderPattern matches Helm and Go template actions such as
+// {{ .Values.x }}, and shell style ${VAR} references, which Kubernetes
+// doesn't expand. Kubernetes' own $(VAR) references are not matched.
+const DefaultPlaceholderPattern = `\{\{.*?\}\}|\$\{[^}]*\}`
+
+// checkUnresolvedPlaceholders rejects containers whose command, args or env
+// values contain templating placeholders that were never rendered, which
+// usually means a Helm or Kustomize rendering mistake.
+func (e *Engine) checkUnresolvedPlaceholders(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	report := func(container, field, value string) {
+		if placeholder := e.placeholderPattern.FindString(value); placeholder != "" {
+			findings = append(findings, Finding{
+				Container: container,
+				Message:   fmt.Sprintf("%s contains unresolved placeholder %s", field, placeholder),
+			})
+		}
+	}
+
+	for _, container := range allContainers(pod) {
+		for i, arg := range container.Command {
+			report(container.Name, fmt.Sprintf("command[%d]", i), arg)
+		}
+		for i, arg := range container.Args {
+			report(container.Name, fmt.Sprintf("args[%d]", i), arg)
+		}
+		for _, env := range container.Env {
+			report(container.Name, fmt.Sprintf("env %s", env.Name), env.Value)
+		}
+	}
+	return findings, nil
+}
@@ -0,0 +1,44 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCheckUnresolvedPlaceholders(t *testing.T) {
+	tests := []struct {
+		name      string
+		container corev1.Container
+		want      []string
+	}{
+		{
+			name:      "rendered",
+			container: corev1.Container{Name: "app", Command: []string{"run"}, Args: []string{"--port=8080"}, Env: []corev1.EnvVar{{Name: "HOST", Value: "db"}}},
+		},
+		{
+			name:      "kubernetes reference",
+			container: corev1.Container{Name: "app", Args: []string{"--host=$(HOST)"}},
+		},
+		{
+			name:      "helm",
+			container: corev1.Container{Name: "app", Args: []string{"--port={{ .Values.port }}"}},
+			want:      []string{"unresolved-placeholders: container app: args[0] contains unresolved placeholder {{ .Values.port }}"},
+		},
+		{
+			name:      "shell",
+			container: corev1.Container{Name: "app", Command: []string{"run", "${CONFIG}"}, Env: []corev1.EnvVar{{Name: "HOST", Value: "${DB_HOST}"}}},
+			want: []string{
+				"unresolved-placeholders: container app: command[1] contains unresolved placeholder ${CONFIG}",
+				"unresolved-placeholders: container app: env HOST contains unresolved placeholder ${DB_HOST}",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{RejectUnresolvedPlaceholders: true}, nil)
+			assertFindings(t, e, testPod(corev1.PodSpec{Containers: []corev1.Container{tt.container}}), RequestMeta{}, tt.want)
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"plugin"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// PluginValidateFunc is the func that validator plugins export as Validate.
+// It's called with the resource of the object, such as v1/pods or
+// apps/v1/deployments, and the object as JSON, and returns why the object is
+// rejected, or an empty message to allow it. An error is handled per the
+// failure policy.
+type PluginValidateFunc = func(resource string, object []byte) (string, error)
+
+// pluginSymbol is the name of the func that validator plugins export.
+const pluginSymbol = "Validate"
+
+// pluginRulePrefix is the prefix of the names of plugins' rules, which are
+// named plugin:<file name>, such as plugin:require-label for
+// require-label.so or require-label.wasm.
+const pluginRulePrefix = "plugin:"
+
+// pluginFunc validates an object like PluginValidateFunc, for plugins of
+// any kind. It returns ctx's error once ctx is done.
+type pluginFunc func(ctx context.Context, resource string, object []byte) (string, error)
+
+// loadPlugins loads every Go plugin, .so file, and WebAssembly plugin,
+// .wasm file, in dir as a rule named plugin:<file name>, which is evaluated
+// against objects of every resource. The rules can be configured and
+// disabled like bundled ones, and disabled plugins aren't loaded at all.
+// A .so and a .wasm file with the same name would have the same rule, so
+// neither of them is loaded.
+// What a plugin's decision depends on isn't known, so its rule is dynamic.
+// Plugins that can't be loaded are skipped with the ignore failure policy,
+// and fail the engine otherwise.
+func (e *Engine) loadPlugins(dir string) ([]rule, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading plugin dir: %w", err)
+	}
+
+	disabled := map[string]bool{}
+	for _, name := range e.opts.DisabledRules {
+		disabled[name] = true
+	}
+
+	plugins := map[string]int{}
+	for _, file := range files {
+		if name, ok := pluginRuleName(file); ok {
+			plugins[name]++
+		}
+	}
+
+	var pluginRules []rule
+	for _, file := range files {
+		name, ok := pluginRuleName(file)
+		if !ok {
+			continue
+		}
+
+		path := filepath.Join(dir, file.Name())
+		if disabled[name] {
+			e.opts.Logger.Printf("rule %s disabled, not loading plugin %s", name, path)
+			continue
+		}
+		var validate pluginFunc
+		if plugins[name] > 1 {
+			err = fmt.Errorf("error loading plugin %s: another plugin is also named %s", path, name)
+		} else {
+			validate, err = openPluginFunc(path)
+		}
+		if err != nil {
+			if e.opts.FailurePolicy == FailurePolicyIgnore {
+				e.opts.Logger.Printf("skipping plugin %s: %v", path, err)
+				continue
+			}
+			return nil, err
+		}
+
+		e.opts.Logger.Printf("loaded plugin %s as rule %s", path, name)
+		pluginRules = append(pluginRules, rule{
+			name:           name,
+			enforcePercent: 100,
+			resource:       anyResource,
+			dynamic:        true,
+			check:          pluginCheck(validate),
+		})
+	}
+	return pluginRules, nil
+}
+
+// pluginRuleName returns the name of the rule for the file, if it's a
+// plugin.
+func pluginRuleName(file os.FileInfo) (string, bool) {
+	ext := filepath.Ext(file.Name())
+	if file.IsDir() || (ext != ".so" && ext != ".wasm") {
+		return "", false
+	}
+	return pluginRulePrefix + strings.TrimSuffix(file.Name(), ext), true
+}
+
+// openPluginFunc opens the plugin at path, which is a WebAssembly plugin if
+// it's a .wasm file and a Go plugin otherwise.
+func openPluginFunc(path string) (pluginFunc, error) {
+	if filepath.Ext(path) == ".wasm" {
+		p, err := openWASMPlugin(path)
+		if err != nil {
+			return nil, err
+		}
+		return p.validate, nil
+	}
+	validate, err := openPlugin(path)
+	if err != nil {
+		return nil, err
+	}
+	return goPluginFunc(validate), nil
+}
+
+// openPlugin opens the plugin at path and looks up its Validate func.
+func openPlugin(path string) (PluginValidateFunc, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening plugin %s: %w", path, err)
+	}
+	symbol, err := p.Lookup(pluginSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("error loading plugin %s: %w", path, err)
+	}
+	validate, ok := symbol.(PluginValidateFunc)
+	if !ok {
+		return nil, fmt.Errorf("error loading plugin %s: %s is %T, not func(string, []byte) (string, error)", path, pluginSymbol, symbol)
+	}
+	return validate, nil
+}
+
+// goPluginFunc adapts a Go plugin's Validate func to a pluginFunc. Panics
+// in the plugin are returned as errors, so that a broken plugin is handled
+// per the failure policy instead of crashing the webhook. Validate doesn't
+// take a context, so it can't be stopped: it runs in its own goroutine, and
+// the func returns once ctx is done without waiting for it. That leaves at
+// most one goroutine running per evaluation that timed out in the plugin,
+// until the plugin returns.
+func goPluginFunc(validate PluginValidateFunc) pluginFunc {
+	return func(ctx context.Context, resource string, object []byte) (string, error) {
+		type result struct {
+			msg string
+			err error
+		}
+		done := make(chan result, 1)
+		go func() {
+			// A panic here would take down the whole webhook, rather than
+			// just this request, since it's outside the handler's goroutine.
+			defer func() {
+				if r := recover(); r != nil {
+					done <- result{err: fmt.Errorf("plugin panicked: %v", r)}
+				}
+			}()
+			msg, err := validate(resource, object)
+			done <- result{msg, err}
+		}()
+
+		select {
+		case res := <-done:
+			return res.msg, res.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+// pluginCheck adapts a plugin so that it can be used as a rule's check.
+func pluginCheck(validate pluginFunc) func(e *Engine, ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+	return func(e *Engine, ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding object: %w", err)
+		}
+		msg, err := validate(ctx, pluginResource(meta.Resource), data)
+		if err != nil {
+			return nil, err
+		}
+		if msg == "" {
+			return nil, nil
+		}
+		return []Finding{{Message: msg}}, nil
+	}
+}
+
+// pluginResource formats the resource as it's passed to plugins, such as
+// v1/pods or apps/v1/deployments.
+func pluginResource(resource metav1.GroupVersionResource) string {
+	if resource.Group == "" {
+		return resource.Version + "/" + resource.Resource
+	}
+	return resource.Group + "/" + resource.Version + "/" + resource.Resource
+}
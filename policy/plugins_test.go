@@ -0,0 +1,211 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// pluginExts are the extensions of the kinds of plugins.
+var pluginExts = []string{".so", ".wasm"}
+
+// buildTestPlugin builds the test plugin of the kind with the extension into
+// a new plugin dir as require-label.so or require-label.wasm, skipping the
+// test if plugins of that kind can't be built here. Go plugins are built from
+// testdata/plugins/requirelabel, and WebAssembly plugins from
+// testdata/plugins/requirelabelwasm.
+func buildTestPlugin(t *testing.T, ext string) string {
+	t.Helper()
+	dir := t.TempDir()
+	out := filepath.Join(dir, "require-label"+ext)
+	var cmd *exec.Cmd
+	switch ext {
+	case ".so":
+		// Go plugins can only be opened by binaries built with the same
+		// flags, which the race detector changes.
+		args := []string{"build", "-buildmode=plugin"}
+		if raceEnabled {
+			args = append(args, "-race")
+		}
+		cmd = exec.Command("go", append(args, "-o", out, "./testdata/plugins/requirelabel")...)
+	case ".wasm":
+		cmd = exec.Command("go", "build", "-buildmode=c-shared", "-o", out, "./testdata/plugins/requirelabelwasm")
+		cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	default:
+		t.Fatalf("unknown plugin extension %s", ext)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("unable to build plugin: %v: %s", err, out)
+	}
+	return dir
+}
+
+func TestPlugins(t *testing.T) {
+	labeled := testPod(corev1.PodSpec{})
+	labeled.Labels["app"] = "web"
+	unlabeled := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+
+	tests := []struct {
+		name string
+		opts Options
+		cfg  *Config
+		pod  *corev1.Pod
+		want []string
+	}{
+		{
+			name: "allowed",
+			pod:  labeled,
+		},
+		{
+			name: "rejected after bundled rules",
+			pod:  unlabeled,
+			want: []string{"hello-label: missing required hello label", "plugin:require-label: missing required app label"},
+		},
+		{
+			name: "sorted by priority",
+			cfg:  &Config{Rules: []RuleConfig{{Name: "plugin:require-label", Priority: 10}}},
+			pod:  unlabeled,
+			want: []string{"plugin:require-label: missing required app label", "hello-label: missing required hello label"},
+		},
+		{
+			name: "warn action",
+			cfg:  &Config{Rules: []RuleConfig{{Name: "plugin:require-label", Action: ActionWarn}}},
+			pod:  unlabeled,
+			want: []string{"hello-label: missing required hello label", "plugin:require-label: warning: missing required app label"},
+		},
+		{
+			name: "disabled",
+			opts: Options{DisabledRules: []string{"plugin:require-label"}},
+			pod:  unlabeled,
+			want: []string{"hello-label: missing required hello label"},
+		},
+	}
+
+	for _, ext := range pluginExts {
+		t.Run(ext, func(t *testing.T) {
+			dir := buildTestPlugin(t, ext)
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					tt.opts.PluginDir = dir
+					e := newTestEngine(t, tt.opts, tt.cfg)
+					assertFindings(t, e, tt.pod, RequestMeta{}, tt.want)
+				})
+			}
+		})
+	}
+}
+
+func TestBrokenPlugins(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		opts    Options
+		wantErr string
+	}{
+		{
+			name:    "fail",
+			files:   []string{"broken.so"},
+			wantErr: "error opening plugin",
+		},
+		{
+			name:  "ignore",
+			files: []string{"broken.so"},
+			opts:  Options{FailurePolicy: FailurePolicyIgnore},
+		},
+		{
+			name:  "disabled",
+			files: []string{"broken.so"},
+			opts:  Options{DisabledRules: []string{"plugin:broken"}},
+		},
+		{
+			name:    "wasm fail",
+			files:   []string{"broken.wasm"},
+			wantErr: "error loading plugin",
+		},
+		{
+			name:  "wasm ignore",
+			files: []string{"broken.wasm"},
+			opts:  Options{FailurePolicy: FailurePolicyIgnore},
+		},
+		{
+			name:    "same name",
+			files:   []string{"broken.so", "broken.wasm"},
+			wantErr: "another plugin is also named plugin:broken",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, file := range tt.files {
+				if err := ioutil.WriteFile(filepath.Join(dir, file), []byte("not a plugin"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			tt.opts.PluginDir = dir
+			tt.opts.Logger = log.New(io.Discard, "", 0)
+			_, err := NewEngine(tt.opts, nil)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("NewEngine() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("NewEngine() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPluginResource(t *testing.T) {
+	if got := pluginResource(podResource); got != "v1/pods" {
+		t.Errorf("pluginResource(pods) = %q, want v1/pods", got)
+	}
+	if got := pluginResource(deploymentResource); got != "apps/v1/deployments" {
+		t.Errorf("pluginResource(deployments) = %q, want apps/v1/deployments", got)
+	}
+}
+
+func TestPluginCheckTimeout(t *testing.T) {
+	// The plugin can't be stopped, so it's only released once the test
+	// ends.
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	check := pluginCheck(goPluginFunc(func(resource string, object []byte) (string, error) {
+		<-release
+		return "", nil
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := check(nil, ctx, testPod(corev1.PodSpec{}), RequestMeta{Resource: podResource}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("check() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestWASMPluginCanceled(t *testing.T) {
+	dir := buildTestPlugin(t, ".wasm")
+	p, err := openWASMPlugin(filepath.Join(dir, "require-label.wasm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.validate(ctx, "v1/pods", []byte("{}")); !errors.Is(err, context.Canceled) {
+		t.Errorf("validate() error = %v, want %v", err, context.Canceled)
+	}
+	// The instance the canceled call used is replaced.
+	if msg, err := p.validate(context.Background(), "v1/pods", []byte("{}")); msg != "missing required app label" || err != nil {
+		t.Errorf("validate() = %q, %v, want missing required app label", msg, err)
+	}
+}
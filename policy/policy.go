@@ -0,0 +1,241 @@
+// Package policy is the policy engine used by the validating webhook. It
+// doesn't depend on admission or HTTP types, so the exact same policy can be
+// evaluated by other programs such as CI tools or operators:
+//
+//	engine, err := policy.NewEngine(policy.Options{EnforceProbesTimeout: true}, nil)
+//	if err != nil {
+//		return err
+//	}
+//
+//	decision := engine.Evaluate(ctx, pod, policy.RequestMeta{})
+//	if !decision.Allowed {
+//		fmt.Println(decision.Message)
+//	}
+//
+// The package-level Evaluate uses the engine set with SetDefault, which is
+// how the webhook server evaluates objects.
+package policy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// RequestMeta describes the request an object is being evaluated for.
+type RequestMeta struct {
+	// Resource is the resource of the object. If it is empty, it is
+	// determined from the type of the object.
+	Resource metav1.GroupVersionResource
+	// Namespace is the namespace the object is being created in. If it is
+	// empty, the object's own namespace is used.
+	Namespace string
+	// ObjectSize is the size in bytes of the object as it was sent, such as
+	// the raw object of an admission request. If it is 0, the size of the
+	// object encoded as JSON is used.
+	ObjectSize int
+	// Username and Groups identify who made the request. They are empty
+	// when the object isn't being evaluated for a request, such as in CI.
+	Username string
+	Groups   []string
+	// OldObject is the existing object that is being updated. It is nil
+	// for objects that are being created.
+	OldObject runtime.Object
+	// Operation is the operation of the request, such as OperationUpdate.
+	// It is empty when the object isn't being evaluated for a request,
+	// which is treated like OperationCreate.
+	Operation string
+}
+
+// Values for RequestMeta.Operation, which mirror the operations of
+// admission requests.
+const (
+	OperationCreate = "CREATE"
+	OperationUpdate = "UPDATE"
+)
+
+// Finding is a single problem a rule found with an object. Findings that are
+// warnings are returned to the user but do not cause the object to be
+// rejected. Findings about a specific container carry its name so that users
+// can tell which container needs fixing in multi-container pods.
+type Finding struct {
+	Rule      string `json:"rule"`
+	Container string `json:"container,omitempty"`
+	Message   string `json:"message"`
+	Warning   bool   `json:"warning,omitempty"`
+	DocURL    string `json:"docURL,omitempty"`
+	// Reason, if set, is the reason for rejecting the object, returned as
+	// the reason of the admission response's status.
+	Reason string `json:"reason,omitempty"`
+	// Remediation, if set, is how to fix the object, in place of the one
+	// documented for the rule.
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// String returns the message for the finding, prefixed with the container
+// it applies to if there is one.
+func (f Finding) String() string {
+	msg := f.Message
+	if f.Container != "" {
+		msg = fmt.Sprintf("container %s: %s", f.Container, msg)
+	}
+	if f.Remediation != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, f.Remediation)
+	}
+	if f.DocURL != "" {
+		msg = fmt.Sprintf("%s (see %s)", msg, f.DocURL)
+	}
+	return msg
+}
+
+// Decision is the outcome of evaluating an object.
+type Decision struct {
+	// Allowed is false if any finding is not a warning.
+	Allowed bool `json:"allowed"`
+	// Message combines every violation that caused the object to be rejected.
+	Message string `json:"message,omitempty"`
+	// Reason is the reason of the first violation that has one, if any.
+	Reason string `json:"reason,omitempty"`
+	// Warnings are the warnings to return to the user, without duplicates.
+	Warnings []string `json:"warnings,omitempty"`
+	// Findings are all of the findings, in the order they were found.
+	Findings []Finding `json:"findings,omitempty"`
+	// TimedOut is true if evaluation hit the pipeline timeout, so the
+	// decision is the pipeline timeout decision rather than the outcome of
+	// every rule.
+	TimedOut bool `json:"timedOut,omitempty"`
+	// Cacheable is true if an identical request would get the same
+	// decision for as long as the engine's rules stay the same. It is false
+	// if a rule couldn't be evaluated, if a dynamic rule, such as one that
+	// looks up other objects in the cluster, was evaluated, or if
+	// evaluation timed out.
+	Cacheable bool `json:"-"`
+}
+
+var defaultEngine atomic.Value
+
+func init() {
+	engine, err := NewEngine(Options{}, nil)
+	if err != nil {
+		panic(err)
+	}
+	SetDefault(engine)
+}
+
+// SetDefault makes engine the one used by the package-level Evaluate. The
+// swap is atomic, so evaluations always use one complete ruleset: those
+// already running finish with the engine they started with.
+func SetDefault(engine *Engine) {
+	defaultEngine.Store(engine)
+}
+
+// Evaluate evaluates the object with the engine set by SetDefault. Until
+// SetDefault is called, only the rules that are enabled by default are used.
+func Evaluate(ctx context.Context, obj runtime.Object, meta RequestMeta) Decision {
+	return defaultEngine.Load().(*Engine).Evaluate(ctx, obj, meta)
+}
+
+// Mutate returns the JSON patch for the object from the engine set by
+// SetDefault.
+func Mutate(ctx context.Context, obj runtime.Object, meta RequestMeta) []PatchOperation {
+	return defaultEngine.Load().(*Engine).Mutate(ctx, obj, meta)
+}
+
+// Evaluate runs every rule for the object's resource against it and decides
+// whether it is allowed.
+func (e *Engine) Evaluate(ctx context.Context, obj runtime.Object, meta RequestMeta) Decision {
+	if meta.Resource == (metav1.GroupVersionResource{}) {
+		meta.Resource = resourceFor(obj)
+	}
+	if meta.Namespace == "" {
+		if accessor, err := apimeta.Accessor(obj); err == nil {
+			meta.Namespace = accessor.GetNamespace()
+		}
+	}
+
+	// Objects carrying a skip label are exempt from every rule.
+	if e.skipped(obj) {
+		return Decision{Allowed: true, Cacheable: true}
+	}
+
+	if e.opts.PipelineTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.opts.PipelineTimeout)
+		defer cancel()
+	}
+
+	findings, cacheable := e.evaluate(ctx, obj, meta)
+	decision := Decide(findings, e.opts.MaxWarnings)
+	decision.TimedOut = e.opts.PipelineTimeout > 0 && ctx.Err() != nil
+	decision.Cacheable = cacheable && !decision.TimedOut
+	return decision
+}
+
+// Decide makes the decision for the findings, returning at most maxWarnings
+// warnings as described by capWarnings. It is used to decide again once
+// findings from elsewhere, such as another webhook, are added to those of an
+// evaluation, so that the warnings of both are deduplicated and capped
+// together.
+func Decide(findings []Finding, maxWarnings int) Decision {
+	decision := decide(findings)
+	decision.Warnings = capWarnings(decision.Warnings, maxWarnings)
+	return decision
+}
+
+// decide rejects the object if any finding is not a warning, and passes all
+// warnings back to the user. Identical warnings, such as the same problem
+// reported by more than one rule, are only returned once.
+func decide(findings []Finding) Decision {
+	decision := Decision{Allowed: true, Findings: findings}
+
+	var messages []string
+	seenWarnings := map[string]bool{}
+	for _, f := range findings {
+		if f.Warning {
+			warning := f.String()
+			if !seenWarnings[warning] {
+				seenWarnings[warning] = true
+				decision.Warnings = append(decision.Warnings, warning)
+			}
+			continue
+		}
+		messages = append(messages, f.String())
+		if decision.Reason == "" {
+			decision.Reason = f.Reason
+		}
+	}
+
+	if len(messages) > 0 {
+		decision.Allowed = false
+		decision.Message = strings.Join(messages, "; ")
+	}
+
+	return decision
+}
+
+// capWarnings returns at most max of the warnings, replacing the rest with
+// a summary of how many were left out, so that responses don't clutter
+// kubectl output. max of 0 means no cap.
+func capWarnings(warnings []string, max int) []string {
+	if max <= 0 || len(warnings) <= max {
+		return warnings
+	}
+	capped := append([]string{}, warnings[:max]...)
+	return append(capped, fmt.Sprintf("%d more warnings suppressed", len(warnings)-max))
+}
+
+// resourceFor returns the resource for objects of obj's type.
+func resourceFor(obj runtime.Object) metav1.GroupVersionResource {
+	for resource, newObject := range resourceTypes {
+		if reflect.TypeOf(newObject()) == reflect.TypeOf(obj) {
+			return resource
+		}
+	}
+	return metav1.GroupVersionResource{}
+}
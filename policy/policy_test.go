@@ -0,0 +1,237 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// testPod returns a pod in the default namespace with spec as its spec. It
+// has the hello label, so it passes the rules that are enabled by default.
+func testPod(spec corev1.PodSpec) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "default",
+			Labels:    map[string]string{"hello": "there"},
+		},
+		Spec: spec,
+	}
+}
+
+// withMeta returns the pod with the labels and annotations added to its
+// own.
+func withMeta(pod *corev1.Pod, podLabels, annotations map[string]string) *corev1.Pod {
+	if len(podLabels) > 0 && pod.Labels == nil {
+		pod.Labels = map[string]string{}
+	}
+	for k, v := range podLabels {
+		pod.Labels[k] = v
+	}
+	if len(annotations) > 0 && pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	for k, v := range annotations {
+		pod.Annotations[k] = v
+	}
+	return pod
+}
+
+// testNamespace returns a namespace with the labels, for the fake clients
+// of rules that look up namespaces.
+func testNamespace(name string, nsLabels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: nsLabels}}
+}
+
+// newTestEngine creates an engine from opts and cfg, failing the test if
+// they're invalid. What the engine logs is discarded.
+func newTestEngine(t *testing.T, opts Options, cfg *Config) *Engine {
+	t.Helper()
+	if opts.Logger == nil {
+		opts.Logger = log.New(io.Discard, "", 0)
+	}
+	e, err := NewEngine(opts, cfg)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	return e
+}
+
+// findingStrings renders findings as their rule and message, with warnings
+// marked, so that tests can compare them.
+func findingStrings(findings []Finding) []string {
+	var rendered []string
+	for _, f := range findings {
+		if f.Warning {
+			rendered = append(rendered, fmt.Sprintf("%s: warning: %s", f.Rule, f.String()))
+			continue
+		}
+		rendered = append(rendered, fmt.Sprintf("%s: %s", f.Rule, f.String()))
+	}
+	return rendered
+}
+
+// assertFindings fails the test unless evaluating obj with e finds exactly
+// want, as rendered by findingStrings.
+func assertFindings(t *testing.T, e *Engine, obj runtime.Object, meta RequestMeta, want []string) {
+	t.Helper()
+	decision := e.Evaluate(context.Background(), obj, meta)
+	if got := findingStrings(decision.Findings); !reflect.DeepEqual(got, want) {
+		t.Errorf("findings = %q, want %q", got, want)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         Options
+		meta         RequestMeta
+		pod          *corev1.Pod
+		wantAllowed  bool
+		wantMessage  string
+		wantWarnings []string
+	}{
+		{
+			name:        "allowed",
+			pod:         testPod(corev1.PodSpec{}),
+			wantAllowed: true,
+		},
+		{
+			name:        "rejected",
+			pod:         &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+			wantMessage: "missing required hello label",
+		},
+		{
+			name:         "warning",
+			pod:          withMeta(testPod(corev1.PodSpec{}), map[string]string{"hello": "world"}, nil),
+			wantAllowed:  true,
+			wantWarnings: []string{"world will be deprecated for hello in the future"},
+		},
+		{
+			name:        "skip label",
+			opts:        Options{SkipObjectLabels: []string{"validation=skip"}},
+			pod:         &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test", Labels: map[string]string{"validation": "skip"}}},
+			wantAllowed: true,
+		},
+		{
+			name:        "skip label with another value",
+			opts:        Options{SkipObjectLabels: []string{"validation=skip"}},
+			pod:         &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test", Labels: map[string]string{"validation": "on"}}},
+			wantMessage: "missing required hello label",
+		},
+		{
+			name: "every violation",
+			opts: Options{ForbidHostPID: true, ForbidHostIPC: true},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Spec:       corev1.PodSpec{HostPID: true, HostIPC: true},
+			},
+			wantMessage: "missing required hello label; must not use the host PID namespace (hostPID); must not use the host IPC namespace (hostIPC)",
+		},
+		{
+			name: "short circuit",
+			opts: Options{ShortCircuit: true, ForbidHostPID: true, ForbidHostIPC: true},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Spec:       corev1.PodSpec{HostPID: true, HostIPC: true},
+			},
+			wantMessage: "missing required hello label",
+		},
+		{
+			name: "update",
+			opts: Options{ForbidHostPID: true, ImmutableFields: []string{"spec.serviceAccountName"}},
+			meta: RequestMeta{Operation: OperationUpdate, OldObject: &corev1.Pod{Spec: corev1.PodSpec{ServiceAccountName: "web"}}},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Spec:       corev1.PodSpec{ServiceAccountName: "admin", HostPID: true},
+			},
+			wantMessage: `field spec.serviceAccountName is immutable, but it was changed from "web" to "admin"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, tt.opts, nil)
+			decision := e.Evaluate(context.Background(), tt.pod, tt.meta)
+			if decision.Allowed != tt.wantAllowed {
+				t.Errorf("Allowed = %t, want %t", decision.Allowed, tt.wantAllowed)
+			}
+			if decision.Message != tt.wantMessage {
+				t.Errorf("Message = %q, want %q", decision.Message, tt.wantMessage)
+			}
+			if !reflect.DeepEqual(decision.Warnings, tt.wantWarnings) {
+				t.Errorf("Warnings = %q, want %q", decision.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestDecide(t *testing.T) {
+	findings := []Finding{
+		{Rule: "a", Message: "first"},
+		{Rule: "b", Message: "same", Warning: true},
+		{Rule: "c", Container: "app", Message: "second"},
+		{Rule: "d", Message: "same", Warning: true},
+	}
+	decision := decide(findings)
+	if decision.Allowed {
+		t.Error("Allowed = true, want false")
+	}
+	if want := "first; container app: second"; decision.Message != want {
+		t.Errorf("Message = %q, want %q", decision.Message, want)
+	}
+	if want := []string{"same"}; !reflect.DeepEqual(decision.Warnings, want) {
+		t.Errorf("Warnings = %q, want %q", decision.Warnings, want)
+	}
+	if !reflect.DeepEqual(decision.Findings, findings) {
+		t.Errorf("Findings = %v, want %v", decision.Findings, findings)
+	}
+}
+
+func TestDecideCapsWarnings(t *testing.T) {
+	findings := []Finding{
+		{Rule: "a", Message: "first", Warning: true},
+		{Rule: "b", Message: "first", Warning: true},
+		{Rule: "c", Message: "second", Warning: true},
+		{Rule: "d", Message: "third", Warning: true},
+	}
+	decision := Decide(findings, 2)
+	if want := []string{"first", "second", "1 more warnings suppressed"}; !reflect.DeepEqual(decision.Warnings, want) {
+		t.Errorf("Warnings = %q, want %q", decision.Warnings, want)
+	}
+}
+
+func TestCapWarnings(t *testing.T) {
+	tests := []struct {
+		name     string
+		warnings []string
+		max      int
+		want     []string
+	}{
+		{name: "no cap", warnings: []string{"a", "b", "c"}, want: []string{"a", "b", "c"}},
+		{name: "under cap", warnings: []string{"a", "b"}, max: 2, want: []string{"a", "b"}},
+		{name: "over cap", warnings: []string{"a", "b", "c", "d"}, max: 2, want: []string{"a", "b", "2 more warnings suppressed"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := capWarnings(tt.warnings, tt.max); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("capWarnings() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindingString(t *testing.T) {
+	f := Finding{Container: "app", Message: "bad", DocURL: "https://docs.example.com/bad"}
+	if want := "container app: bad (see https://docs.example.com/bad)"; f.String() != want {
+		t.Errorf("String() = %q, want %q", f.String(), want)
+	}
+}
@@ -0,0 +1,55 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PriorityClassTiers restricts a priority class to pods in namespaces of
+// the given tiers, e.g. so that only system namespaces can use
+// system-cluster-critical.
+type PriorityClassTiers struct {
+	// PriorityClass is the name of the priority class.
+	PriorityClass string `json:"priorityClass"`
+	// Tiers are the namespace tiers whose pods may use the priority class.
+	Tiers []string `json:"tiers"`
+}
+
+// checkPriorityClassTier rejects pods that use a restricted priority class
+// in a namespace whose tier label isn't one of the tiers allowed to use it.
+// Priority classes that aren't restricted by config can be used anywhere.
+func (e *Engine) checkPriorityClassTier(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	class := pod.Spec.PriorityClassName
+	if class == "" {
+		return nil, nil
+	}
+
+	var tiers []string
+	restricted := false
+	for _, pct := range e.priorityClassTiers {
+		if pct.PriorityClass == class {
+			tiers, restricted = pct.Tiers, true
+			break
+		}
+	}
+	if !restricted {
+		return nil, nil
+	}
+
+	nsLabels, err := e.namespaceLabels(ctx, meta.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up namespace %s: %w", meta.Namespace, err)
+	}
+
+	tier, ok := nsLabels[e.opts.TierLabel]
+	if !ok {
+		return []Finding{{Message: fmt.Sprintf("priority class %s is restricted to namespaces with %s %s, and namespace %s has no %s label", class, e.opts.TierLabel, strings.Join(tiers, ", "), meta.Namespace, e.opts.TierLabel)}}, nil
+	}
+	if !contains(tiers, tier) {
+		return []Finding{{Message: fmt.Sprintf("priority class %s is restricted to namespaces with %s %s, but namespace %s is %s %s", class, e.opts.TierLabel, strings.Join(tiers, ", "), meta.Namespace, e.opts.TierLabel, tier)}}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,59 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCheckPriorityClassTier(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		testNamespace("kube-system", map[string]string{"tier": "system"}),
+		testNamespace("apps", map[string]string{"tier": "apps"}),
+		testNamespace("unlabeled", nil),
+	)
+	cfg := &Config{PriorityClassTiers: []PriorityClassTiers{{PriorityClass: "system-cluster-critical", Tiers: []string{"system"}}}}
+	tests := []struct {
+		name          string
+		namespace     string
+		priorityClass string
+		want          []string
+	}{
+		{
+			name:          "allowed tier",
+			namespace:     "kube-system",
+			priorityClass: "system-cluster-critical",
+		},
+		{
+			name:          "other tier",
+			namespace:     "apps",
+			priorityClass: "system-cluster-critical",
+			want:          []string{"priority-class-tier: priority class system-cluster-critical is restricted to namespaces with tier system, but namespace apps is tier apps"},
+		},
+		{
+			name:          "no tier",
+			namespace:     "unlabeled",
+			priorityClass: "system-cluster-critical",
+			want:          []string{"priority-class-tier: priority class system-cluster-critical is restricted to namespaces with tier system, and namespace unlabeled has no tier label"},
+		},
+		{
+			name:          "unrestricted class",
+			namespace:     "apps",
+			priorityClass: "high",
+		},
+		{
+			name:      "no class",
+			namespace: "apps",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{VerifyPriorityClassTier: true, TierLabel: "tier", Client: client}, cfg)
+			pod := testPod(corev1.PodSpec{PriorityClassName: tt.priorityClass})
+			pod.Namespace = tt.namespace
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
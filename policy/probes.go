@@ -0,0 +1,144 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Defaults the API server applies to probes that don't set these fields.
+const (
+	defaultProbeTimeoutSeconds int32 = 1
+	defaultProbePeriodSeconds  int32 = 10
+)
+
+// checkProbeTimings rejects probes that are allowed to run for as long as
+// (or longer than) the period between them, which causes probes to overlap,
+// and probes that start before the configured initial delay floor.
+func (e *Engine) checkProbeTimings(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	for _, container := range pod.Spec.Containers {
+		probes := []struct {
+			name  string
+			probe *corev1.Probe
+		}{
+			{"livenessProbe", container.LivenessProbe},
+			{"readinessProbe", container.ReadinessProbe},
+			{"startupProbe", container.StartupProbe},
+		}
+
+		for _, p := range probes {
+			if p.probe == nil {
+				continue
+			}
+
+			timeout := p.probe.TimeoutSeconds
+			if timeout == 0 {
+				timeout = defaultProbeTimeoutSeconds
+			}
+			period := p.probe.PeriodSeconds
+			if period == 0 {
+				period = defaultProbePeriodSeconds
+			}
+
+			if timeout >= period {
+				findings = append(findings, Finding{
+					Container: container.Name,
+					Message:   fmt.Sprintf("%s timeoutSeconds (%d) must be less than periodSeconds (%d)", p.name, timeout, period),
+				})
+			}
+			if p.probe.InitialDelaySeconds < e.opts.ProbeInitialDelayFloor {
+				findings = append(findings, Finding{
+					Container: container.Name,
+					Message:   fmt.Sprintf("%s initialDelaySeconds (%d) must be at least %d", p.name, p.probe.InitialDelaySeconds, e.opts.ProbeInitialDelayFloor),
+				})
+			}
+		}
+	}
+	return findings, nil
+}
+
+// DefaultExpensiveExecPattern matches exec probe commands that start a
+// heavyweight process, such as an HTTP client, an interpreter or a script.
+const DefaultExpensiveExecPattern = `\b(curl|wget|python[0-9.]*|node|java|ruby|perl|php)\b|\.(sh|py|rb|pl)\b`
+
+// checkExpensiveLivenessExec warns about liveness probes that exec an
+// expensive command. Exec probes fork a process in the container every
+// period, and when that process is slow under load the probe fails and the
+// container is restarted, which makes the load worse.
+func (e *Engine) checkExpensiveLivenessExec(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	for _, container := range allContainers(pod) {
+		probe := container.LivenessProbe
+		if probe == nil || probe.Exec == nil {
+			continue
+		}
+		command := strings.Join(probe.Exec.Command, " ")
+		if match := e.expensiveExecPattern.FindString(command); match != "" {
+			findings = append(findings, Finding{
+				Container: container.Name,
+				Message:   fmt.Sprintf("livenessProbe exec command %q looks expensive (matched %q) and can cause restart storms under load; consider an httpGet or tcpSocket probe", command, match),
+				Warning:   true,
+			})
+		}
+	}
+	return findings, nil
+}
+
+// checkProbePorts rejects httpGet and tcpSocket probes whose port, by
+// number or name, isn't one of the container's declared ports, which is
+// usually a typo or a port that was changed in one place but not the other.
+func (e *Engine) checkProbePorts(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	for _, container := range pod.Spec.Containers {
+		probes := []struct {
+			name  string
+			probe *corev1.Probe
+		}{
+			{"livenessProbe", container.LivenessProbe},
+			{"readinessProbe", container.ReadinessProbe},
+			{"startupProbe", container.StartupProbe},
+		}
+
+		for _, p := range probes {
+			if p.probe == nil {
+				continue
+			}
+
+			var port intstr.IntOrString
+			switch {
+			case p.probe.HTTPGet != nil:
+				port = p.probe.HTTPGet.Port
+			case p.probe.TCPSocket != nil:
+				port = p.probe.TCPSocket.Port
+			default:
+				continue
+			}
+
+			if !declaresPort(container, port) {
+				findings = append(findings, Finding{
+					Container: container.Name,
+					Message:   fmt.Sprintf("%s port %s is not declared in the container's ports", p.name, port.String()),
+				})
+			}
+		}
+	}
+	return findings, nil
+}
+
+// declaresPort reports whether the port, by number or name, is one of the
+// container's declared ports.
+func declaresPort(container corev1.Container, port intstr.IntOrString) bool {
+	for _, declared := range container.Ports {
+		if port.Type == intstr.String && declared.Name == port.StrVal {
+			return true
+		}
+		if port.Type == intstr.Int && declared.ContainerPort == port.IntVal {
+			return true
+		}
+	}
+	return false
+}
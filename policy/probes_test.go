@@ -0,0 +1,134 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestCheckProbeTimings(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  Options
+		probe *corev1.Probe
+		want  []string
+	}{
+		{
+			name:  "timeout less than period",
+			probe: &corev1.Probe{TimeoutSeconds: 2, PeriodSeconds: 5},
+		},
+		{
+			name:  "defaults",
+			probe: &corev1.Probe{},
+		},
+		{
+			name:  "timeout equal to period",
+			probe: &corev1.Probe{TimeoutSeconds: 5, PeriodSeconds: 5},
+			want:  []string{"probe-timings: container app: livenessProbe timeoutSeconds (5) must be less than periodSeconds (5)"},
+		},
+		{
+			name:  "timeout more than default period",
+			probe: &corev1.Probe{TimeoutSeconds: 15},
+			want:  []string{"probe-timings: container app: livenessProbe timeoutSeconds (15) must be less than periodSeconds (10)"},
+		},
+		{
+			name:  "initial delay under floor",
+			opts:  Options{ProbeInitialDelayFloor: 10},
+			probe: &corev1.Probe{InitialDelaySeconds: 5},
+			want:  []string{"probe-timings: container app: livenessProbe initialDelaySeconds (5) must be at least 10"},
+		},
+		{
+			name:  "initial delay at floor",
+			opts:  Options{ProbeInitialDelayFloor: 10},
+			probe: &corev1.Probe{InitialDelaySeconds: 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.EnforceProbesTimeout = true
+			e := newTestEngine(t, tt.opts, nil)
+			pod := testPod(corev1.PodSpec{Containers: []corev1.Container{{Name: "app", LivenessProbe: tt.probe}}})
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestCheckExpensiveLivenessExec(t *testing.T) {
+	tests := []struct {
+		name    string
+		command []string
+		want    []string
+	}{
+		{
+			name:    "cheap command",
+			command: []string{"cat", "/tmp/healthy"},
+		},
+		{
+			name:    "http client",
+			command: []string{"curl", "-f", "http://localhost/healthz"},
+			want:    []string{`expensive-liveness-exec: warning: container app: livenessProbe exec command "curl -f http://localhost/healthz" looks expensive (matched "curl") and can cause restart storms under load; consider an httpGet or tcpSocket probe`},
+		},
+		{
+			name:    "script",
+			command: []string{"/bin/check.sh"},
+			want:    []string{`expensive-liveness-exec: warning: container app: livenessProbe exec command "/bin/check.sh" looks expensive (matched ".sh") and can cause restart storms under load; consider an httpGet or tcpSocket probe`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{WarnExpensiveLivenessExec: true}, nil)
+			pod := testPod(corev1.PodSpec{Containers: []corev1.Container{{
+				Name:          "app",
+				LivenessProbe: &corev1.Probe{Handler: corev1.Handler{Exec: &corev1.ExecAction{Command: tt.command}}},
+			}}})
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestCheckProbePorts(t *testing.T) {
+	ports := []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}}
+	tests := []struct {
+		name    string
+		handler corev1.Handler
+		want    []string
+	}{
+		{
+			name:    "declared port number",
+			handler: corev1.Handler{HTTPGet: &corev1.HTTPGetAction{Port: intstr.FromInt(8080)}},
+		},
+		{
+			name:    "declared port name",
+			handler: corev1.Handler{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromString("http")}},
+		},
+		{
+			name:    "undeclared port number",
+			handler: corev1.Handler{HTTPGet: &corev1.HTTPGetAction{Port: intstr.FromInt(9090)}},
+			want:    []string{"probe-ports: container app: readinessProbe port 9090 is not declared in the container's ports"},
+		},
+		{
+			name:    "undeclared port name",
+			handler: corev1.Handler{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromString("metrics")}},
+			want:    []string{"probe-ports: container app: readinessProbe port metrics is not declared in the container's ports"},
+		},
+		{
+			name:    "exec probe",
+			handler: corev1.Handler{Exec: &corev1.ExecAction{Command: []string{"true"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{ValidateProbePorts: true}, nil)
+			pod := testPod(corev1.PodSpec{Containers: []corev1.Container{{
+				Name:           "app",
+				Ports:          ports,
+				ReadinessProbe: &corev1.Probe{Handler: tt.handler},
+			}}})
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
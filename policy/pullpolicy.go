@@ -0,0 +1,24 @@
+package policy
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// checkImagePullPolicySet rejects containers that leave imagePullPolicy
+// unset, whose pull policy then depends on the image's tag. The API server
+// sets the default before admission, so this only catches pods that are
+// evaluated outside of the webhook, such as by bench, /preview or in CI.
+func (e *Engine) checkImagePullPolicySet(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	for _, container := range allContainers(pod) {
+		if container.ImagePullPolicy == "" {
+			findings = append(findings, Finding{
+				Container: container.Name,
+				Message:   "imagePullPolicy must be set explicitly",
+			})
+		}
+	}
+	return findings, nil
+}
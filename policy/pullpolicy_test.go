@@ -0,0 +1,16 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCheckImagePullPolicySet(t *testing.T) {
+	e := newTestEngine(t, Options{RequireImagePullPolicy: true}, nil)
+	pod := testPod(corev1.PodSpec{Containers: []corev1.Container{
+		{Name: "set", Image: "app:v1", ImagePullPolicy: corev1.PullIfNotPresent},
+		{Name: "unset", Image: "app:v1"},
+	}})
+	assertFindings(t, e, pod, RequestMeta{}, []string{"image-pull-policy: container unset: imagePullPolicy must be set explicitly"})
+}
@@ -0,0 +1,43 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// checkPullSecretsExist rejects pods that reference imagePullSecrets that
+// don't exist in the pod's namespace, which would otherwise only surface
+// later as an ImagePullBackOff.
+func (e *Engine) checkPullSecretsExist(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	for _, ref := range pod.Spec.ImagePullSecrets {
+		exists, err := e.secretExists(ctx, meta.Namespace, ref.Name)
+		if err != nil {
+			return nil, fmt.Errorf("error looking up image pull secret %s: %w", ref.Name, err)
+		}
+		if !exists {
+			findings = append(findings, Finding{
+				Message: fmt.Sprintf("image pull secret %s does not exist in namespace %s", ref.Name, meta.Namespace),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// secretExists reports whether the secret exists, using the lookup cache.
+// Only secrets that exist are cached, as not found errors aren't, so that
+// pods are admitted as soon as their missing secrets are created.
+func (e *Engine) secretExists(ctx context.Context, namespace, name string) (bool, error) {
+	_, err := e.lookups.get(fmt.Sprintf("secret/%s/%s", namespace, name), func() (interface{}, error) {
+		_, err := e.opts.Client.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		return true, err
+	})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
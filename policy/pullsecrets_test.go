@@ -0,0 +1,70 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestCheckPullSecretsExist(t *testing.T) {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "default"}}
+	tests := []struct {
+		name    string
+		secrets []string
+		getErr  error
+		want    []string
+	}{
+		{
+			name:    "exists",
+			secrets: []string{"registry"},
+		},
+		{
+			name:    "doesn't exist",
+			secrets: []string{"registry", "other"},
+			want:    []string{"pull-secrets-exist: image pull secret other does not exist in namespace default"},
+		},
+		{
+			name:    "lookup fails",
+			secrets: []string{"registry"},
+			getErr:  errors.New("connection refused"),
+			want:    []string{"pull-secrets-exist: unable to evaluate rule pull-secrets-exist: error looking up image pull secret registry: connection refused"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(secret)
+			if tt.getErr != nil {
+				client.PrependReactor("get", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, tt.getErr
+				})
+			}
+			e := newTestEngine(t, Options{VerifyPullSecretsExist: true, Client: client}, nil)
+			var refs []corev1.LocalObjectReference
+			for _, name := range tt.secrets {
+				refs = append(refs, corev1.LocalObjectReference{Name: name})
+			}
+			assertFindings(t, e, testPod(corev1.PodSpec{ImagePullSecrets: refs}), RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestCheckPullSecretsExistAfterCreation(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	e := newTestEngine(t, Options{VerifyPullSecretsExist: true, Client: client, LookupCacheTTL: time.Hour}, nil)
+	pod := testPod(corev1.PodSpec{ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}}})
+	assertFindings(t, e, pod, RequestMeta{}, []string{"pull-secrets-exist: image pull secret registry does not exist in namespace default"})
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "default"}}
+	if _, err := client.CoreV1().Secrets("default").Create(context.Background(), secret, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	assertFindings(t, e, pod, RequestMeta{}, nil)
+}
@@ -0,0 +1,28 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// checkPVCStorageBounds rejects persistent volume claims that request less
+// storage than the configured minimum, or more than the configured maximum,
+// to prevent both under- and over-provisioning.
+func (e *Engine) checkPVCStorageBounds(ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+	pvc := obj.(*corev1.PersistentVolumeClaim)
+	requested, ok := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	if !ok {
+		return []Finding{{Message: "persistent volume claim must set spec.resources.requests.storage"}}, nil
+	}
+
+	switch {
+	case e.minPVCStorage != nil && requested.Cmp(*e.minPVCStorage) < 0:
+		return []Finding{{Message: fmt.Sprintf("persistent volume claim requests %s of storage, less than the minimum of %s", requested.String(), e.minPVCStorage.String())}}, nil
+	case e.maxPVCStorage != nil && requested.Cmp(*e.maxPVCStorage) > 0:
+		return []Finding{{Message: fmt.Sprintf("persistent volume claim requests %s of storage, more than the maximum of %s", requested.String(), e.maxPVCStorage.String())}}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,52 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestCheckPVCStorageBounds(t *testing.T) {
+	tests := []struct {
+		name    string
+		storage string
+		want    []string
+	}{
+		{
+			name:    "within bounds",
+			storage: "10Gi",
+		},
+		{
+			name:    "under min",
+			storage: "500Mi",
+			want:    []string{"pvc-storage-bounds: persistent volume claim requests 500Mi of storage, less than the minimum of 1Gi"},
+		},
+		{
+			name:    "over max",
+			storage: "1Ti",
+			want:    []string{"pvc-storage-bounds: persistent volume claim requests 1Ti of storage, more than the maximum of 100Gi"},
+		},
+		{
+			name: "unset",
+			want: []string{"pvc-storage-bounds: persistent volume claim must set spec.resources.requests.storage"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{MinPVCStorage: "1Gi", MaxPVCStorage: "100Gi"}, nil)
+			pvc := &corev1.PersistentVolumeClaim{}
+			if tt.storage != "" {
+				pvc.Spec.Resources.Requests = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(tt.storage)}
+			}
+			assertFindings(t, e, pvc, RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestPVCStorageBoundsOrder(t *testing.T) {
+	if _, err := NewEngine(Options{MinPVCStorage: "10Gi", MaxPVCStorage: "1Gi"}, nil); err == nil {
+		t.Error("NewEngine() with min PVC storage over max succeeded, want error")
+	}
+}
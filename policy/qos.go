@@ -0,0 +1,113 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// qosClassAnnotation declares the QoS class a pod is meant to get, such as
+// Guaranteed for latency-sensitive workloads.
+const qosClassAnnotation = annotationPrefix + "qos-class"
+
+// checkQOSClass warns about pods whose QoS class isn't the one declared by
+// their QoS class annotation. For pods that want Guaranteed QoS, each
+// container preventing it is reported.
+func (e *Engine) checkQOSClass(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	declared, ok := pod.Annotations[qosClassAnnotation]
+	if !ok {
+		return nil, nil
+	}
+
+	switch corev1.PodQOSClass(declared) {
+	case corev1.PodQOSGuaranteed, corev1.PodQOSBurstable, corev1.PodQOSBestEffort:
+	default:
+		return []Finding{{
+			Message: fmt.Sprintf("annotation %s has unknown QoS class %q: must be %s, %s or %s", qosClassAnnotation, declared, corev1.PodQOSGuaranteed, corev1.PodQOSBurstable, corev1.PodQOSBestEffort),
+			Warning: true,
+		}}, nil
+	}
+
+	qosClass, notGuaranteed := podQOSClass(pod)
+	if string(qosClass) == declared {
+		return nil, nil
+	}
+
+	if corev1.PodQOSClass(declared) != corev1.PodQOSGuaranteed {
+		return []Finding{{
+			Message: fmt.Sprintf("declares %s QoS in annotation %s, but its QoS class is %s", declared, qosClassAnnotation, qosClass),
+			Warning: true,
+		}}, nil
+	}
+
+	var findings []Finding
+	for _, name := range notGuaranteed {
+		findings = append(findings, Finding{
+			Container: name,
+			Message:   fmt.Sprintf("prevents the Guaranteed QoS declared in annotation %s, which needs CPU and memory limits with requests equal to them, so the pod is %s", qosClassAnnotation, qosClass),
+			Warning:   true,
+		})
+	}
+	return findings, nil
+}
+
+// podQOSClass returns the QoS class Kubernetes gives the pod, which only
+// depends on its CPU and memory, and the containers that keep it from being
+// Guaranteed. Requests that aren't set default to the limits.
+func podQOSClass(pod *corev1.Pod) (corev1.PodQOSClass, []string) {
+	var notGuaranteed []string
+	bestEffort := true
+	for _, container := range allContainers(pod) {
+		guaranteed := true
+		for _, resourceName := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			request, hasRequest := container.Resources.Requests[resourceName]
+			limit, hasLimit := container.Resources.Limits[resourceName]
+			if (hasRequest && !request.IsZero()) || (hasLimit && !limit.IsZero()) {
+				bestEffort = false
+			}
+			if !hasLimit || (hasRequest && request.Cmp(limit) != 0) {
+				guaranteed = false
+			}
+		}
+		if !guaranteed {
+			notGuaranteed = append(notGuaranteed, container.Name)
+		}
+	}
+
+	switch {
+	case bestEffort:
+		return corev1.PodQOSBestEffort, notGuaranteed
+	case len(notGuaranteed) == 0:
+		return corev1.PodQOSGuaranteed, nil
+	}
+	return corev1.PodQOSBurstable, notGuaranteed
+}
+
+// checkGPUNamespaceQOS rejects pods that wouldn't get Guaranteed QoS in
+// namespaces with the GPU namespace label, where scheduling onto scarce GPU
+// nodes needs to be predictable. Each container preventing it is reported.
+func (e *Engine) checkGPUNamespaceQOS(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	qosClass, notGuaranteed := podQOSClass(pod)
+	if qosClass == corev1.PodQOSGuaranteed {
+		return nil, nil
+	}
+
+	nsLabels, err := e.namespaceLabels(ctx, meta.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up namespace %s: %w", meta.Namespace, err)
+	}
+	value, ok := nsLabels[e.gpuNamespaceLabel.key]
+	if !ok || !e.gpuNamespaceLabel.matches(e.gpuNamespaceLabel.key, value) {
+		return nil, nil
+	}
+
+	var findings []Finding
+	for _, name := range notGuaranteed {
+		findings = append(findings, Finding{
+			Container: name,
+			Message:   fmt.Sprintf("must set CPU and memory limits with requests equal to them for Guaranteed QoS in GPU namespace %s (%s)", meta.Namespace, e.gpuNamespaceLabel),
+		})
+	}
+	return findings, nil
+}
@@ -0,0 +1,114 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// guaranteedResources are CPU and memory limits, with requests defaulting to
+// them, which give a container Guaranteed QoS.
+var guaranteedResources = corev1.ResourceRequirements{Limits: corev1.ResourceList{
+	corev1.ResourceCPU:    resource.MustParse("1"),
+	corev1.ResourceMemory: resource.MustParse("1Gi"),
+}}
+
+// burstableResources are requests without limits, which give a container
+// Burstable QoS.
+var burstableResources = corev1.ResourceRequirements{Requests: corev1.ResourceList{
+	corev1.ResourceCPU: resource.MustParse("100m"),
+}}
+
+func TestCheckQOSClass(t *testing.T) {
+	tests := []struct {
+		name       string
+		declared   string
+		containers []corev1.Container
+		want       []string
+	}{
+		{
+			name:       "guaranteed",
+			declared:   "Guaranteed",
+			containers: []corev1.Container{{Name: "app", Resources: guaranteedResources}},
+		},
+		{
+			name:       "not guaranteed",
+			declared:   "Guaranteed",
+			containers: []corev1.Container{{Name: "app", Resources: guaranteedResources}, {Name: "sidecar", Resources: burstableResources}},
+			want:       []string{"qos-class: warning: container sidecar: prevents the Guaranteed QoS declared in annotation webhook.trstringer.com/qos-class, which needs CPU and memory limits with requests equal to them, so the pod is Burstable"},
+		},
+		{
+			name:       "best effort",
+			declared:   "BestEffort",
+			containers: []corev1.Container{{Name: "app"}},
+		},
+		{
+			name:       "not best effort",
+			declared:   "BestEffort",
+			containers: []corev1.Container{{Name: "app", Resources: burstableResources}},
+			want:       []string{"qos-class: warning: declares BestEffort QoS in annotation webhook.trstringer.com/qos-class, but its QoS class is Burstable"},
+		},
+		{
+			name:       "unknown",
+			declared:   "Premium",
+			containers: []corev1.Container{{Name: "app"}},
+			want:       []string{`qos-class: warning: annotation webhook.trstringer.com/qos-class has unknown QoS class "Premium": must be Guaranteed, Burstable or BestEffort`},
+		},
+		{
+			name:       "undeclared",
+			containers: []corev1.Container{{Name: "app", Resources: burstableResources}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{WarnQOSMismatch: true}, nil)
+			pod := testPod(corev1.PodSpec{Containers: tt.containers})
+			if tt.declared != "" {
+				pod = withMeta(pod, nil, map[string]string{qosClassAnnotation: tt.declared})
+			}
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestCheckGPUNamespaceQOS(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		testNamespace("gpu", map[string]string{"accelerator": "gpu"}),
+		testNamespace("cpu", nil),
+	)
+	tests := []struct {
+		name      string
+		namespace string
+		resources corev1.ResourceRequirements
+		want      []string
+	}{
+		{
+			name:      "guaranteed in GPU namespace",
+			namespace: "gpu",
+			resources: guaranteedResources,
+		},
+		{
+			name:      "burstable in GPU namespace",
+			namespace: "gpu",
+			resources: burstableResources,
+			want:      []string{"gpu-namespace-qos: container app: must set CPU and memory limits with requests equal to them for Guaranteed QoS in GPU namespace gpu (accelerator=gpu)"},
+		},
+		{
+			name:      "burstable elsewhere",
+			namespace: "cpu",
+			resources: burstableResources,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{GPUNamespaceLabel: "accelerator=gpu", Client: client}, nil)
+			pod := testPod(corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Resources: tt.resources}}})
+			pod.Namespace = tt.namespace
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DefaultQuotaBypassAnnotations are the pod annotations that are known to
+// let pods get around ResourceQuota enforcement.
+var DefaultQuotaBypassAnnotations = []string{"scheduler.alpha.kubernetes.io/critical-pod"}
+
+// checkQuotaBypass rejects pods that set annotations that bypass resource
+// quota enforcement, in namespaces with the quota-enforced label. Pods in
+// other namespaces aren't checked.
+func (e *Engine) checkQuotaBypass(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	keys := make([]string, 0, len(pod.Annotations))
+	for key := range pod.Annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var bypassing []string
+	for _, key := range keys {
+		for _, annotation := range e.bypassAnnotations {
+			if annotation.matches(key, pod.Annotations[key]) {
+				bypassing = append(bypassing, key)
+				break
+			}
+		}
+	}
+	if len(bypassing) == 0 {
+		return nil, nil
+	}
+
+	nsLabels, err := e.namespaceLabels(ctx, meta.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up namespace %s: %w", meta.Namespace, err)
+	}
+	value, ok := nsLabels[e.quotaNamespaceLabel.key]
+	if !ok || !e.quotaNamespaceLabel.matches(e.quotaNamespaceLabel.key, value) {
+		return nil, nil
+	}
+
+	var findings []Finding
+	for _, annotation := range bypassing {
+		findings = append(findings, Finding{Message: fmt.Sprintf("annotation %s bypasses resource quota enforcement, which namespace %s requires (%s)", annotation, meta.Namespace, e.quotaNamespaceLabel)})
+	}
+	return findings, nil
+}
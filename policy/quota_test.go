@@ -0,0 +1,53 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCheckQuotaBypass(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		testNamespace("enforced", map[string]string{"quota": "enforced"}),
+		testNamespace("open", nil),
+	)
+	tests := []struct {
+		name        string
+		namespace   string
+		annotations map[string]string
+		want        []string
+	}{
+		{
+			name:        "bypass in enforced namespace",
+			namespace:   "enforced",
+			annotations: map[string]string{"scheduler.alpha.kubernetes.io/critical-pod": ""},
+			want:        []string{"quota-bypass: annotation scheduler.alpha.kubernetes.io/critical-pod bypasses resource quota enforcement, which namespace enforced requires (quota=enforced)"},
+		},
+		{
+			name:        "bypass in other namespace",
+			namespace:   "open",
+			annotations: map[string]string{"scheduler.alpha.kubernetes.io/critical-pod": ""},
+		},
+		{
+			name:        "no bypass",
+			namespace:   "enforced",
+			annotations: map[string]string{"team": "payments"},
+		},
+		{
+			// Pods that don't bypass quotas don't need their namespace
+			// looked up, so it not existing doesn't matter.
+			name:      "no bypass in missing namespace",
+			namespace: "missing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{QuotaEnforcedNamespaceLabel: "quota=enforced", Client: client}, nil)
+			pod := withMeta(testPod(corev1.PodSpec{}), nil, tt.annotations)
+			pod.Namespace = tt.namespace
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
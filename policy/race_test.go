@@ -0,0 +1,7 @@
+//go:build race
+// +build race
+
+package policy
+
+// raceEnabled is whether the tests are built with the race detector.
+const raceEnabled = true
@@ -0,0 +1,318 @@
+package policy
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// defaultRegistry is the registry that images without a registry host, such
+// as nginx, are pulled from.
+const defaultRegistry = "registry-1.docker.io"
+
+// The media types of the manifests that images can be looked up from.
+const (
+	mediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+)
+
+// imageReference is an image split into the parts needed to find its
+// manifest in a registry.
+type imageReference struct {
+	registry   string
+	repository string
+	// reference is the tag or digest of the image.
+	reference string
+}
+
+// parseImageReference splits an image, such as nginx:1.21,
+// ghcr.io/org/app@sha256:... or localhost:5000/app, into its parts. Images
+// without a tag or digest are the latest tag, like the container runtime
+// pulls.
+func parseImageReference(image string) (imageReference, error) {
+	ref := imageReference{registry: defaultRegistry, reference: "latest"}
+
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, ref.reference = name[:i], name[i+1:]
+	} else if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.reference = name[:i], name[i+1:]
+	}
+
+	// The first part of the name is a registry host only if it looks like a
+	// host, otherwise it's part of a Docker Hub repository.
+	if i := strings.Index(name, "/"); i >= 0 {
+		host := name[:i]
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			ref.registry, name = host, name[i+1:]
+		}
+	}
+	if ref.registry == defaultRegistry && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+
+	if name == "" || ref.reference == "" {
+		return imageReference{}, fmt.Errorf("invalid image %q", image)
+	}
+	ref.repository = name
+	return ref, nil
+}
+
+// registryClient reads image manifests and configs from registries,
+// authenticating with the credentials from a docker config file if there
+// are any for the registry.
+type registryClient struct {
+	client *http.Client
+	// auths are the base64 encoded user:password credentials for each
+	// registry host.
+	auths map[string]string
+}
+
+// newRegistryClient creates a client that uses the credentials in the docker
+// config file at path, such as a mounted .dockerconfigjson pull secret.
+// path may be empty to only pull anonymously.
+func newRegistryClient(path string) (*registryClient, error) {
+	c := &registryClient{client: &http.Client{}, auths: map[string]string{}}
+	if path == "" {
+		return c, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading docker config: %w", err)
+	}
+	var config struct {
+		Auths map[string]struct {
+			Auth     string `json:"auth"`
+			Username string `json:"username"`
+			Password string `json:"password"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("error parsing docker config: %w", err)
+	}
+	for server, auth := range config.Auths {
+		// Servers are often configured as URLs, such as
+		// https://index.docker.io/v1/, rather than hosts.
+		host := server
+		if u, err := url.Parse(server); err == nil && u.Host != "" {
+			host = u.Host
+		}
+		if host == "index.docker.io" || host == "docker.io" {
+			host = defaultRegistry
+		}
+
+		if auth.Auth != "" {
+			c.auths[host] = auth.Auth
+		} else if auth.Username != "" {
+			c.auths[host] = base64.StdEncoding.EncodeToString([]byte(auth.Username + ":" + auth.Password))
+		}
+	}
+	return c, nil
+}
+
+// imageManifest is the manifest of an image for a single platform.
+type imageManifest struct {
+	Config imageDescriptor   `json:"config"`
+	Layers []imageDescriptor `json:"layers"`
+}
+
+// imageDescriptor refers to a blob of an image, such as its config or a
+// layer.
+type imageDescriptor struct {
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+}
+
+// manifest returns the manifest of the image. For multi-platform images the
+// manifest of the linux/amd64 image is returned, or of the first image if
+// there is no linux/amd64 image.
+func (c *registryClient) manifest(ctx context.Context, ref imageReference) (imageManifest, error) {
+	var manifest struct {
+		imageManifest
+		Manifests []struct {
+			Digest   string `json:"digest"`
+			Platform struct {
+				Architecture string `json:"architecture"`
+				OS           string `json:"os"`
+			} `json:"platform"`
+		} `json:"manifests"`
+	}
+
+	accept := strings.Join([]string{mediaTypeDockerManifest, mediaTypeDockerManifestList, mediaTypeOCIManifest, mediaTypeOCIIndex}, ", ")
+	mediaType, err := c.get(ctx, ref, "manifests/"+ref.reference, accept, &manifest)
+	if err != nil {
+		return imageManifest{}, err
+	}
+
+	if mediaType == mediaTypeDockerManifestList || mediaType == mediaTypeOCIIndex {
+		if len(manifest.Manifests) == 0 {
+			return imageManifest{}, fmt.Errorf("image index for %s has no manifests", ref.repository)
+		}
+		digest := manifest.Manifests[0].Digest
+		for _, m := range manifest.Manifests {
+			if m.Platform.OS == "linux" && m.Platform.Architecture == "amd64" {
+				digest = m.Digest
+				break
+			}
+		}
+		return c.manifest(ctx, imageReference{registry: ref.registry, repository: ref.repository, reference: digest})
+	}
+	return manifest.imageManifest, nil
+}
+
+// imageSize returns the compressed size of the image: the size of its config
+// and every layer, as listed in its manifest.
+func (c *registryClient) imageSize(ctx context.Context, ref imageReference) (int64, error) {
+	manifest, err := c.manifest(ctx, ref)
+	if err != nil {
+		return 0, err
+	}
+
+	size := manifest.Config.Size
+	for _, layer := range manifest.Layers {
+		size += layer.Size
+	}
+	return size, nil
+}
+
+// imageUser returns the user the image runs as by default, from its config.
+// It's empty if the image doesn't set a user, so it runs as root.
+func (c *registryClient) imageUser(ctx context.Context, ref imageReference) (string, error) {
+	manifest, err := c.manifest(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	if manifest.Config.Digest == "" {
+		return "", fmt.Errorf("manifest for %s/%s has no config", ref.registry, ref.repository)
+	}
+
+	var config struct {
+		Config struct {
+			User string `json:"User"`
+		} `json:"config"`
+	}
+	if _, err := c.get(ctx, ref, "blobs/"+manifest.Config.Digest, "*/*", &config); err != nil {
+		return "", err
+	}
+	return config.Config.User, nil
+}
+
+// get gets the path, such as manifests/latest, under the image's repository
+// in its registry and decodes it into v, returning its media type.
+func (c *registryClient) get(ctx context.Context, ref imageReference, path, accept string, v interface{}) (string, error) {
+	scheme := "https"
+	if strings.HasPrefix(ref.registry, "localhost") || strings.HasPrefix(ref.registry, "127.0.0.1") {
+		scheme = "http"
+	}
+	getURL := fmt.Sprintf("%s://%s/v2/%s/%s", scheme, ref.registry, ref.repository, path)
+
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, getURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", accept)
+		return req, nil
+	}
+
+	req, err := newRequest()
+	if err != nil {
+		return "", err
+	}
+	if auth, ok := c.auths[ref.registry]; ok {
+		req.Header.Set("Authorization", "Basic "+auth)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	// Most registries, including Docker Hub, want a bearer token even for
+	// anonymous pulls, from the token service they point to.
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+			return "", fmt.Errorf("unauthorized to get %s for %s/%s", path, ref.registry, ref.repository)
+		}
+		token, err := c.token(ctx, ref, challenge)
+		if err != nil {
+			return "", err
+		}
+
+		if req, err = newRequest(); err != nil {
+			return "", err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		if resp, err = c.client.Do(req); err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d getting %s for %s/%s", resp.StatusCode, path, ref.registry, ref.repository)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return "", fmt.Errorf("error decoding %s for %s/%s: %w", path, ref.registry, ref.repository, err)
+	}
+	return strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0]), nil
+}
+
+// token gets a bearer token to pull the image from the token service in the
+// WWW-Authenticate challenge, using the registry's credentials if there are
+// any.
+func (c *registryClient) token(ctx context.Context, ref imageReference, challenge string) (string, error) {
+	params := map[string]string{}
+	for _, part := range strings.Split(challenge[len("bearer "):], ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) == 2 {
+			params[kv[0]] = strings.Trim(kv[1], `"`)
+		}
+	}
+	if params["realm"] == "" {
+		return "", fmt.Errorf("registry %s returned a bearer challenge without a realm", ref.registry)
+	}
+
+	query := url.Values{}
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:pull", ref.repository))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, params["realm"]+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if auth, ok := c.auths[ref.registry]; ok {
+		req.Header.Set("Authorization", "Basic "+auth)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d getting token for %s/%s", resp.StatusCode, ref.registry, ref.repository)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("error decoding token for %s/%s: %w", ref.registry, ref.repository, err)
+	}
+	if token.Token != "" {
+		return token.Token, nil
+	}
+	return token.AccessToken, nil
+}
@@ -0,0 +1,76 @@
+package policy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testImage is an image served by a test registry.
+type testImage struct {
+	// user is the user in the image's config.
+	user string
+	// layers are the sizes of the image's layers.
+	layers []int64
+}
+
+// newTestRegistry starts a registry that serves the images, keyed by
+// repository:tag, and returns its host. The registry is on localhost, so it
+// is reached over plain HTTP.
+func newTestRegistry(t *testing.T, images map[string]testImage) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/v2/")
+		if i := strings.Index(path, "/manifests/"); i >= 0 {
+			image, ok := images[path[:i]+":"+path[i+len("/manifests/"):]]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			manifest := imageManifest{Config: imageDescriptor{Digest: "sha256:" + image.user, Size: 100}}
+			for _, size := range image.layers {
+				manifest.Layers = append(manifest.Layers, imageDescriptor{Size: size})
+			}
+			w.Header().Set("Content-Type", mediaTypeDockerManifest)
+			json.NewEncoder(w).Encode(manifest)
+			return
+		}
+		if i := strings.Index(path, "/blobs/sha256:"); i >= 0 {
+			config := map[string]map[string]string{"config": {"User": path[i+len("/blobs/sha256:"):]}}
+			json.NewEncoder(w).Encode(config)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+func TestParseImageReference(t *testing.T) {
+	tests := []struct {
+		image   string
+		want    imageReference
+		wantErr bool
+	}{
+		{image: "nginx", want: imageReference{registry: defaultRegistry, repository: "library/nginx", reference: "latest"}},
+		{image: "nginx:1.21", want: imageReference{registry: defaultRegistry, repository: "library/nginx", reference: "1.21"}},
+		{image: "org/app:v1", want: imageReference{registry: defaultRegistry, repository: "org/app", reference: "v1"}},
+		{image: "ghcr.io/org/app@sha256:abc", want: imageReference{registry: "ghcr.io", repository: "org/app", reference: "sha256:abc"}},
+		{image: "localhost:5000/app", want: imageReference{registry: "localhost:5000", repository: "app", reference: "latest"}},
+		{image: "app:", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			got, err := parseImageReference(tt.image)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseImageReference() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseImageReference() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// RequesterRestriction locks a namespace so that only the listed users and
+// members of the listed groups can create or update objects in it. Service
+// accounts are users named system:serviceaccount:<namespace>:<name>.
+type RequesterRestriction struct {
+	// Namespace is the locked namespace.
+	Namespace string `json:"namespace"`
+	// Users are the users that are allowed.
+	Users []string `json:"users,omitempty"`
+	// Groups are the groups whose members are allowed.
+	Groups []string `json:"groups,omitempty"`
+}
+
+// checkRequester rejects requests for objects of any resource in a locked
+// namespace from users that aren't allowed to make them, such as anyone
+// but the CI service account. Objects that aren't evaluated for a request,
+// and so have no user, aren't checked.
+func (e *Engine) checkRequester(ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+	if meta.Username == "" {
+		return nil, nil
+	}
+
+	for _, restriction := range e.lockedNamespaces {
+		if restriction.Namespace != meta.Namespace {
+			continue
+		}
+		if contains(restriction.Users, meta.Username) {
+			return nil, nil
+		}
+		for _, group := range meta.Groups {
+			if contains(restriction.Groups, group) {
+				return nil, nil
+			}
+		}
+
+		var allowed []string
+		if len(restriction.Users) > 0 {
+			allowed = append(allowed, "users "+strings.Join(restriction.Users, ", "))
+		}
+		if len(restriction.Groups) > 0 {
+			allowed = append(allowed, "members of groups "+strings.Join(restriction.Groups, ", "))
+		}
+		return []Finding{{Message: fmt.Sprintf("user %s is not allowed to make changes in locked namespace %s: only %s are", meta.Username, meta.Namespace, strings.Join(allowed, " and "))}}, nil
+	}
+	return nil, nil
+}
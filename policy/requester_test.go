@@ -0,0 +1,58 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCheckRequester(t *testing.T) {
+	cfg := &Config{RequesterRestrictions: []RequesterRestriction{{
+		Namespace: "prod",
+		Users:     []string{"system:serviceaccount:ci:deployer"},
+		Groups:    []string{"sre"},
+	}}}
+	tests := []struct {
+		name      string
+		namespace string
+		username  string
+		groups    []string
+		want      []string
+	}{
+		{
+			name:      "allowed user",
+			namespace: "prod",
+			username:  "system:serviceaccount:ci:deployer",
+		},
+		{
+			name:      "allowed group",
+			namespace: "prod",
+			username:  "alice",
+			groups:    []string{"developers", "sre"},
+		},
+		{
+			name:      "not allowed",
+			namespace: "prod",
+			username:  "alice",
+			groups:    []string{"developers"},
+			want:      []string{"requester: user alice is not allowed to make changes in locked namespace prod: only users system:serviceaccount:ci:deployer and members of groups sre are"},
+		},
+		{
+			name:      "unlocked namespace",
+			namespace: "dev",
+			username:  "alice",
+		},
+		{
+			name:      "no request",
+			namespace: "prod",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{}, cfg)
+			meta := RequestMeta{Namespace: tt.namespace, Username: tt.username, Groups: tt.groups}
+			assertFindings(t, e, testPod(corev1.PodSpec{}), meta, tt.want)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package policy
+
+import (
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// The resources that rules can be evaluated against.
+var (
+	podResource                     = metav1.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}
+	deploymentResource              = metav1.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	networkPolicyResource           = metav1.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"}
+	serviceResource                 = metav1.GroupVersionResource{Group: "", Version: "v1", Resource: "services"}
+	persistentVolumeClaimResource   = metav1.GroupVersionResource{Group: "", Version: "v1", Resource: "persistentvolumeclaims"}
+	ingressResource                 = metav1.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"}
+	jobResource                     = metav1.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"}
+	cronJobResource                 = metav1.GroupVersionResource{Group: "batch", Version: "v1", Resource: "cronjobs"}
+	horizontalPodAutoscalerResource = metav1.GroupVersionResource{Group: "autoscaling", Version: "v1", Resource: "horizontalpodautoscalers"}
+	statefulSetResource             = metav1.GroupVersionResource{Group: "apps", Version: "v1", Resource: "statefulsets"}
+)
+
+// The subresources that rules can be evaluated against. Their Resource is
+// the resource and subresource, such as pods/exec, and their objects are
+// the options of the request rather than the object of the resource.
+var (
+	podExecResource   = metav1.GroupVersionResource{Group: "", Version: "v1", Resource: "pods/exec"}
+	podAttachResource = metav1.GroupVersionResource{Group: "", Version: "v1", Resource: "pods/attach"}
+)
+
+// anyResource is the resource of rules that are evaluated against objects of
+// every resource. They aren't evaluated against subresources.
+var anyResource = metav1.GroupVersionResource{}
+
+// isSubresource reports whether the resource is a subresource, such as
+// pods/exec.
+func isSubresource(resource metav1.GroupVersionResource) bool {
+	return strings.Contains(resource.Resource, "/")
+}
+
+// resourceTypes maps each resource that rules can be evaluated against to a
+// func that returns an empty object of the right type to decode it into.
+var resourceTypes = map[metav1.GroupVersionResource]func() runtime.Object{
+	podResource:                     func() runtime.Object { return &corev1.Pod{} },
+	deploymentResource:              func() runtime.Object { return &appsv1.Deployment{} },
+	networkPolicyResource:           func() runtime.Object { return &networkingv1.NetworkPolicy{} },
+	serviceResource:                 func() runtime.Object { return &corev1.Service{} },
+	persistentVolumeClaimResource:   func() runtime.Object { return &corev1.PersistentVolumeClaim{} },
+	ingressResource:                 func() runtime.Object { return &networkingv1.Ingress{} },
+	jobResource:                     func() runtime.Object { return &batchv1.Job{} },
+	cronJobResource:                 func() runtime.Object { return &batchv1.CronJob{} },
+	horizontalPodAutoscalerResource: func() runtime.Object { return &autoscalingv1.HorizontalPodAutoscaler{} },
+	statefulSetResource:             func() runtime.Object { return &appsv1.StatefulSet{} },
+	podExecResource:                 func() runtime.Object { return &corev1.PodExecOptions{} },
+	podAttachResource:               func() runtime.Object { return &corev1.PodAttachOptions{} },
+}
+
+// NewObject returns an empty object to decode objects of the resource into,
+// or false if the resource isn't one that rules can be evaluated against.
+func NewObject(resource metav1.GroupVersionResource) (runtime.Object, bool) {
+	newObject, ok := resourceTypes[resource]
+	if !ok {
+		return nil, false
+	}
+	return newObject(), true
+}
@@ -0,0 +1,211 @@
+package policy
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// annotationPrefix is the prefix of the annotations that objects can use to
+// adjust how rules treat them, such as exemptions.
+const annotationPrefix = "webhook.trstringer.com/"
+
+// rule is a named policy check that is run against every object of its
+// resource type, or every object if its resource is anyResource. Rules with
+// an enabled func are only run when it returns true. A check returns an
+// error when it can't decide, for example because an external lookup failed,
+// and the error is handled per the failure policy. A check must honor ctx,
+// returning soon after it's done, since that is how PipelineTimeout stops
+// it. Go plugins' checks are the exception: they return once ctx is done,
+// but the plugin can't be stopped and keeps running until it returns. Rules
+// configured to only warn have all of their findings returned as warnings.
+// Rules are dynamic if their findings depend on more than the request and
+// the engine's settings, such as on the time or on other objects in the
+// cluster, so that decisions they contributed to aren't reused for
+// identical requests. Only rules with
+// onUpdate set are run for updates, which are mostly controllers changing
+// labels or status of objects that were already admitted, so that objects
+// created before a rule was enabled can still be updated.
+type rule struct {
+	name           string
+	priority       int
+	warnOnly       bool
+	enforcePercent int
+	docURL         *template.Template
+	resource       metav1.GroupVersionResource
+	dynamic        bool
+	onUpdate       bool
+	enabled        func(e *Engine) bool
+	check          func(e *Engine, ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error)
+}
+
+// podCheck adapts a check of pods so that it can be used as a rule's check.
+func podCheck(check func(e *Engine, ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error)) func(e *Engine, ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+	return func(e *Engine, ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+		return check(e, ctx, obj.(*corev1.Pod), meta)
+	}
+}
+
+// rules is every policy check the engine knows about, in bundled order.
+var rules = []rule{
+	{name: "default-deny", resource: anyResource, enabled: func(e *Engine) bool { return e.defaultDeny != nil }, check: (*Engine).checkDefaultDeny},
+	{name: "hello-label", resource: podResource, check: podCheck((*Engine).checkHelloLabel)},
+	{name: "probe-timings", resource: podResource, enabled: func(e *Engine) bool { return e.opts.EnforceProbesTimeout }, check: podCheck((*Engine).checkProbeTimings)},
+	{name: "name-convention", resource: podResource, enabled: func(e *Engine) bool { return e.namePattern != nil }, check: podCheck((*Engine).checkNameConvention)},
+	{name: "emptydir-size-limit", resource: podResource, enabled: func(e *Engine) bool { return e.maxEmptyDirSize != nil || e.opts.RequireEmptyDirSizeLimit }, check: podCheck((*Engine).checkEmptyDirSizeLimit)},
+	{name: "privilege-escalation", resource: podResource, enabled: func(e *Engine) bool { return e.opts.ForbidPrivilegeEscalation }, check: podCheck((*Engine).checkPrivilegeEscalation)},
+	{name: "apparmor-profile", resource: podResource, enabled: func(e *Engine) bool { return e.opts.RequireAppArmor }, check: podCheck((*Engine).checkAppArmorProfile)},
+	{name: "read-only-root-filesystem", resource: podResource, enabled: func(e *Engine) bool { return e.opts.RequireReadOnlyRootFilesystem }, check: podCheck((*Engine).checkReadOnlyRootFilesystem)},
+	{name: "pull-secrets-exist", resource: podResource, dynamic: true, enabled: func(e *Engine) bool { return e.opts.VerifyPullSecretsExist }, check: podCheck((*Engine).checkPullSecretsExist)},
+	{name: "team-allowlist", resource: podResource, enabled: func(e *Engine) bool { return e.opts.TeamAllowlist != nil }, check: podCheck((*Engine).checkTeamLabel)},
+	{name: "anti-affinity", resource: podResource, enabled: func(e *Engine) bool { return e.antiAffinitySelector != nil }, check: podCheck((*Engine).checkAntiAffinity)},
+	{name: "limit-consistency", resource: podResource, enabled: func(e *Engine) bool { return e.opts.LimitRatio > 0 }, check: podCheck((*Engine).checkLimitConsistency)},
+	{name: "namespace-environment", resource: podResource, dynamic: true, enabled: func(e *Engine) bool { return e.opts.VerifyEnvironment }, check: podCheck((*Engine).checkEnvironment)},
+	{name: "max-env-vars", resource: podResource, enabled: func(e *Engine) bool { return e.opts.MaxEnvVars > 0 }, check: podCheck((*Engine).checkMaxEnvVars)},
+	{name: "host-pid", resource: podResource, enabled: func(e *Engine) bool { return e.opts.ForbidHostPID }, check: podCheck((*Engine).checkHostPID)},
+	{name: "host-ipc", resource: podResource, enabled: func(e *Engine) bool { return e.opts.ForbidHostIPC }, check: podCheck((*Engine).checkHostIPC)},
+	{name: "security-context", resource: podResource, enabled: func(e *Engine) bool { return e.opts.RequireSecurityContext }, check: podCheck((*Engine).checkSecurityContextSet)},
+	{name: "label-format", resource: podResource, enabled: func(e *Engine) bool { return len(e.labelFormats) > 0 }, check: podCheck((*Engine).checkLabelFormats)},
+	{name: "required-volumes", resource: podResource, enabled: func(e *Engine) bool { return len(e.requiredVolumes) > 0 }, check: podCheck((*Engine).checkRequiredVolumes)},
+	{name: "unresolved-placeholders", resource: podResource, enabled: func(e *Engine) bool { return e.placeholderPattern != nil }, check: podCheck((*Engine).checkUnresolvedPlaceholders)},
+	{name: "image-size", resource: podResource, dynamic: true, enabled: func(e *Engine) bool { return e.maxImageSize != nil }, check: podCheck((*Engine).checkImageSize)},
+	{name: "scheduler-name", resource: podResource, enabled: func(e *Engine) bool { return len(e.opts.AllowedSchedulers) > 0 }, check: podCheck((*Engine).checkSchedulerName)},
+	{name: "priority-class-tier", resource: podResource, dynamic: true, enabled: func(e *Engine) bool { return e.opts.VerifyPriorityClassTier }, check: podCheck((*Engine).checkPriorityClassTier)},
+	{name: "root-image-user", resource: podResource, dynamic: true, enabled: func(e *Engine) bool { return e.opts.RejectRootImages }, check: podCheck((*Engine).checkRootImageUser)},
+	{name: "id-ranges", resource: podResource, enabled: func(e *Engine) bool { return len(e.fsGroupRanges)+len(e.runAsUserRanges)+len(e.runAsGroupRanges) > 0 }, check: podCheck((*Engine).checkIDRanges)},
+	{name: "container-names", resource: podResource, enabled: func(e *Engine) bool { return e.opts.ValidateContainerNames }, check: podCheck((*Engine).checkContainerNames)},
+	{name: "env-refs-exist", resource: podResource, dynamic: true, enabled: func(e *Engine) bool { return e.opts.VerifyEnvRefsExist }, check: podCheck((*Engine).checkEnvRefsExist)},
+	{name: "stateful-on-spot", resource: podResource, enabled: func(e *Engine) bool { return e.statefulLabel != nil }, check: podCheck((*Engine).checkStatefulOnSpot)},
+	{name: "sensitive-data-security", resource: podResource, enabled: func(e *Engine) bool { return e.sensitiveLabel != nil }, check: podCheck((*Engine).checkSensitiveDataSecurity)},
+	{name: "expensive-liveness-exec", resource: podResource, enabled: func(e *Engine) bool { return e.expensiveExecPattern != nil }, check: podCheck((*Engine).checkExpensiveLivenessExec)},
+	{name: "quota-bypass", resource: podResource, dynamic: true, enabled: func(e *Engine) bool { return e.quotaNamespaceLabel != nil }, check: podCheck((*Engine).checkQuotaBypass)},
+	{name: "probe-ports", resource: podResource, enabled: func(e *Engine) bool { return e.opts.ValidateProbePorts }, check: podCheck((*Engine).checkProbePorts)},
+	{name: "freeze-window", resource: podResource, dynamic: true, enabled: func(e *Engine) bool { return e.freezeNamespaceLabel != nil && len(e.freezeWindows) > 0 }, check: podCheck((*Engine).checkFreezeWindow)},
+	{name: "max-volume-mounts", resource: podResource, enabled: func(e *Engine) bool { return e.opts.MaxVolumeMounts > 0 }, check: podCheck((*Engine).checkMaxVolumeMounts)},
+	{name: "mutable-tags", resource: podResource, dynamic: true, enabled: func(e *Engine) bool { return e.productionLabel != nil }, check: podCheck((*Engine).checkMutableTags)},
+	{name: "deprecated-annotations", resource: podResource, enabled: func(e *Engine) bool { return e.opts.WarnDeprecatedAnnotations }, check: podCheck((*Engine).checkDeprecatedAnnotations)},
+	{name: "image-pull-policy", resource: podResource, enabled: func(e *Engine) bool { return e.opts.RequireImagePullPolicy }, check: podCheck((*Engine).checkImagePullPolicySet)},
+	{name: "overlapping-mounts", resource: podResource, enabled: func(e *Engine) bool { return e.opts.WarnOverlappingMounts }, check: podCheck((*Engine).checkOverlappingMounts)},
+	{name: "hugepages", resource: podResource, enabled: func(e *Engine) bool { return e.opts.ValidateHugePages }, check: podCheck((*Engine).checkHugePages)},
+	{name: "qos-class", resource: podResource, enabled: func(e *Engine) bool { return e.opts.WarnQOSMismatch }, check: podCheck((*Engine).checkQOSClass)},
+	{name: "capabilities-add", resource: podResource, dynamic: true, enabled: func(e *Engine) bool { return e.restrictedLabel != nil }, check: podCheck((*Engine).checkCapabilitiesAdd)},
+	{name: "emptydir-storage-limits", resource: podResource, enabled: func(e *Engine) bool { return e.opts.RequireEmptyDirStorageLimits }, check: podCheck((*Engine).checkEmptyDirStorageLimits)},
+	{name: "gpu-namespace-qos", resource: podResource, dynamic: true, enabled: func(e *Engine) bool { return e.gpuNamespaceLabel != nil }, check: podCheck((*Engine).checkGPUNamespaceQOS)},
+	{name: "configmap-owners", resource: podResource, dynamic: true, enabled: func(e *Engine) bool { return e.opts.ConfigMapOwnerLabel != "" }, check: podCheck((*Engine).checkConfigMapOwners)},
+	{name: "topology-spread", resource: podResource, enabled: func(e *Engine) bool { return e.opts.MaxTopologySpreadConstraints > 0 }, check: podCheck((*Engine).checkTopologySpread)},
+	{name: "secret-env", resource: podResource, enabled: func(e *Engine) bool { return e.secretEnvPattern != nil }, check: podCheck((*Engine).checkSecretEnv)},
+	{name: "revision-history-limit", resource: deploymentResource, enabled: func(e *Engine) bool { return e.opts.RequireRevisionHistoryLimit || e.opts.MaxRevisionHistoryLimit > 0 }, check: (*Engine).checkRevisionHistoryLimit},
+	{name: "network-policy-permissive", resource: networkPolicyResource, enabled: func(e *Engine) bool { return len(e.opts.RestrictedNetworkNamespaces) > 0 }, check: (*Engine).checkNetworkPolicyPermissive},
+	{name: "load-balancer-annotations", resource: serviceResource, enabled: func(e *Engine) bool { return len(e.opts.LoadBalancerAnnotations) > 0 }, check: (*Engine).checkLoadBalancerAnnotations},
+	{name: "pvc-storage-bounds", resource: persistentVolumeClaimResource, enabled: func(e *Engine) bool { return e.minPVCStorage != nil || e.maxPVCStorage != nil }, check: (*Engine).checkPVCStorageBounds},
+	{name: "ingress-tls", resource: ingressResource, enabled: func(e *Engine) bool { return e.publicHostPattern != nil }, check: (*Engine).checkIngressTLS},
+	{name: "service-selector", resource: serviceResource, enabled: func(e *Engine) bool { return len(e.opts.ServiceSelectorLabels) > 0 }, check: (*Engine).checkServiceSelector},
+	{name: "job-ttl", resource: jobResource, enabled: func(e *Engine) bool { return e.opts.RequireJobTTL }, check: (*Engine).checkJobTTL},
+	{name: "cronjob-ttl", resource: cronJobResource, enabled: func(e *Engine) bool { return e.opts.RequireJobTTL }, check: (*Engine).checkCronJobTTL},
+	{name: "hpa-replicas", resource: horizontalPodAutoscalerResource, enabled: func(e *Engine) bool { return e.opts.ValidateHPAReplicas }, check: (*Engine).checkHPAReplicas},
+	{name: "deployment-strategy", resource: deploymentResource, enabled: func(e *Engine) bool { return e.opts.ValidateDeploymentStrategy }, check: (*Engine).checkDeploymentStrategy},
+	{name: "object-size", resource: anyResource, enabled: func(e *Engine) bool { return e.opts.MaxObjectSize > 0 }, check: (*Engine).checkObjectSize},
+	{name: "managed-by", resource: anyResource, enabled: func(e *Engine) bool { return len(e.opts.AllowedManagedBy) > 0 }, check: (*Engine).checkManagedBy},
+	{name: "progress-deadline", resource: deploymentResource, enabled: func(e *Engine) bool { return e.opts.RequireProgressDeadline || e.opts.MaxProgressDeadlineSeconds > 0 }, check: (*Engine).checkProgressDeadline},
+	{name: "pod-management-policy", resource: statefulSetResource, enabled: func(e *Engine) bool { return e.opts.ParallelStartupLabel != "" }, check: (*Engine).checkPodManagementPolicy},
+	{name: "metadata-injection", resource: anyResource, enabled: func(e *Engine) bool { return e.injectionPattern != nil }, check: (*Engine).checkMetadataInjection},
+	{name: "pod-exec", resource: podExecResource, dynamic: true, enabled: func(e *Engine) bool { return e.noExecNamespaceLabel != nil }, check: (*Engine).checkPodExec},
+	{name: "pod-attach", resource: podAttachResource, dynamic: true, enabled: func(e *Engine) bool { return e.noExecNamespaceLabel != nil }, check: (*Engine).checkPodExec},
+	{name: "requester", resource: anyResource, enabled: func(e *Engine) bool { return len(e.lockedNamespaces) > 0 }, check: (*Engine).checkRequester},
+	{name: "immutable-fields", resource: anyResource, onUpdate: true, enabled: func(e *Engine) bool { return len(e.immutableFields) > 0 }, check: (*Engine).checkImmutableFields},
+	{name: "ingress-class", resource: ingressResource, enabled: func(e *Engine) bool { return len(e.opts.AllowedIngressClasses) > 0 }, check: (*Engine).checkIngressClass},
+}
+
+// buildRuleset returns the enabled rules, the bundled ones followed by
+// pluginRules, with the settings from cfg applied, sorted by priority. The
+// sort is stable so that rules with the same priority are always evaluated in
+// the same order. The config must already be valid. Disabled rules are left
+// out regardless of config or other options.
+func (e *Engine) buildRuleset(cfg *Config, pluginRules []rule) []rule {
+	settings := map[string]RuleConfig{}
+	for _, rc := range cfg.Rules {
+		settings[rc.Name] = rc
+	}
+
+	disabled := map[string]bool{}
+	for _, name := range e.opts.DisabledRules {
+		disabled[name] = true
+	}
+
+	var ruleset []rule
+	for _, r := range append(append([]rule{}, rules...), pluginRules...) {
+		r.enforcePercent = 100
+		if disabled[r.name] {
+			e.opts.Logger.Printf("rule %s disabled", r.name)
+			continue
+		}
+		if rc, ok := settings[r.name]; ok {
+			r.priority = rc.Priority
+			r.warnOnly = rc.Action == ActionWarn
+			if rc.EnforcePercent != nil {
+				r.enforcePercent = *rc.EnforcePercent
+			}
+			if rc.DocURL != "" {
+				r.docURL = template.Must(parseDocURL(rc.DocURL))
+			}
+		}
+		if r.enabled != nil && !r.enabled(e) {
+			continue
+		}
+		ruleset = append(ruleset, r)
+	}
+
+	sort.SliceStable(ruleset, func(i, j int) bool {
+		return ruleset[i].priority > ruleset[j].priority
+	})
+
+	return ruleset
+}
+
+// IsKnownRule reports whether name is the name of a bundled rule, or of a
+// plugin's rule. Which plugins there are isn't known until they're loaded,
+// so the name of any plugin is accepted.
+func IsKnownRule(name string) bool {
+	if strings.HasPrefix(name, pluginRulePrefix) && len(name) > len(pluginRulePrefix) {
+		return true
+	}
+	for _, r := range rules {
+		if r.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHelloLabel requires every pod to have a hello label, and warns when
+// the label is set to a value that will be deprecated.
+func (e *Engine) checkHelloLabel(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	if value, ok := pod.Labels["hello"]; !ok {
+		return []Finding{{Message: "missing required hello label"}}, nil
+	} else if value == "world" {
+		return []Finding{{Message: "world will be deprecated for hello in the future", Warning: true}}, nil
+	}
+	return nil, nil
+}
+
+// allContainers returns the pod's init containers followed by its containers.
+func allContainers(pod *corev1.Pod) []corev1.Container {
+	containers := make([]corev1.Container, 0, len(pod.Spec.InitContainers)+len(pod.Spec.Containers))
+	containers = append(containers, pod.Spec.InitContainers...)
+	return append(containers, pod.Spec.Containers...)
+}
+
+// contains reports whether s is one of values.
+func contains(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
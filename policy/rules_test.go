@@ -0,0 +1,31 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBuildRuleset(t *testing.T) {
+	opts := Options{ForbidHostPID: true, ForbidHostIPC: true, DisabledRules: []string{"host-ipc"}}
+	cfg := &Config{Rules: []RuleConfig{{Name: "host-pid", Priority: 10, Action: ActionWarn}}}
+	e := newTestEngine(t, opts, cfg)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec:       corev1.PodSpec{HostPID: true, HostIPC: true},
+	}
+	assertFindings(t, e, pod, RequestMeta{}, []string{
+		"host-pid: warning: must not use the host PID namespace (hostPID)",
+		"hello-label: missing required hello label",
+	})
+}
+
+func TestIsKnownRule(t *testing.T) {
+	for name, want := range map[string]bool{"hello-label": true, "host-pid": true, "hello": false, "plugin:require-label": true, "plugin:": false} {
+		if got := IsKnownRule(name); got != want {
+			t.Errorf("IsKnownRule(%q) = %t, want %t", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// checkSchedulerName rejects pods that use a scheduler that isn't allowed,
+// for clusters with more than one scheduler. Pods that don't set a scheduler
+// use the default scheduler, which is only allowed with
+// AllowDefaultScheduler set.
+func (e *Engine) checkSchedulerName(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	scheduler := pod.Spec.SchedulerName
+	if scheduler == "" {
+		scheduler = corev1.DefaultSchedulerName
+	}
+
+	if contains(e.opts.AllowedSchedulers, scheduler) || (scheduler == corev1.DefaultSchedulerName && e.opts.AllowDefaultScheduler) {
+		return nil, nil
+	}
+	return []Finding{{Message: fmt.Sprintf("scheduler %s is not allowed, must be one of %s", scheduler, strings.Join(e.allowedSchedulerNames(), ", "))}}, nil
+}
+
+// allowedSchedulerNames returns every scheduler pods are allowed to use.
+func (e *Engine) allowedSchedulerNames() []string {
+	names := e.opts.AllowedSchedulers
+	if e.opts.AllowDefaultScheduler && !contains(names, corev1.DefaultSchedulerName) {
+		names = append([]string{corev1.DefaultSchedulerName}, names...)
+	}
+	return names
+}
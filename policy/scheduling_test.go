@@ -0,0 +1,47 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCheckSchedulerName(t *testing.T) {
+	tests := []struct {
+		name         string
+		allowDefault bool
+		scheduler    string
+		want         []string
+	}{
+		{
+			name:      "allowed",
+			scheduler: "batch",
+		},
+		{
+			name:      "not allowed",
+			scheduler: "custom",
+			want:      []string{"scheduler-name: scheduler custom is not allowed, must be one of batch"},
+		},
+		{
+			name: "default not allowed",
+			want: []string{"scheduler-name: scheduler default-scheduler is not allowed, must be one of batch"},
+		},
+		{
+			name:         "default allowed",
+			allowDefault: true,
+		},
+		{
+			name:         "not allowed with default",
+			allowDefault: true,
+			scheduler:    "custom",
+			want:         []string{"scheduler-name: scheduler custom is not allowed, must be one of default-scheduler, batch"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{AllowedSchedulers: []string{"batch"}, AllowDefaultScheduler: tt.allowDefault}, nil)
+			assertFindings(t, e, testPod(corev1.PodSpec{SchedulerName: tt.scheduler}), RequestMeta{}, tt.want)
+		})
+	}
+}
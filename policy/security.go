@@ -0,0 +1,199 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// checkPrivilegeEscalation rejects containers that set
+// allowPrivilegeEscalation to true. With RequireExplicitPrivilegeEscalation
+// set, containers that leave it unset are also rejected, because the
+// container runtime allows privilege escalation by default.
+func (e *Engine) checkPrivilegeEscalation(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	for _, container := range allContainers(pod) {
+		var allowed *bool
+		if container.SecurityContext != nil {
+			allowed = container.SecurityContext.AllowPrivilegeEscalation
+		}
+
+		switch {
+		case allowed == nil && e.opts.RequireExplicitPrivilegeEscalation:
+			findings = append(findings, Finding{
+				Container: container.Name,
+				Message:   "securityContext.allowPrivilegeEscalation must be set to false",
+			})
+		case allowed != nil && *allowed:
+			findings = append(findings, Finding{
+				Container: container.Name,
+				Message:   "securityContext.allowPrivilegeEscalation must not be true",
+			})
+		}
+	}
+	return findings, nil
+}
+
+// appArmorAnnotationPrefix is the prefix of the annotations that set the
+// AppArmor profile for each container, followed by the container name.
+const appArmorAnnotationPrefix = "container.apparmor.security.beta.kubernetes.io/"
+
+// checkAppArmorProfile rejects containers that don't have an AppArmor
+// profile annotation, or whose annotation sets the unconfined profile.
+func (e *Engine) checkAppArmorProfile(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	for _, container := range allContainers(pod) {
+		annotation := appArmorAnnotationPrefix + container.Name
+		profile, ok := pod.Annotations[annotation]
+		switch {
+		case !ok:
+			findings = append(findings, Finding{
+				Container: container.Name,
+				Message:   fmt.Sprintf("missing AppArmor profile annotation %s", annotation),
+			})
+		case profile == "unconfined":
+			findings = append(findings, Finding{
+				Container: container.Name,
+				Message:   fmt.Sprintf("AppArmor profile annotation %s must not be unconfined", annotation),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// writableRootFilesystemAnnotation exempts the comma-separated containers it
+// lists, which may be surrounded by spaces, from requiring a read-only root
+// filesystem.
+const writableRootFilesystemAnnotation = annotationPrefix + "writable-root-filesystem"
+
+// checkReadOnlyRootFilesystem rejects containers that don't set
+// readOnlyRootFilesystem, unless the pod exempts them because they
+// genuinely need to write to their root filesystem.
+func (e *Engine) checkReadOnlyRootFilesystem(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var exempt []string
+	for _, name := range strings.Split(pod.Annotations[writableRootFilesystemAnnotation], ",") {
+		exempt = append(exempt, strings.TrimSpace(name))
+	}
+
+	var findings []Finding
+	for _, container := range allContainers(pod) {
+		if contains(exempt, container.Name) {
+			continue
+		}
+		if container.SecurityContext == nil || container.SecurityContext.ReadOnlyRootFilesystem == nil || !*container.SecurityContext.ReadOnlyRootFilesystem {
+			findings = append(findings, Finding{
+				Container: container.Name,
+				Message:   fmt.Sprintf("securityContext.readOnlyRootFilesystem must be true (or the container listed in the %s annotation)", writableRootFilesystemAnnotation),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// checkHostPID rejects pods that share the host's PID namespace, which lets
+// them see and signal every process on the node.
+func (e *Engine) checkHostPID(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	if pod.Spec.HostPID {
+		return []Finding{{Message: "must not use the host PID namespace (hostPID)"}}, nil
+	}
+	return nil, nil
+}
+
+// checkHostIPC rejects pods that share the host's IPC namespace, which lets
+// them read the shared memory of every process on the node.
+func (e *Engine) checkHostIPC(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	if pod.Spec.HostIPC {
+		return []Finding{{Message: "must not use the host IPC namespace (hostIPC)"}}, nil
+	}
+	return nil, nil
+}
+
+// checkSecurityContextSet rejects containers that have no securityContext at
+// all, since that means none of the hardening settings are set. The granular
+// security rules check what the settings are.
+func (e *Engine) checkSecurityContextSet(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	for _, container := range allContainers(pod) {
+		if container.SecurityContext == nil {
+			findings = append(findings, Finding{
+				Container: container.Name,
+				Message:   "securityContext must be set, e.g. with runAsNonRoot, allowPrivilegeEscalation: false, readOnlyRootFilesystem and capabilities.drop: [ALL]",
+			})
+		}
+	}
+	return findings, nil
+}
+
+// checkRootImageUser rejects containers whose image runs as root by default,
+// which runAsNonRoot can only catch when it's set. Containers that set
+// runAsNonRoot, or a non-root runAsUser, themselves or through the pod's
+// securityContext aren't checked, since they don't run as the image's user.
+// The image's user is looked up in its registry.
+func (e *Engine) checkRootImageUser(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	for _, container := range allContainers(pod) {
+		if runsAsNonRoot(pod, container) {
+			continue
+		}
+
+		user, err := e.imageUser(ctx, container.Image)
+		if err != nil {
+			return nil, fmt.Errorf("error looking up user of image %s: %w", container.Image, err)
+		}
+		if isRootUser(user) {
+			findings = append(findings, Finding{
+				Container: container.Name,
+				Message:   fmt.Sprintf("image %s runs as root by default; set securityContext.runAsNonRoot or a non-root runAsUser", container.Image),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// runsAsNonRoot reports whether the container is set to run as a non-root
+// user regardless of its image, with settings on the container taking
+// precedence over the pod's.
+func runsAsNonRoot(pod *corev1.Pod, container corev1.Container) bool {
+	var runAsNonRoot *bool
+	var runAsUser *int64
+	if psc := pod.Spec.SecurityContext; psc != nil {
+		runAsNonRoot, runAsUser = psc.RunAsNonRoot, psc.RunAsUser
+	}
+	if sc := container.SecurityContext; sc != nil {
+		if sc.RunAsNonRoot != nil {
+			runAsNonRoot = sc.RunAsNonRoot
+		}
+		if sc.RunAsUser != nil {
+			runAsUser = sc.RunAsUser
+		}
+	}
+
+	if runAsUser != nil {
+		return *runAsUser != 0
+	}
+	return runAsNonRoot != nil && *runAsNonRoot
+}
+
+// isRootUser reports whether an image's user, as user, uid, user:group or
+// uid:gid, is root. Images without a user run as root.
+func isRootUser(user string) bool {
+	name := strings.SplitN(user, ":", 2)[0]
+	return name == "" || name == "root" || name == "0"
+}
+
+// imageUser returns the default user of the image, using the lookup cache.
+func (e *Engine) imageUser(ctx context.Context, image string) (string, error) {
+	user, err := e.lookups.get(fmt.Sprintf("image-user/%s", image), func() (interface{}, error) {
+		ref, err := parseImageReference(image)
+		if err != nil {
+			return nil, err
+		}
+		return e.registry.imageUser(ctx, ref)
+	})
+	if err != nil {
+		return "", err
+	}
+	return user.(string), nil
+}
@@ -0,0 +1,230 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+func int64Ptr(i int64) *int64 { return &i }
+
+func TestCheckPrivilegeEscalation(t *testing.T) {
+	tests := []struct {
+		name            string
+		requireExplicit bool
+		securityContext *corev1.SecurityContext
+		want            []string
+	}{
+		{
+			name:            "false",
+			securityContext: &corev1.SecurityContext{AllowPrivilegeEscalation: boolPtr(false)},
+		},
+		{
+			name:            "true",
+			securityContext: &corev1.SecurityContext{AllowPrivilegeEscalation: boolPtr(true)},
+			want:            []string{"privilege-escalation: container app: securityContext.allowPrivilegeEscalation must not be true"},
+		},
+		{
+			name: "unset",
+		},
+		{
+			name:            "unset when explicit is required",
+			requireExplicit: true,
+			want:            []string{"privilege-escalation: container app: securityContext.allowPrivilegeEscalation must be set to false"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{ForbidPrivilegeEscalation: true, RequireExplicitPrivilegeEscalation: tt.requireExplicit}, nil)
+			pod := testPod(corev1.PodSpec{Containers: []corev1.Container{{Name: "app", SecurityContext: tt.securityContext}}})
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestCheckAppArmorProfile(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        []string
+	}{
+		{
+			name:        "runtime default",
+			annotations: map[string]string{appArmorAnnotationPrefix + "app": "runtime/default"},
+		},
+		{
+			name: "missing",
+			want: []string{"apparmor-profile: container app: missing AppArmor profile annotation container.apparmor.security.beta.kubernetes.io/app"},
+		},
+		{
+			name:        "unconfined",
+			annotations: map[string]string{appArmorAnnotationPrefix + "app": "unconfined"},
+			want:        []string{"apparmor-profile: container app: AppArmor profile annotation container.apparmor.security.beta.kubernetes.io/app must not be unconfined"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{RequireAppArmor: true}, nil)
+			pod := withMeta(testPod(corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}), nil, tt.annotations)
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestCheckReadOnlyRootFilesystem(t *testing.T) {
+	tests := []struct {
+		name            string
+		annotations     map[string]string
+		securityContext *corev1.SecurityContext
+		want            []string
+	}{
+		{
+			name:            "read-only",
+			securityContext: &corev1.SecurityContext{ReadOnlyRootFilesystem: boolPtr(true)},
+		},
+		{
+			name:            "writable",
+			securityContext: &corev1.SecurityContext{ReadOnlyRootFilesystem: boolPtr(false)},
+			want:            []string{"read-only-root-filesystem: container app: securityContext.readOnlyRootFilesystem must be true (or the container listed in the webhook.trstringer.com/writable-root-filesystem annotation)"},
+		},
+		{
+			name: "unset",
+			want: []string{"read-only-root-filesystem: container app: securityContext.readOnlyRootFilesystem must be true (or the container listed in the webhook.trstringer.com/writable-root-filesystem annotation)"},
+		},
+		{
+			name:        "exempt",
+			annotations: map[string]string{writableRootFilesystemAnnotation: "sidecar,app"},
+		},
+		{
+			name:        "exempt with spaces",
+			annotations: map[string]string{writableRootFilesystemAnnotation: "sidecar, app"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{RequireReadOnlyRootFilesystem: true}, nil)
+			pod := withMeta(testPod(corev1.PodSpec{Containers: []corev1.Container{{Name: "app", SecurityContext: tt.securityContext}}}), nil, tt.annotations)
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestCheckHostNamespaces(t *testing.T) {
+	tests := []struct {
+		name string
+		spec corev1.PodSpec
+		want []string
+	}{
+		{
+			name: "no host namespaces",
+		},
+		{
+			name: "host PID",
+			spec: corev1.PodSpec{HostPID: true},
+			want: []string{"host-pid: must not use the host PID namespace (hostPID)"},
+		},
+		{
+			name: "host IPC",
+			spec: corev1.PodSpec{HostIPC: true},
+			want: []string{"host-ipc: must not use the host IPC namespace (hostIPC)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{ForbidHostPID: true, ForbidHostIPC: true}, nil)
+			assertFindings(t, e, testPod(tt.spec), RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestCheckSecurityContextSet(t *testing.T) {
+	e := newTestEngine(t, Options{RequireSecurityContext: true}, nil)
+	pod := testPod(corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "init"}},
+		Containers:     []corev1.Container{{Name: "app", SecurityContext: &corev1.SecurityContext{}}},
+	})
+	assertFindings(t, e, pod, RequestMeta{}, []string{
+		"security-context: container init: securityContext must be set, e.g. with runAsNonRoot, allowPrivilegeEscalation: false, readOnlyRootFilesystem and capabilities.drop: [ALL]",
+	})
+}
+
+func TestCheckRootImageUser(t *testing.T) {
+	registry := newTestRegistry(t, map[string]testImage{
+		"app:root":    {user: ""},
+		"app:nonroot": {user: "1000:1000"},
+	})
+
+	tests := []struct {
+		name               string
+		image              string
+		podSecurityContext *corev1.PodSecurityContext
+		securityContext    *corev1.SecurityContext
+		want               []string
+	}{
+		{
+			name:  "non-root image",
+			image: registry + "/app:nonroot",
+		},
+		{
+			name:  "root image",
+			image: registry + "/app:root",
+			want:  []string{"root-image-user: container app: image " + registry + "/app:root runs as root by default; set securityContext.runAsNonRoot or a non-root runAsUser"},
+		},
+		{
+			name:            "root image with runAsNonRoot",
+			image:           registry + "/app:root",
+			securityContext: &corev1.SecurityContext{RunAsNonRoot: boolPtr(true)},
+		},
+		{
+			name:               "root image with pod runAsUser",
+			image:              registry + "/app:root",
+			podSecurityContext: &corev1.PodSecurityContext{RunAsUser: int64Ptr(1000)},
+		},
+		{
+			name:               "container runAsUser root overrides pod",
+			image:              registry + "/app:root",
+			podSecurityContext: &corev1.PodSecurityContext{RunAsNonRoot: boolPtr(true)},
+			securityContext:    &corev1.SecurityContext{RunAsUser: int64Ptr(0)},
+			want:               []string{"root-image-user: container app: image " + registry + "/app:root runs as root by default; set securityContext.runAsNonRoot or a non-root runAsUser"},
+		},
+		{
+			name:  "unknown image",
+			image: registry + "/app:missing",
+			want:  []string{"root-image-user: unable to evaluate rule root-image-user: error looking up user of image " + registry + "/app:missing: unexpected status 404 getting manifests/missing for " + registry + "/app"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{RejectRootImages: true}, nil)
+			pod := testPod(corev1.PodSpec{
+				SecurityContext: tt.podSecurityContext,
+				Containers:      []corev1.Container{{Name: "app", Image: tt.image, SecurityContext: tt.securityContext}},
+			})
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestIsRootUser(t *testing.T) {
+	for user, want := range map[string]bool{
+		"":          true,
+		"root":      true,
+		"0":         true,
+		"0:0":       true,
+		"root:1000": true,
+		"1000":      false,
+		"app":       false,
+		"1000:0":    false,
+	} {
+		if got := isRootUser(user); got != want {
+			t.Errorf("isRootUser(%q) = %t, want %t", user, got, want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// checkSensitiveDataSecurity holds pods with the sensitive data label, such
+// as data-classification=pii, to a stricter security context: every
+// container must run as non-root, with a read-only root filesystem and all
+// capabilities dropped.
+func (e *Engine) checkSensitiveDataSecurity(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	value, ok := pod.Labels[e.sensitiveLabel.key]
+	if !ok || !e.sensitiveLabel.matches(e.sensitiveLabel.key, value) {
+		return nil, nil
+	}
+
+	var findings []Finding
+	for _, container := range allContainers(pod) {
+		var unmet []string
+		if !runsAsNonRoot(pod, container) {
+			unmet = append(unmet, "runAsNonRoot")
+		}
+		sc := container.SecurityContext
+		if sc == nil || sc.ReadOnlyRootFilesystem == nil || !*sc.ReadOnlyRootFilesystem {
+			unmet = append(unmet, "readOnlyRootFilesystem")
+		}
+		if !dropsAllCapabilities(sc) {
+			unmet = append(unmet, "dropping ALL capabilities")
+		}
+
+		if len(unmet) > 0 {
+			findings = append(findings, Finding{
+				Container: container.Name,
+				Message:   fmt.Sprintf("pods with sensitive data (%s) require %s", e.sensitiveLabel, strings.Join(unmet, ", ")),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// dropsAllCapabilities reports whether the security context drops every
+// capability.
+func dropsAllCapabilities(sc *corev1.SecurityContext) bool {
+	if sc == nil || sc.Capabilities == nil {
+		return false
+	}
+	for _, capability := range sc.Capabilities.Drop {
+		if strings.EqualFold(string(capability), "ALL") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSecretEnv rejects containers that consume sensitive secrets, those
+// whose names match the secret env pattern, as environment variables
+// through secretKeyRef or envFrom. The environment is exposed to every
+// process in the container and often ends up in logs and crash reports, so
+// such secrets must be mounted as files instead.
+func (e *Engine) checkSecretEnv(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	for _, container := range allContainers(pod) {
+		for _, env := range container.Env {
+			if env.ValueFrom == nil || env.ValueFrom.SecretKeyRef == nil {
+				continue
+			}
+			if name := env.ValueFrom.SecretKeyRef.Name; e.secretEnvPattern.MatchString(name) {
+				findings = append(findings, Finding{
+					Container: container.Name,
+					Message:   fmt.Sprintf("env %s: sensitive secret %s must be mounted as a file, not consumed as an environment variable", env.Name, name),
+				})
+			}
+		}
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.SecretRef == nil {
+				continue
+			}
+			if name := envFrom.SecretRef.Name; e.secretEnvPattern.MatchString(name) {
+				findings = append(findings, Finding{
+					Container: container.Name,
+					Message:   fmt.Sprintf("envFrom: sensitive secret %s must be mounted as a file, not consumed as environment variables", name),
+				})
+			}
+		}
+	}
+	return findings, nil
+}
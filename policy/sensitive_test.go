@@ -0,0 +1,86 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCheckSensitiveDataSecurity(t *testing.T) {
+	hardened := &corev1.SecurityContext{
+		RunAsNonRoot:           boolPtr(true),
+		ReadOnlyRootFilesystem: boolPtr(true),
+		Capabilities:           &corev1.Capabilities{Drop: []corev1.Capability{"all"}},
+	}
+	sensitive := map[string]string{"data-classification": "pii"}
+	tests := []struct {
+		name            string
+		labels          map[string]string
+		securityContext *corev1.SecurityContext
+		want            []string
+	}{
+		{
+			name:            "hardened",
+			labels:          sensitive,
+			securityContext: hardened,
+		},
+		{
+			name:   "not hardened",
+			labels: sensitive,
+			want:   []string{"sensitive-data-security: container app: pods with sensitive data (data-classification=pii) require runAsNonRoot, readOnlyRootFilesystem, dropping ALL capabilities"},
+		},
+		{
+			name:            "capabilities kept",
+			labels:          sensitive,
+			securityContext: &corev1.SecurityContext{RunAsNonRoot: boolPtr(true), ReadOnlyRootFilesystem: boolPtr(true)},
+			want:            []string{"sensitive-data-security: container app: pods with sensitive data (data-classification=pii) require dropping ALL capabilities"},
+		},
+		{
+			name: "not sensitive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{SensitiveDataLabel: "data-classification=pii"}, nil)
+			pod := withMeta(testPod(corev1.PodSpec{Containers: []corev1.Container{{Name: "app", SecurityContext: tt.securityContext}}}), tt.labels, nil)
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestCheckSecretEnv(t *testing.T) {
+	tests := []struct {
+		name      string
+		container corev1.Container
+		want      []string
+	}{
+		{
+			name: "other secret",
+			container: corev1.Container{Name: "app", Env: []corev1.EnvVar{{Name: "FLAG", ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "feature-flags"}, Key: "flag"},
+			}}}},
+		},
+		{
+			name: "secretKeyRef",
+			container: corev1.Container{Name: "app", Env: []corev1.EnvVar{{Name: "DB_PASSWORD", ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "db-credentials"}, Key: "password"},
+			}}}},
+			want: []string{"secret-env: container app: env DB_PASSWORD: sensitive secret db-credentials must be mounted as a file, not consumed as an environment variable"},
+		},
+		{
+			name: "envFrom",
+			container: corev1.Container{Name: "app", EnvFrom: []corev1.EnvFromSource{{
+				SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "api-credentials"}},
+			}}},
+			want: []string{"secret-env: container app: envFrom: sensitive secret api-credentials must be mounted as a file, not consumed as environment variables"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{SecretEnvPattern: "credentials$"}, nil)
+			assertFindings(t, e, testPod(corev1.PodSpec{Containers: []corev1.Container{tt.container}}), RequestMeta{}, tt.want)
+		})
+	}
+}
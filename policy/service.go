@@ -0,0 +1,63 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// checkLoadBalancerAnnotations rejects LoadBalancer services that are
+// missing any of the required annotations, such as the cloud provider's
+// internal load balancer annotation, to prevent accidental public exposure.
+// Required annotations are either a key, which must be present, or a
+// key=value pair, which must be present with that value.
+func (e *Engine) checkLoadBalancerAnnotations(ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+	service := obj.(*corev1.Service)
+	if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+		return nil, nil
+	}
+
+	var missing []string
+	for _, required := range e.opts.LoadBalancerAnnotations {
+		parts := strings.SplitN(required, "=", 2)
+		actual, ok := service.Annotations[parts[0]]
+		if !ok || (len(parts) == 2 && actual != parts[1]) {
+			missing = append(missing, required)
+		}
+	}
+
+	if len(missing) > 0 {
+		return []Finding{{Message: fmt.Sprintf("LoadBalancer service is missing required annotations: %s", strings.Join(missing, ", "))}}, nil
+	}
+	return nil, nil
+}
+
+// checkServiceSelector warns about services whose selector doesn't use every
+// label that selectors are required to by convention, such as
+// app.kubernetes.io/name, since a misconfigured selector silently matches
+// no pods. Services without a selector, whose endpoints are managed some
+// other way, aren't checked.
+func (e *Engine) checkServiceSelector(ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+	service := obj.(*corev1.Service)
+	if len(service.Spec.Selector) == 0 {
+		return nil, nil
+	}
+
+	var missing []string
+	for _, label := range e.opts.ServiceSelectorLabels {
+		if _, ok := service.Spec.Selector[label]; !ok {
+			missing = append(missing, label)
+		}
+	}
+
+	if len(missing) > 0 {
+		return []Finding{{
+			Message: fmt.Sprintf("service selector should use the labels %s by convention", strings.Join(missing, ", ")),
+			Warning: true,
+		}}, nil
+	}
+	return nil, nil
+}
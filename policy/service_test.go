@@ -0,0 +1,79 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckLoadBalancerAnnotations(t *testing.T) {
+	required := []string{"cloud.example.com/internal=true", "cloud.example.com/subnet"}
+	tests := []struct {
+		name        string
+		serviceType corev1.ServiceType
+		annotations map[string]string
+		want        []string
+	}{
+		{
+			name:        "every annotation",
+			serviceType: corev1.ServiceTypeLoadBalancer,
+			annotations: map[string]string{"cloud.example.com/internal": "true", "cloud.example.com/subnet": "apps"},
+		},
+		{
+			name:        "wrong value",
+			serviceType: corev1.ServiceTypeLoadBalancer,
+			annotations: map[string]string{"cloud.example.com/internal": "false", "cloud.example.com/subnet": "apps"},
+			want:        []string{"load-balancer-annotations: LoadBalancer service is missing required annotations: cloud.example.com/internal=true"},
+		},
+		{
+			name:        "missing",
+			serviceType: corev1.ServiceTypeLoadBalancer,
+			want:        []string{"load-balancer-annotations: LoadBalancer service is missing required annotations: cloud.example.com/internal=true, cloud.example.com/subnet"},
+		},
+		{
+			name:        "cluster IP",
+			serviceType: corev1.ServiceTypeClusterIP,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{LoadBalancerAnnotations: required}, nil)
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations},
+				Spec:       corev1.ServiceSpec{Type: tt.serviceType},
+			}
+			assertFindings(t, e, service, RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestCheckServiceSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector map[string]string
+		want     []string
+	}{
+		{
+			name:     "conventional",
+			selector: map[string]string{"app.kubernetes.io/name": "web", "app.kubernetes.io/instance": "web-1"},
+		},
+		{
+			name:     "unconventional",
+			selector: map[string]string{"app": "web"},
+			want:     []string{"service-selector: warning: service selector should use the labels app.kubernetes.io/name, app.kubernetes.io/instance by convention"},
+		},
+		{
+			name: "no selector",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{ServiceSelectorLabels: []string{"app.kubernetes.io/name", "app.kubernetes.io/instance"}}, nil)
+			service := &corev1.Service{Spec: corev1.ServiceSpec{Selector: tt.selector}}
+			assertFindings(t, e, service, RequestMeta{}, tt.want)
+		})
+	}
+}
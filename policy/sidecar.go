@@ -0,0 +1,38 @@
+package policy
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// injectSidecarAnnotation opts pods in to having the configured sidecar
+// injected when it's set to true.
+const injectSidecarAnnotation = annotationPrefix + "inject-sidecar"
+
+// PatchOperation is a JSON patch (RFC 6902) operation.
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Mutate returns the JSON patch that makes the changes the engine's options
+// call for to the object, which is empty if there are none. With
+// InjectSidecar set, the configured sidecar is appended to the containers of
+// pods that opt in with the inject-sidecar annotation and don't already
+// have a container of the same name.
+func (e *Engine) Mutate(ctx context.Context, obj runtime.Object, meta RequestMeta) []PatchOperation {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || !e.opts.InjectSidecar || pod.Annotations[injectSidecarAnnotation] != "true" {
+		return nil
+	}
+
+	for _, container := range pod.Spec.Containers {
+		if container.Name == e.sidecar.Name {
+			return nil
+		}
+	}
+	return []PatchOperation{{Op: "add", Path: "/spec/containers/-", Value: e.sidecar}}
+}
@@ -0,0 +1,56 @@
+package policy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestMutate(t *testing.T) {
+	sidecar := &corev1.Container{Name: "proxy", Image: "proxy:v1"}
+	optIn := map[string]string{injectSidecarAnnotation: "true"}
+
+	tests := []struct {
+		name        string
+		inject      bool
+		annotations map[string]string
+		containers  []corev1.Container
+		want        []PatchOperation
+	}{
+		{
+			name:        "opted in",
+			inject:      true,
+			annotations: optIn,
+			containers:  []corev1.Container{{Name: "app"}},
+			want:        []PatchOperation{{Op: "add", Path: "/spec/containers/-", Value: sidecar}},
+		},
+		{
+			name:       "not opted in",
+			inject:     true,
+			containers: []corev1.Container{{Name: "app"}},
+		},
+		{
+			name:        "already has sidecar",
+			inject:      true,
+			annotations: optIn,
+			containers:  []corev1.Container{{Name: "app"}, {Name: "proxy"}},
+		},
+		{
+			name:        "injection disabled",
+			annotations: optIn,
+			containers:  []corev1.Container{{Name: "app"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{InjectSidecar: tt.inject}, &Config{Sidecar: sidecar})
+			pod := withMeta(testPod(corev1.PodSpec{Containers: tt.containers}), nil, tt.annotations)
+			if got := e.Mutate(context.Background(), pod, RequestMeta{}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Mutate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// checkObjectSize rejects objects of any resource that are bigger than the
+// configured maximum, since very large objects strain etcd and everything
+// that watches them.
+func (e *Engine) checkObjectSize(ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+	size := meta.ObjectSize
+	if size == 0 {
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding object: %w", err)
+		}
+		size = len(data)
+	}
+
+	if int64(size) > e.opts.MaxObjectSize {
+		return []Finding{{Message: fmt.Sprintf("object is %d bytes, which exceeds the maximum object size of %d bytes", size, e.opts.MaxObjectSize)}}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,37 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCheckObjectSize(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		want []string
+	}{
+		{
+			name: "under max",
+			size: 1024,
+		},
+		{
+			name: "over max",
+			size: 4096,
+			want: []string{"object-size: object is 4096 bytes, which exceeds the maximum object size of 2048 bytes"},
+		},
+		{
+			// Without the size of the request, the object is encoded to
+			// measure it, and the test pod is much smaller than the max.
+			name: "encoded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{MaxObjectSize: 2048}, nil)
+			assertFindings(t, e, testPod(corev1.PodSpec{}), RequestMeta{ObjectSize: tt.size}, tt.want)
+		})
+	}
+}
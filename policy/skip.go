@@ -0,0 +1,48 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// skipLabel is a label that exempts the objects carrying it from
+// validation.
+type skipLabel struct {
+	key   string
+	value string
+}
+
+// parseSkipLabels parses labels given as key=value.
+func parseSkipLabels(values []string) ([]skipLabel, error) {
+	var skipLabels []skipLabel
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid skip label %q: must be key=value", value)
+		}
+		skipLabels = append(skipLabels, skipLabel{key: parts[0], value: parts[1]})
+	}
+	return skipLabels, nil
+}
+
+// skipped reports whether the object carries any of the skip labels, like a
+// webhook's objectSelector but enforced by the engine.
+func (e *Engine) skipped(obj runtime.Object) bool {
+	if len(e.skipLabels) == 0 {
+		return false
+	}
+	accessor, err := apimeta.Accessor(obj)
+	if err != nil {
+		return false
+	}
+	objLabels := accessor.GetLabels()
+	for _, l := range e.skipLabels {
+		if value, ok := objLabels[l.key]; ok && value == l.value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// keyValue is a label or taint given as key=value, or just key to match any
+// value.
+type keyValue struct {
+	key   string
+	value string
+	// anyValue is set when only the key was given.
+	anyValue bool
+}
+
+func (kv keyValue) String() string {
+	if kv.anyValue {
+		return kv.key
+	}
+	return kv.key + "=" + kv.value
+}
+
+// parseKeyValues parses values given as key=value or key.
+func parseKeyValues(values []string) ([]keyValue, error) {
+	var kvs []keyValue
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if parts[0] == "" {
+			return nil, fmt.Errorf("invalid %q: must be key=value or key", value)
+		}
+		kv := keyValue{key: parts[0], anyValue: len(parts) == 1}
+		if !kv.anyValue {
+			kv.value = parts[1]
+		}
+		kvs = append(kvs, kv)
+	}
+	return kvs, nil
+}
+
+// matches reports whether the key and value match.
+func (kv keyValue) matches(key, value string) bool {
+	return kv.key == key && (kv.anyValue || kv.value == value)
+}
+
+// checkStatefulOnSpot rejects stateful pods, those with the stateful label,
+// that tolerate the taints of spot or preemptible nodes or select their
+// labels, since having the node reclaimed would disrupt the workload.
+func (e *Engine) checkStatefulOnSpot(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	value, ok := pod.Labels[e.statefulLabel.key]
+	if !ok || !e.statefulLabel.matches(e.statefulLabel.key, value) {
+		return nil, nil
+	}
+
+	var findings []Finding
+	report := func(msg string) {
+		findings = append(findings, Finding{Message: fmt.Sprintf("stateful pod (%s) must not run on spot nodes, but %s", e.statefulLabel, msg)})
+	}
+
+	for _, toleration := range pod.Spec.Tolerations {
+		for _, taint := range e.spotTaints {
+			// A toleration without a key, with the Exists operator,
+			// tolerates every taint.
+			if (toleration.Key == "" && toleration.Operator == corev1.TolerationOpExists) ||
+				(toleration.Key == taint.key && (toleration.Operator == corev1.TolerationOpExists || taint.anyValue || toleration.Value == taint.value)) {
+				report(fmt.Sprintf("it tolerates spot node taint %s", taint))
+			}
+		}
+	}
+
+	for key, value := range pod.Spec.NodeSelector {
+		for _, label := range e.spotNodeLabels {
+			if label.matches(key, value) {
+				report(fmt.Sprintf("its nodeSelector selects spot node label %s", label))
+			}
+		}
+	}
+
+	if affinity := pod.Spec.Affinity; affinity != nil && affinity.NodeAffinity != nil {
+		var terms []corev1.NodeSelectorTerm
+		if required := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution; required != nil {
+			terms = append(terms, required.NodeSelectorTerms...)
+		}
+		for _, preferred := range affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+			terms = append(terms, preferred.Preference)
+		}
+
+		for _, term := range terms {
+			for _, expr := range term.MatchExpressions {
+				for _, label := range e.spotNodeLabels {
+					if expr.Key == label.key && selectsNodeLabel(expr, label) {
+						report(fmt.Sprintf("its node affinity selects spot node label %s", label))
+					}
+				}
+			}
+		}
+	}
+	return findings, nil
+}
+
+// selectsNodeLabel reports whether the node selector requirement, for the
+// key of the node label, selects nodes with the label.
+func selectsNodeLabel(expr corev1.NodeSelectorRequirement, label keyValue) bool {
+	switch expr.Operator {
+	case corev1.NodeSelectorOpExists:
+		return true
+	case corev1.NodeSelectorOpIn:
+		if label.anyValue {
+			return true
+		}
+		return contains(expr.Values, label.value)
+	}
+	return false
+}
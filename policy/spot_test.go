@@ -0,0 +1,87 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCheckStatefulOnSpot(t *testing.T) {
+	opts := Options{
+		StatefulLabel:  "workload=stateful",
+		SpotNodeTaints: []string{"spot=true"},
+		SpotNodeLabels: []string{"node-pool=spot"},
+	}
+	stateful := map[string]string{"workload": "stateful"}
+	tests := []struct {
+		name   string
+		labels map[string]string
+		spec   corev1.PodSpec
+		want   []string
+	}{
+		{
+			name:   "stateful on regular nodes",
+			labels: stateful,
+			spec:   corev1.PodSpec{NodeSelector: map[string]string{"node-pool": "general"}},
+		},
+		{
+			name:   "tolerates spot taint",
+			labels: stateful,
+			spec:   corev1.PodSpec{Tolerations: []corev1.Toleration{{Key: "spot", Operator: corev1.TolerationOpEqual, Value: "true"}}},
+			want:   []string{"stateful-on-spot: stateful pod (workload=stateful) must not run on spot nodes, but it tolerates spot node taint spot=true"},
+		},
+		{
+			name:   "tolerates every taint",
+			labels: stateful,
+			spec:   corev1.PodSpec{Tolerations: []corev1.Toleration{{Operator: corev1.TolerationOpExists}}},
+			want:   []string{"stateful-on-spot: stateful pod (workload=stateful) must not run on spot nodes, but it tolerates spot node taint spot=true"},
+		},
+		{
+			name:   "selects spot label",
+			labels: stateful,
+			spec:   corev1.PodSpec{NodeSelector: map[string]string{"node-pool": "spot"}},
+			want:   []string{"stateful-on-spot: stateful pod (workload=stateful) must not run on spot nodes, but its nodeSelector selects spot node label node-pool=spot"},
+		},
+		{
+			name:   "node affinity",
+			labels: stateful,
+			spec: corev1.PodSpec{Affinity: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{{
+					Preference: corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "node-pool", Operator: corev1.NodeSelectorOpIn, Values: []string{"spot"}}}},
+				}},
+			}}},
+			want: []string{"stateful-on-spot: stateful pod (workload=stateful) must not run on spot nodes, but its node affinity selects spot node label node-pool=spot"},
+		},
+		{
+			name: "stateless on spot",
+			spec: corev1.PodSpec{NodeSelector: map[string]string{"node-pool": "spot"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, opts, nil)
+			assertFindings(t, e, withMeta(testPod(tt.spec), tt.labels, nil), RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestParseKeyValues(t *testing.T) {
+	kvs, err := parseKeyValues([]string{"a=b", "c", "d="})
+	if err != nil {
+		t.Fatalf("parseKeyValues() error = %v", err)
+	}
+	want := []keyValue{{key: "a", value: "b"}, {key: "c", anyValue: true}, {key: "d"}}
+	if len(kvs) != len(want) {
+		t.Fatalf("parseKeyValues() = %v, want %v", kvs, want)
+	}
+	for i := range want {
+		if kvs[i] != want[i] {
+			t.Errorf("parseKeyValues()[%d] = %+v, want %+v", i, kvs[i], want[i])
+		}
+	}
+
+	if _, err := parseKeyValues([]string{"=b"}); err == nil {
+		t.Error("parseKeyValues() with no key succeeded, want error")
+	}
+}
@@ -0,0 +1,35 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// checkPodManagementPolicy warns about statefulsets whose
+// podManagementPolicy doesn't fit their parallel startup label: those
+// labeled true can start their pods in parallel, so OrderedReady only slows
+// down scaling, and those labeled false need their pods started in order,
+// so Parallel can break them. Statefulsets without the label aren't checked.
+func (e *Engine) checkPodManagementPolicy(ctx context.Context, obj runtime.Object, meta RequestMeta) ([]Finding, error) {
+	statefulSet := obj.(*appsv1.StatefulSet)
+	value, ok := statefulSet.Labels[e.opts.ParallelStartupLabel]
+	if !ok {
+		return nil, nil
+	}
+
+	policy := statefulSet.Spec.PodManagementPolicy
+	if policy == "" {
+		policy = appsv1.OrderedReadyPodManagement
+	}
+
+	switch {
+	case value == "true" && policy == appsv1.OrderedReadyPodManagement:
+		return []Finding{{Message: fmt.Sprintf("statefulset is labeled %s=true but uses podManagementPolicy %s; Parallel would scale it faster", e.opts.ParallelStartupLabel, policy), Warning: true}}, nil
+	case value == "false" && policy == appsv1.ParallelPodManagement:
+		return []Finding{{Message: fmt.Sprintf("statefulset is labeled %s=false but uses podManagementPolicy %s; OrderedReady would start its pods in order", e.opts.ParallelStartupLabel, policy), Warning: true}}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,49 @@
+package policy
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckPodManagementPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels map[string]string
+		policy appsv1.PodManagementPolicyType
+		want   []string
+	}{
+		{
+			name:   "parallel startup with parallel policy",
+			labels: map[string]string{"parallel-startup": "true"},
+			policy: appsv1.ParallelPodManagement,
+		},
+		{
+			name:   "parallel startup with default policy",
+			labels: map[string]string{"parallel-startup": "true"},
+			want:   []string{"pod-management-policy: warning: statefulset is labeled parallel-startup=true but uses podManagementPolicy OrderedReady; Parallel would scale it faster"},
+		},
+		{
+			name:   "ordered startup with parallel policy",
+			labels: map[string]string{"parallel-startup": "false"},
+			policy: appsv1.ParallelPodManagement,
+			want:   []string{"pod-management-policy: warning: statefulset is labeled parallel-startup=false but uses podManagementPolicy Parallel; OrderedReady would start its pods in order"},
+		},
+		{
+			name:   "unlabeled",
+			policy: appsv1.ParallelPodManagement,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{ParallelStartupLabel: "parallel-startup"}, nil)
+			statefulSet := &appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Labels: tt.labels},
+				Spec:       appsv1.StatefulSetSpec{PodManagementPolicy: tt.policy},
+			}
+			assertFindings(t, e, statefulSet, RequestMeta{}, tt.want)
+		})
+	}
+}
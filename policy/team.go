@@ -0,0 +1,21 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// checkTeamLabel rejects pods that don't carry the team label, or whose team
+// isn't in the current team allowlist.
+func (e *Engine) checkTeamLabel(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	team, ok := pod.Labels[e.opts.TeamLabel]
+	switch {
+	case !ok:
+		return []Finding{{Message: fmt.Sprintf("missing required %s label", e.opts.TeamLabel)}}, nil
+	case !e.opts.TeamAllowlist.Allowed(team):
+		return []Finding{{Message: fmt.Sprintf("%s label value %s is not an allowed team", e.opts.TeamLabel, team)}}, nil
+	}
+	return nil, nil
+}
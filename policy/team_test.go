@@ -0,0 +1,64 @@
+package policy
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCheckTeamLabel(t *testing.T) {
+	allowlist := NewAllowlist([]string{"payments"})
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   []string
+	}{
+		{
+			name:   "allowed",
+			labels: map[string]string{"team": "payments"},
+		},
+		{
+			name:   "not allowed",
+			labels: map[string]string{"team": "search"},
+			want:   []string{"team-allowlist: team label value search is not an allowed team"},
+		},
+		{
+			name: "missing",
+			want: []string{"team-allowlist: missing required team label"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{TeamLabel: "team", TeamAllowlist: allowlist}, nil)
+			assertFindings(t, e, withMeta(testPod(corev1.PodSpec{}), tt.labels, nil), RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestAllowlistSet(t *testing.T) {
+	allowlist := NewAllowlist([]string{"payments"})
+	e := newTestEngine(t, Options{TeamLabel: "team", TeamAllowlist: allowlist}, nil)
+	pod := withMeta(testPod(corev1.PodSpec{}), map[string]string{"team": "search"}, nil)
+	assertFindings(t, e, pod, RequestMeta{}, []string{"team-allowlist: team label value search is not an allowed team"})
+
+	allowlist.Set([]string{"payments", "search"})
+	assertFindings(t, e, pod, RequestMeta{}, nil)
+}
+
+func TestReadAllowlistFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "teams")
+	if err := ioutil.WriteFile(path, []byte("# teams\npayments\n\n  search  \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	values, err := ReadAllowlistFile(path)
+	if err != nil {
+		t.Fatalf("ReadAllowlistFile() error = %v", err)
+	}
+	if want := []string{"payments", "search"}; !reflect.DeepEqual(values, want) {
+		t.Errorf("ReadAllowlistFile() = %q, want %q", values, want)
+	}
+}
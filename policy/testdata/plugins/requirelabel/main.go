@@ -0,0 +1,30 @@
+// Command requirelabel is a validator plugin, built with
+// -buildmode=plugin, that rejects pods without an app label.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Validate rejects pods without an app label.
+func Validate(resource string, object []byte) (string, error) {
+	if resource != "v1/pods" {
+		return "", nil
+	}
+
+	var pod struct {
+		Metadata struct {
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(object, &pod); err != nil {
+		return "", fmt.Errorf("error decoding pod: %w", err)
+	}
+	if pod.Metadata.Labels["app"] == "" {
+		return "missing required app label", nil
+	}
+	return "", nil
+}
+
+func main() {}
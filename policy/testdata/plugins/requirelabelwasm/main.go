@@ -0,0 +1,73 @@
+// Command requirelabelwasm is the requirelabel plugin as a WebAssembly
+// plugin, built with GOOS=wasip1 GOARCH=wasm -buildmode=c-shared, that
+// rejects pods without an app label.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"unsafe"
+)
+
+// buffers keeps the buffers returned by allocate until validate is done
+// with them, so that they aren't garbage collected.
+var buffers = map[uintptr][]byte{}
+
+// result keeps the last result until the next call.
+var result []byte
+
+//go:wasmexport allocate
+func allocate(size uint32) uint32 {
+	// The buffer is never empty, so that it has an address.
+	buf := make([]byte, size+1)
+	ptr := uintptr(unsafe.Pointer(&buf[0]))
+	buffers[ptr] = buf
+	return uint32(ptr)
+}
+
+//go:wasmexport validate
+func validate(resourcePtr, resourceLen, objectPtr, objectLen uint32) uint64 {
+	resource := string(takeBuffer(resourcePtr, resourceLen))
+	object := takeBuffer(objectPtr, objectLen)
+
+	var res struct {
+		Message string `json:"message,omitempty"`
+		Error   string `json:"error,omitempty"`
+	}
+	msg, err := Validate(resource, object)
+	res.Message = msg
+	if err != nil {
+		res.Error = err.Error()
+	}
+	result, _ = json.Marshal(res)
+	return uint64(uintptr(unsafe.Pointer(&result[0])))<<32 | uint64(len(result))
+}
+
+// takeBuffer returns the first n bytes of the buffer at ptr and forgets it.
+func takeBuffer(ptr, n uint32) []byte {
+	buf := buffers[uintptr(ptr)]
+	delete(buffers, uintptr(ptr))
+	return buf[:n]
+}
+
+// Validate rejects pods without an app label.
+func Validate(resource string, object []byte) (string, error) {
+	if resource != "v1/pods" {
+		return "", nil
+	}
+
+	var pod struct {
+		Metadata struct {
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(object, &pod); err != nil {
+		return "", fmt.Errorf("error decoding pod: %w", err)
+	}
+	if pod.Metadata.Labels["app"] == "" {
+		return "missing required app label", nil
+	}
+	return "", nil
+}
+
+func main() {}
@@ -0,0 +1,138 @@
+package policy
+
+import (
+	"strings"
+)
+
+// Violation describes a finding in a form that tooling can parse and
+// display, rather than only the message returned to the user.
+type Violation struct {
+	// Code identifies the kind of violation. It is stable for each rule.
+	Code string `json:"code"`
+	// Rule is the name of the rule that found the violation.
+	Rule string `json:"rule"`
+	// Field is the path of the field in the object that is in violation.
+	Field string `json:"field,omitempty"`
+	// Message describes the violation.
+	Message string `json:"message"`
+	// Remediation is a hint about how to fix the object.
+	Remediation string `json:"remediation,omitempty"`
+	// Warning is true if the violation didn't cause the object to be rejected.
+	Warning bool `json:"warning,omitempty"`
+	// DocURL links to documentation on fixing the violation.
+	DocURL string `json:"docURL,omitempty"`
+}
+
+// ruleDoc is what's known about the violations a rule finds: the field it
+// checks, relative to the container for findings about a container, and how
+// to fix violations. Rules without a doc still get violations, just without
+// a field or remediation.
+type ruleDoc struct {
+	field       string
+	remediation string
+}
+
+var ruleDocs = map[string]ruleDoc{
+	"hello-label":               {"metadata.labels.hello", "add a hello label to the pod"},
+	"probe-timings":             {"", "make each probe's timeoutSeconds less than its periodSeconds and raise initialDelaySeconds to the minimum"},
+	"name-convention":           {"metadata.name", "rename the pod to match the naming convention for its labels"},
+	"emptydir-size-limit":       {"spec.volumes", "set a sizeLimit within the maximum on every emptyDir volume"},
+	"privilege-escalation":      {"securityContext.allowPrivilegeEscalation", "set securityContext.allowPrivilegeEscalation to false"},
+	"apparmor-profile":          {"metadata.annotations", "annotate the pod with a confined AppArmor profile, such as runtime/default, for every container"},
+	"read-only-root-filesystem": {"securityContext.readOnlyRootFilesystem", "set securityContext.readOnlyRootFilesystem to true and mount volumes for paths that must be writable"},
+	"pull-secrets-exist":        {"spec.imagePullSecrets", "create the pull secret in the pod's namespace or remove the reference to it"},
+	"team-allowlist":            {"metadata.labels", "set the team label to one of the allowed teams"},
+	"anti-affinity":             {"spec.affinity.podAntiAffinity", "add a podAntiAffinity term that spreads the pods across nodes"},
+	"limit-consistency":         {"resources.limits", "check the limit for a typo, such as 10Gi instead of 1Gi"},
+	"namespace-environment":     {"metadata.labels", "set the pod's environment label to match its namespace's"},
+	"max-env-vars":              {"env", "move configuration into a ConfigMap mounted as a file"},
+	"host-pid":                  {"spec.hostPID", "remove hostPID from the pod"},
+	"host-ipc":                  {"spec.hostIPC", "remove hostIPC from the pod"},
+	"security-context":          {"securityContext", "set a securityContext with runAsNonRoot, allowPrivilegeEscalation: false, readOnlyRootFilesystem and capabilities.drop: [ALL]"},
+	"label-format":              {"metadata.labels", "set the label to a value in the required format"},
+	"required-volumes":          {"spec.volumes", "add the required volume to the pod and mount it in every container"},
+	"revision-history-limit":    {"spec.revisionHistoryLimit", "set spec.revisionHistoryLimit within the maximum"},
+	"network-policy-permissive": {"spec", "limit the policy's ingress and egress rules to the traffic that's needed"},
+	"load-balancer-annotations": {"metadata.annotations", "add the required annotations, such as the internal load balancer annotation"},
+	"unresolved-placeholders":   {"", "render the manifest's templates before applying it, or fix the reference to the value"},
+	"pvc-storage-bounds":        {"spec.resources.requests.storage", "request an amount of storage within the allowed bounds"},
+	"image-size":                {"image", "use a smaller base image, or remove build tools and caches from the image"},
+	"scheduler-name":            {"spec.schedulerName", "set spec.schedulerName to one of the allowed schedulers"},
+	"ingress-tls":               {"spec.tls", "add a spec.tls entry with a certificate for the host"},
+	"priority-class-tier":       {"spec.priorityClassName", "use a priority class allowed for the namespace's tier"},
+	"root-image-user":           {"securityContext.runAsNonRoot", "set USER to a non-root user in the image, or set securityContext.runAsNonRoot or a non-root runAsUser"},
+	"service-selector":          {"spec.selector", "select pods by the conventional labels, such as app.kubernetes.io/name"},
+	"job-ttl":                   {"spec.ttlSecondsAfterFinished", "set spec.ttlSecondsAfterFinished so the finished job is cleaned up"},
+	"cronjob-ttl":               {"spec.jobTemplate.spec.ttlSecondsAfterFinished", "set spec.jobTemplate.spec.ttlSecondsAfterFinished so finished jobs are cleaned up"},
+	"id-ranges":                 {"securityContext", "use a user and group ID within the ranges allowed for the namespace"},
+	"container-names":           {"", "give every container a unique name that follows the naming convention"},
+	"env-refs-exist":            {"env", "create the config map or secret key in the pod's namespace, or mark the reference optional"},
+	"stateful-on-spot":          {"spec", "remove the tolerations, nodeSelector and node affinity that target spot nodes"},
+	"hpa-replicas":              {"spec", "set minReplicas to at least 1 and no more than maxReplicas, within the cluster maximum"},
+	"sensitive-data-security":   {"spec.containers[].securityContext", "set runAsNonRoot, readOnlyRootFilesystem and drop ALL capabilities on every container"},
+	"deployment-strategy":       {"spec.strategy", "use the RollingUpdate strategy with maxUnavailable within the maximum"},
+	"object-size":               {"metadata", "reduce the size of the object, for example by moving large data into a ConfigMap or volume"},
+	"expensive-liveness-exec":   {"spec.containers[].livenessProbe.exec", "use an httpGet or tcpSocket liveness probe, or a lightweight exec command"},
+	"quota-bypass":              {"metadata.annotations", "remove the annotation, or run the pod in a namespace that doesn't enforce quotas"},
+	"managed-by":                {"metadata.labels", "create the object with one of the allowed tools, such as through GitOps, which sets the app.kubernetes.io/managed-by label"},
+	"probe-ports":               {"ports", "declare the probe's port in the container's ports, or point the probe at a declared port"},
+	"freeze-window":             {"metadata.namespace", "wait until the maintenance window ends, or annotate the pod as a break-glass change for an emergency"},
+	"progress-deadline":         {"spec.progressDeadlineSeconds", "set spec.progressDeadlineSeconds to how long a rollout should take, within the maximum"},
+	"max-volume-mounts":         {"volumeMounts", "mount fewer volumes, for example by combining ConfigMaps and Secrets into a projected volume"},
+	"pod-management-policy":     {"spec.podManagementPolicy", "set spec.podManagementPolicy to match whether the pods can start in parallel"},
+	"mutable-tags":              {"image", "pin the image to a version tag or digest"},
+	"deprecated-annotations":    {"metadata.annotations", "replace the annotation with the field that superseded it"},
+	"image-pull-policy":         {"imagePullPolicy", "set imagePullPolicy to Always, IfNotPresent or Never"},
+	"metadata-injection":        {"metadata", "remove newlines and shell metacharacters from the label or annotation value"},
+	"pod-exec":                  {"", "debug with logs, metrics or an ephemeral copy of the pod in a non-production namespace"},
+	"pod-attach":                {"", "debug with logs, metrics or an ephemeral copy of the pod in a non-production namespace"},
+	"overlapping-mounts":        {"volumeMounts", "mount the volumes at separate paths, or use subPath to combine them deliberately"},
+	"requester":                 {"", "make the change through one of the allowed users, such as the CI pipeline's service account"},
+	"hugepages":                 {"resources", "set each huge pages limit equal to its request, and schedule the pod onto nodes with huge pages"},
+	"qos-class":                 {"resources", "set CPU and memory limits with requests equal to them on every container, or change the declared QoS class"},
+	"capabilities-add":          {"securityContext.capabilities.add", "remove securityContext.capabilities.add, or run the pod in a namespace that isn't restricted"},
+	"immutable-fields":          {"", "revert the change to the field, or delete and recreate the object if the change is intended"},
+	"emptydir-storage-limits":   {"resources.limits.ephemeral-storage", "set an ephemeral-storage limit on every container that mounts an emptyDir volume"},
+	"ingress-class":             {"spec.ingressClassName", "set spec.ingressClassName to one of the allowed ingress classes, and remove the legacy kubernetes.io/ingress.class annotation"},
+	"gpu-namespace-qos":         {"resources", "set CPU and memory limits with requests equal to them on every container"},
+	"configmap-owners":          {"metadata.labels", "set the same owner label on the pod and the config maps it uses, or use config maps owned by the pod's team"},
+	"decision-default":          {"", "retry once the webhook can evaluate its rules again, for example once it can reach the API server"},
+	"topology-spread":           {"spec.topologySpreadConstraints", "remove constraints down to the maximum, and use whenUnsatisfiable: ScheduleAnyway for those that are preferences"},
+	"secret-env":                {"env", "mount the secret as a volume and read it from a file instead of the environment"},
+	"pipeline-timeout":          {"", "retry the request, and check the webhook's rule latency metrics for the rule that is slow"},
+	"default-deny":              {"", "change the object so that an allow rule matches it, or ask the policy's owners for a new allow rule"},
+}
+
+// Violations returns the findings as violations, in the same order.
+func Violations(findings []Finding) []Violation {
+	violations := make([]Violation, 0, len(findings))
+	for _, f := range findings {
+		doc := ruleDocs[f.Rule]
+		field := doc.field
+		if f.Container != "" && field != "" && !strings.HasPrefix(field, "spec.") && !strings.HasPrefix(field, "metadata.") {
+			field = "spec.containers[" + f.Container + "]." + field
+		}
+		remediation := doc.remediation
+		if f.Remediation != "" {
+			remediation = f.Remediation
+		}
+		v := Violation{
+			Code:        violationCode(f.Rule),
+			Rule:        f.Rule,
+			Field:       field,
+			Remediation: remediation,
+			Warning:     f.Warning,
+			DocURL:      f.DocURL,
+		}
+		f.DocURL, f.Remediation = "", ""
+		v.Message = f.String()
+		violations = append(violations, v)
+	}
+	return violations
+}
+
+// violationCode returns the code for violations found by the rule, e.g.
+// HOST_PID for host-pid.
+func violationCode(rule string) string {
+	return strings.ToUpper(strings.ReplaceAll(rule, "-", "_"))
+}
@@ -0,0 +1,22 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestViolations(t *testing.T) {
+	findings := []Finding{
+		{Rule: "host-pid", Message: "must not use the host PID namespace (hostPID)"},
+		{Rule: "privilege-escalation", Container: "app", Message: "allowPrivilegeEscalation must be false", Warning: true, DocURL: "https://docs.example.com"},
+		{Rule: "custom", Message: "something"},
+	}
+	want := []Violation{
+		{Code: "HOST_PID", Rule: "host-pid", Field: "spec.hostPID", Message: "must not use the host PID namespace (hostPID)", Remediation: "remove hostPID from the pod"},
+		{Code: "PRIVILEGE_ESCALATION", Rule: "privilege-escalation", Field: "spec.containers[app].securityContext.allowPrivilegeEscalation", Message: "container app: allowPrivilegeEscalation must be false", Remediation: "set securityContext.allowPrivilegeEscalation to false", Warning: true, DocURL: "https://docs.example.com"},
+		{Code: "CUSTOM", Rule: "custom", Message: "something"},
+	}
+	if got := Violations(findings); !reflect.DeepEqual(got, want) {
+		t.Errorf("Violations() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,155 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// checkEmptyDirSizeLimit bounds the node disk that emptyDir volumes can use,
+// by rejecting emptyDirs with a sizeLimit over the configured maximum, or
+// without a sizeLimit when one is required.
+func (e *Engine) checkEmptyDirSizeLimit(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	for _, volume := range pod.Spec.Volumes {
+		if volume.EmptyDir == nil {
+			continue
+		}
+
+		sizeLimit := volume.EmptyDir.SizeLimit
+		if sizeLimit == nil {
+			if e.opts.RequireEmptyDirSizeLimit {
+				findings = append(findings, Finding{
+					Message: fmt.Sprintf("emptyDir volume %s must set a sizeLimit", volume.Name),
+				})
+			}
+			continue
+		}
+
+		if e.maxEmptyDirSize != nil && sizeLimit.Cmp(*e.maxEmptyDirSize) > 0 {
+			findings = append(findings, Finding{
+				Message: fmt.Sprintf("emptyDir volume %s sizeLimit (%s) exceeds the maximum of %s", volume.Name, sizeLimit.String(), e.maxEmptyDirSize.String()),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// checkEmptyDirStorageLimits rejects containers that mount a disk-backed
+// emptyDir volume without an ephemeral-storage limit, since nothing else
+// stops them from filling the node's disk. Memory-backed emptyDirs count
+// against the memory limit instead, so they aren't checked.
+func (e *Engine) checkEmptyDirStorageLimits(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	diskEmptyDirs := map[string]bool{}
+	for _, volume := range pod.Spec.Volumes {
+		if volume.EmptyDir != nil && volume.EmptyDir.Medium != corev1.StorageMediumMemory {
+			diskEmptyDirs[volume.Name] = true
+		}
+	}
+	if len(diskEmptyDirs) == 0 {
+		return nil, nil
+	}
+
+	var findings []Finding
+	for _, container := range allContainers(pod) {
+		if _, ok := container.Resources.Limits[corev1.ResourceEphemeralStorage]; ok {
+			continue
+		}
+		for _, mount := range container.VolumeMounts {
+			if diskEmptyDirs[mount.Name] {
+				findings = append(findings, Finding{
+					Container: container.Name,
+					Message:   fmt.Sprintf("mounts emptyDir volume %s without an ephemeral-storage limit", mount.Name),
+				})
+			}
+		}
+	}
+	return findings, nil
+}
+
+// RequiredVolume is a volume that every pod must have, such as a CA bundle
+// or logging config that the platform relies on.
+type RequiredVolume struct {
+	// Name is the name of the volume.
+	Name string `json:"name"`
+	// MountPath, if set, is where every container must mount the volume.
+	MountPath string `json:"mountPath,omitempty"`
+}
+
+// checkRequiredVolumes rejects pods that don't have every required volume,
+// or that have containers which don't mount it where it's required to be.
+func (e *Engine) checkRequiredVolumes(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	volumes := map[string]bool{}
+	for _, volume := range pod.Spec.Volumes {
+		volumes[volume.Name] = true
+	}
+
+	var findings []Finding
+	for _, required := range e.requiredVolumes {
+		if !volumes[required.Name] {
+			findings = append(findings, Finding{
+				Message: fmt.Sprintf("required volume %s is missing", required.Name),
+			})
+			continue
+		}
+		if required.MountPath == "" {
+			continue
+		}
+
+		for _, container := range pod.Spec.Containers {
+			mounted := false
+			for _, mount := range container.VolumeMounts {
+				if mount.Name == required.Name && mount.MountPath == required.MountPath {
+					mounted = true
+					break
+				}
+			}
+			if !mounted {
+				findings = append(findings, Finding{
+					Container: container.Name,
+					Message:   fmt.Sprintf("must mount required volume %s at %s", required.Name, required.MountPath),
+				})
+			}
+		}
+	}
+	return findings, nil
+}
+
+// checkOverlappingMounts warns about containers with a volume mounted at or
+// inside another of their mounts, where one mount shadows part of the
+// other.
+func (e *Engine) checkOverlappingMounts(ctx context.Context, pod *corev1.Pod, meta RequestMeta) ([]Finding, error) {
+	var findings []Finding
+	for _, container := range allContainers(pod) {
+		mounts := container.VolumeMounts
+		for i := 0; i < len(mounts); i++ {
+			for j := i + 1; j < len(mounts); j++ {
+				if !mountPathsOverlap(mounts[i].MountPath, mounts[j].MountPath) {
+					continue
+				}
+				findings = append(findings, Finding{
+					Container: container.Name,
+					Message:   fmt.Sprintf("volumeMounts %s (%s) and %s (%s) overlap, so one shadows part of the other", mounts[i].Name, mounts[i].MountPath, mounts[j].Name, mounts[j].MountPath),
+					Warning:   true,
+				})
+			}
+		}
+	}
+	return findings, nil
+}
+
+// mountPathsOverlap reports whether the paths are the same, or one is
+// inside the other.
+func mountPathsOverlap(a, b string) bool {
+	a, b = path.Clean(a), path.Clean(b)
+	if a == b {
+		return true
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	return strings.HasPrefix(b, strings.TrimSuffix(a, "/")+"/")
+}
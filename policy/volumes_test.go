@@ -0,0 +1,169 @@
+package policy
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func quantityPtr(s string) *resource.Quantity {
+	q := resource.MustParse(s)
+	return &q
+}
+
+func TestCheckEmptyDirSizeLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		emptyDir *corev1.EmptyDirVolumeSource
+		want     []string
+	}{
+		{
+			name:     "under max",
+			opts:     Options{MaxEmptyDirSize: "1Gi"},
+			emptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: quantityPtr("512Mi")},
+		},
+		{
+			name:     "at max",
+			opts:     Options{MaxEmptyDirSize: "1Gi"},
+			emptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: quantityPtr("1Gi")},
+		},
+		{
+			name:     "over max",
+			opts:     Options{MaxEmptyDirSize: "1Gi"},
+			emptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: quantityPtr("2Gi")},
+			want:     []string{"emptydir-size-limit: emptyDir volume scratch sizeLimit (2Gi) exceeds the maximum of 1Gi"},
+		},
+		{
+			name:     "no size limit",
+			opts:     Options{MaxEmptyDirSize: "1Gi"},
+			emptyDir: &corev1.EmptyDirVolumeSource{},
+		},
+		{
+			name:     "no size limit when required",
+			opts:     Options{RequireEmptyDirSizeLimit: true},
+			emptyDir: &corev1.EmptyDirVolumeSource{},
+			want:     []string{"emptydir-size-limit: emptyDir volume scratch must set a sizeLimit"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, tt.opts, nil)
+			pod := testPod(corev1.PodSpec{Volumes: []corev1.Volume{{Name: "scratch", VolumeSource: corev1.VolumeSource{EmptyDir: tt.emptyDir}}}})
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestCheckEmptyDirStorageLimits(t *testing.T) {
+	limited := corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceEphemeralStorage: resource.MustParse("1Gi")}}
+	tests := []struct {
+		name      string
+		medium    corev1.StorageMedium
+		resources corev1.ResourceRequirements
+		want      []string
+	}{
+		{
+			name: "disk without limit",
+			want: []string{"emptydir-storage-limits: container app: mounts emptyDir volume scratch without an ephemeral-storage limit"},
+		},
+		{
+			name:      "disk with limit",
+			resources: limited,
+		},
+		{
+			name:   "memory",
+			medium: corev1.StorageMediumMemory,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{RequireEmptyDirStorageLimits: true}, nil)
+			pod := testPod(corev1.PodSpec{
+				Volumes: []corev1.Volume{{Name: "scratch", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{Medium: tt.medium}}}},
+				Containers: []corev1.Container{
+					{Name: "app", Resources: tt.resources, VolumeMounts: []corev1.VolumeMount{{Name: "scratch", MountPath: "/scratch"}}},
+					{Name: "sidecar"},
+				},
+			})
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestCheckRequiredVolumes(t *testing.T) {
+	cfg := &Config{RequiredVolumes: []RequiredVolume{
+		{Name: "ca-bundle", MountPath: "/etc/ssl/certs"},
+		{Name: "logging"},
+	}}
+	tests := []struct {
+		name    string
+		volumes []string
+		mounts  []corev1.VolumeMount
+		want    []string
+	}{
+		{
+			name:    "every volume mounted",
+			volumes: []string{"ca-bundle", "logging"},
+			mounts:  []corev1.VolumeMount{{Name: "ca-bundle", MountPath: "/etc/ssl/certs"}},
+		},
+		{
+			name:    "missing volume",
+			volumes: []string{"ca-bundle"},
+			mounts:  []corev1.VolumeMount{{Name: "ca-bundle", MountPath: "/etc/ssl/certs"}},
+			want:    []string{"required-volumes: required volume logging is missing"},
+		},
+		{
+			name:    "mounted elsewhere",
+			volumes: []string{"ca-bundle", "logging"},
+			mounts:  []corev1.VolumeMount{{Name: "ca-bundle", MountPath: "/certs"}},
+			want:    []string{"required-volumes: container app: must mount required volume ca-bundle at /etc/ssl/certs"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{}, cfg)
+			var volumes []corev1.Volume
+			for _, name := range tt.volumes {
+				volumes = append(volumes, corev1.Volume{Name: name})
+			}
+			pod := testPod(corev1.PodSpec{Volumes: volumes, Containers: []corev1.Container{{Name: "app", VolumeMounts: tt.mounts}}})
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
+
+func TestCheckOverlappingMounts(t *testing.T) {
+	tests := []struct {
+		name   string
+		mounts []corev1.VolumeMount
+		want   []string
+	}{
+		{
+			name:   "separate",
+			mounts: []corev1.VolumeMount{{Name: "a", MountPath: "/data"}, {Name: "b", MountPath: "/data-2"}},
+		},
+		{
+			name:   "same path",
+			mounts: []corev1.VolumeMount{{Name: "a", MountPath: "/data"}, {Name: "b", MountPath: "/data/"}},
+			want:   []string{"overlapping-mounts: warning: container app: volumeMounts a (/data) and b (/data/) overlap, so one shadows part of the other"},
+		},
+		{
+			name:   "nested",
+			mounts: []corev1.VolumeMount{{Name: "a", MountPath: "/data/cache"}, {Name: "b", MountPath: "/data"}},
+			want:   []string{"overlapping-mounts: warning: container app: volumeMounts a (/data/cache) and b (/data) overlap, so one shadows part of the other"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, Options{WarnOverlappingMounts: true}, nil)
+			pod := testPod(corev1.PodSpec{Containers: []corev1.Container{{Name: "app", VolumeMounts: tt.mounts}}})
+			assertFindings(t, e, pod, RequestMeta{}, tt.want)
+		})
+	}
+}
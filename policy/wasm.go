@@ -0,0 +1,187 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// A WebAssembly plugin is a WASI module, such as one built by Go with
+// GOOS=wasip1 GOARCH=wasm -buildmode=c-shared, that exports its memory and:
+//
+//	allocate(size u32) u32
+//	validate(resource_ptr, resource_len, object_ptr, object_len u32) u64
+//
+// allocate returns a buffer of size bytes in the plugin's memory, which is
+// how the resource and the object are passed to validate. The buffers
+// belong to the plugin once validate is called. validate does what Validate
+// does for Go plugins and returns its result as JSON, {"message": "...",
+// "error": "..."}, with a pointer to it in the high 32 bits and its length
+// in the low ones. The result only needs to stay valid until the next call.
+// If the module exports _initialize, it's called before anything else.
+const (
+	wasmAllocate = "allocate"
+	wasmValidate = "validate"
+)
+
+// wasmResult is the result of a WebAssembly plugin's validate.
+type wasmResult struct {
+	Message string `json:"message"`
+	Error   string `json:"error"`
+}
+
+// wasmPlugin is a loaded WebAssembly plugin. Unlike Go plugins, calls can
+// be stopped: when ctx is done, the call is aborted and the instance it was
+// using is thrown away.
+type wasmPlugin struct {
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+	// instance holds the module instance while no call is using it, so
+	// calls are serialized. It holds nil once an instance was thrown away,
+	// and the next call instantiates the module again.
+	instance chan api.Module
+}
+
+var (
+	wasmPluginsMu sync.Mutex
+	// wasmPlugins are the WebAssembly plugins that were loaded, by path.
+	// Like Go plugins, each is only loaded once, so that engines built
+	// again when the config is reloaded don't compile them again.
+	wasmPlugins = map[string]*wasmPlugin{}
+)
+
+// openWASMPlugin opens the WebAssembly plugin at path, or returns the one
+// that was already opened.
+func openWASMPlugin(path string) (*wasmPlugin, error) {
+	wasmPluginsMu.Lock()
+	defer wasmPluginsMu.Unlock()
+	if p, ok := wasmPlugins[path]; ok {
+		return p, nil
+	}
+
+	code, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening plugin %s: %w", path, err)
+	}
+
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	p, err := newWASMPlugin(ctx, r, code)
+	if err != nil {
+		r.Close(ctx)
+		return nil, fmt.Errorf("error loading plugin %s: %w", path, err)
+	}
+	wasmPlugins[path] = p
+	return p, nil
+}
+
+func newWASMPlugin(ctx context.Context, r wazero.Runtime, code []byte) (*wasmPlugin, error) {
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
+		return nil, err
+	}
+	compiled, err := r.CompileModule(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{wasmAllocate, wasmValidate} {
+		if _, ok := compiled.ExportedFunctions()[name]; !ok {
+			return nil, fmt.Errorf("%s isn't exported", name)
+		}
+	}
+
+	p := &wasmPlugin{runtime: r, compiled: compiled, instance: make(chan api.Module, 1)}
+	m, err := p.instantiate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.instance <- m
+	return p, nil
+}
+
+// instantiate returns a new instance of the plugin's module. Instances are
+// anonymous so that there can be more than one over time.
+func (p *wasmPlugin) instantiate(ctx context.Context) (api.Module, error) {
+	return p.runtime.InstantiateModule(ctx, p.compiled, wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize"))
+}
+
+// validate calls the plugin's validate with the resource and the object.
+func (p *wasmPlugin) validate(ctx context.Context, resource string, object []byte) (string, error) {
+	var m api.Module
+	select {
+	case m = <-p.instance:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	if m == nil {
+		var err error
+		if m, err = p.instantiate(ctx); err != nil {
+			p.instance <- nil
+			return "", fmt.Errorf("error instantiating plugin: %w", err)
+		}
+	}
+
+	res, err := p.call(ctx, m, resource, object)
+	if err != nil {
+		// The instance may be left in any state, or closed if ctx is done,
+		// so it isn't used again.
+		m.Close(context.Background())
+		p.instance <- nil
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", err
+	}
+	p.instance <- m
+
+	if res.Error != "" {
+		return "", fmt.Errorf("%s", res.Error)
+	}
+	return res.Message, nil
+}
+
+// call calls validate in the instance m, returning an error if the instance
+// fails rather than the plugin.
+func (p *wasmPlugin) call(ctx context.Context, m api.Module, resource string, object []byte) (wasmResult, error) {
+	resourcePtr, err := writeWASMBuffer(ctx, m, []byte(resource))
+	if err != nil {
+		return wasmResult{}, err
+	}
+	objectPtr, err := writeWASMBuffer(ctx, m, object)
+	if err != nil {
+		return wasmResult{}, err
+	}
+
+	results, err := m.ExportedFunction(wasmValidate).Call(ctx, uint64(resourcePtr), uint64(len(resource)), uint64(objectPtr), uint64(len(object)))
+	if err != nil {
+		return wasmResult{}, fmt.Errorf("error calling %s: %w", wasmValidate, err)
+	}
+	data, ok := m.Memory().Read(uint32(results[0]>>32), uint32(results[0]))
+	if !ok {
+		return wasmResult{}, fmt.Errorf("%s returned a result outside of the plugin's memory", wasmValidate)
+	}
+	var res wasmResult
+	if err := json.Unmarshal(data, &res); err != nil {
+		return wasmResult{}, fmt.Errorf("error decoding result: %w", err)
+	}
+	return res, nil
+}
+
+// writeWASMBuffer copies data into a buffer allocated in the instance's
+// memory and returns a pointer to it.
+func writeWASMBuffer(ctx context.Context, m api.Module, data []byte) (uint32, error) {
+	results, err := m.ExportedFunction(wasmAllocate).Call(ctx, uint64(len(data)))
+	if err != nil {
+		return 0, fmt.Errorf("error calling %s: %w", wasmAllocate, err)
+	}
+	ptr := uint32(results[0])
+	if !m.Memory().Write(ptr, data) {
+		return 0, fmt.Errorf("%s returned a buffer outside of the plugin's memory", wasmAllocate)
+	}
+	return ptr, nil
+}